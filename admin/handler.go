@@ -0,0 +1,297 @@
+// Package admin provides an http.Handler for inspecting and managing a cachier.Cache.
+//
+// Endpoints (relative to the mount point):
+//
+//	GET    /keys?prefix=p&limit=n  lists keys starting with prefix (at most n, default 1000)
+//	GET    /keys?cursor=c&limit=n  lists a page of keys; next_cursor of the response points to the next page
+//	GET    /entry?key=k            returns the decoded entry as JSON without side effects (Peek)
+//	DELETE /entry?key=k            deletes the entry
+//	GET    /stats                  returns cache statistics
+//	POST   /purge?prefix=p         removes all entries (or only the ones starting with prefix)
+//	GET    /memory?key=k           returns the memory used by the key
+//	GET    /memory?limit=n         returns the n keys using the most memory (default 20)
+//	GET    /metrics                returns the number of keys and the latency histograms in the Prometheus text format
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datasapiens/cachier"
+)
+
+const defaultKeysLimit = 1000
+
+const defaultMemoryLimit = 20
+
+// Middleware wraps a handler, e.g. with authentication
+type Middleware func(http.Handler) http.Handler
+
+type handler[T any] struct {
+	cache *cachier.Cache[T]
+}
+
+// KeysResponse is returned by the /keys endpoint
+type KeysResponse struct {
+	Keys       []string `json:"keys"`
+	Truncated  bool     `json:"truncated"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// EntryResponse is returned by the /entry endpoint
+type EntryResponse struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// StatsResponse is returned by the /stats endpoint
+type StatsResponse struct {
+	Keys int `json:"keys"`
+	// Evictions are reported only by engines implementing cachier.EvictionReporter (e.g. LRUCache)
+	Evictions *cachier.EvictionStats `json:"evictions,omitempty"`
+	// Latencies are recorded only with cachier.WithLatencyHistograms
+	Latencies map[string]cachier.LatencyHistogram `json:"latencies,omitempty"`
+}
+
+// MemoryResponse is returned by the /memory endpoint; the keys are ordered by size, largest first
+type MemoryResponse struct {
+	Keys []cachier.KeySize `json:"keys"`
+}
+
+// ErrorResponse is returned when a request fails
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler creates admin http.Handler for the cache protected by the auth middleware.
+// If auth is nil all requests are rejected; pass a pass-through middleware to disable authentication explicitly.
+func NewHandler[T any](cache *cachier.Cache[T], auth Middleware) http.Handler {
+	h := &handler[T]{cache: cache}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/keys", h.keys)
+	mux.HandleFunc("/entry", h.entry)
+	mux.HandleFunc("/stats", h.stats)
+	mux.HandleFunc("/purge", h.purge)
+	mux.HandleFunc("/memory", h.memory)
+	mux.HandleFunc("/metrics", h.metrics)
+
+	if auth == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusForbidden, "admin handler has no auth middleware configured")
+		})
+	}
+	return auth(mux)
+}
+
+func (h *handler[T]) keys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := defaultKeysLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		keys, next, err := h.cache.KeysPage(r.URL.Query().Get("cursor"), limit)
+		if errors.Is(err, cachier.ErrInvalidCursor) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if keys == nil {
+			keys = make([]string, 0)
+		}
+		writeJSON(w, http.StatusOK, KeysResponse{Keys: keys, Truncated: next != "", NextCursor: next})
+		return
+	}
+
+	response := KeysResponse{Keys: make([]string, 0)}
+	err := h.cache.Range(func(key string) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return true
+		}
+		if len(response.Keys) == limit {
+			response.Truncated = true
+			return false
+		}
+		response.Keys = append(response.Keys, key)
+		return true
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *handler[T]) entry(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, "missing key")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		value, err := h.cache.Peek(key)
+		if errors.Is(err, cachier.ErrNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, EntryResponse{Key: key, Value: value})
+	case http.MethodDelete:
+		if err := h.cache.Delete(key); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *handler[T]) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	count, err := h.cache.Count()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response := StatsResponse{Keys: count, Latencies: h.cache.Stats().Latencies}
+	if evictions, ok := h.cache.EvictionStats(); ok {
+		response.Evictions = &evictions
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// metrics writes the latency histograms as cachier_operation_duration_seconds in the Prometheus text format
+func (h *handler[T]) metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	latencies := h.cache.Stats().Latencies
+	ops := make([]string, 0, len(latencies))
+	for op := range latencies {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	if count, err := h.cache.Count(); err == nil {
+		fmt.Fprintln(w, "# HELP cachier_keys Number of keys in the cache.")
+		fmt.Fprintln(w, "# TYPE cachier_keys gauge")
+		fmt.Fprintf(w, "cachier_keys %d\n", count)
+	}
+	if len(ops) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "# HELP cachier_operation_duration_seconds Duration of cache operations.")
+	fmt.Fprintln(w, "# TYPE cachier_operation_duration_seconds histogram")
+	for _, op := range ops {
+		histogram := latencies[op]
+		var cumulative uint64
+		for i, bound := range histogram.Bounds {
+			cumulative += histogram.Counts[i]
+			fmt.Fprintf(w, "cachier_operation_duration_seconds_bucket{op=%q,le=\"%g\"} %d\n", op, bound.Seconds(), cumulative)
+		}
+		fmt.Fprintf(w, "cachier_operation_duration_seconds_bucket{op=%q,le=\"+Inf\"} %d\n", op, histogram.Count)
+		fmt.Fprintf(w, "cachier_operation_duration_seconds_sum{op=%q} %g\n", op, histogram.Sum.Seconds())
+		fmt.Fprintf(w, "cachier_operation_duration_seconds_count{op=%q} %d\n", op, histogram.Count)
+	}
+}
+
+func (h *handler[T]) purge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var err error
+	if prefix := r.URL.Query().Get("prefix"); prefix != "" {
+		err = h.cache.PurgePrefix(prefix)
+	} else {
+		err = h.cache.Purge()
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler[T]) memory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var response MemoryResponse
+	var err error
+	if key := r.URL.Query().Get("key"); key != "" {
+		var size int64
+		if size, err = h.cache.MemoryUsage(key); err == nil {
+			response.Keys = []cachier.KeySize{{Key: key, Size: size}}
+		}
+	} else {
+		limit := defaultMemoryLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid limit")
+				return
+			}
+		}
+		response.Keys, err = h.cache.TopKeysBySize(limit)
+	}
+	switch {
+	case errors.Is(err, cachier.ErrMemoryUsageNotSupported):
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	case errors.Is(err, cachier.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if response.Keys == nil {
+		response.Keys = make([]cachier.KeySize, 0)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
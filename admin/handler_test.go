@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tokenAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func do(t *testing.T, h http.Handler, method string, target string, response interface{}) int {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if response != nil && w.Code == http.StatusOK {
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), response))
+	}
+	return w.Code
+}
+
+func TestHandler(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0))
+	for i, key := range []string{"a:1", "a:2", "b:1"} {
+		value := i
+		require.Nil(t, cache.Set(key, &value))
+	}
+	h := NewHandler(cache, tokenAuth)
+
+	var keys KeysResponse
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/keys?prefix=a:", &keys))
+	assert.ElementsMatch(t, []string{"a:1", "a:2"}, keys.Keys)
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/keys?limit=1", &keys))
+	assert.Len(t, keys.Keys, 1)
+	assert.True(t, keys.Truncated)
+
+	var entry EntryResponse
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/entry?key=a:2", &entry))
+	assert.Equal(t, float64(1), entry.Value)
+	assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/entry?key=c", nil))
+
+	assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/entry?key=a:2", nil))
+	assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodPost, "/purge?prefix=a:", nil))
+
+	var stats StatsResponse
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/stats", &stats))
+	assert.Equal(t, 1, stats.Keys)
+	assert.Nil(t, stats.Evictions)
+	assert.Equal(t, http.StatusNotImplemented, do(t, h, http.MethodGet, "/memory", nil))
+
+	r := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	NewHandler(cache, nil).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0), cachier.WithLatencyHistograms())
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	h := NewHandler(cache, tokenAuth)
+
+	var stats StatsResponse
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/stats", &stats))
+	assert.Equal(t, uint64(1), stats.Latencies[cachier.OpSet].Count)
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "cachier_keys 1\n"))
+	assert.True(t, strings.Contains(body, "# TYPE cachier_operation_duration_seconds histogram\n"))
+	assert.True(t, strings.Contains(body, `cachier_operation_duration_seconds_bucket{op="set",le="+Inf"} 1`))
+	assert.True(t, strings.Contains(body, `cachier_operation_duration_seconds_count{op="get"} 0`))
+}
+
+func TestHandlerEntryWithoutSideEffects(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	cache := cachier.MakeCache[int](engine)
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	h := NewHandler(cache, tokenAuth)
+
+	var entry EntryResponse
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/entry?key=a", &entry))
+	assert.Equal(t, float64(1), entry.Value)
+	// inspecting an entry does not refresh its recency or sliding expiration
+	assert.Zero(t, engine.Calls(cachiertest.OpGet))
+	assert.Equal(t, 1, engine.Calls(cachiertest.OpPeek))
+}
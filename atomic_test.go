@@ -0,0 +1,59 @@
+package cachier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAtomicEngine(t *testing.T, engine AtomicEngine) {
+	stored, err := engine.SetIfAbsent("atomic", "first")
+	require.Nil(t, err)
+	assert.True(t, stored)
+	stored, err = engine.SetIfAbsent("atomic", "second")
+	require.Nil(t, err)
+	assert.False(t, stored)
+
+	value, version, err := engine.GetWithVersion("atomic")
+	require.Nil(t, err)
+	assert.Equal(t, "first", value)
+
+	swapped, err := engine.CompareAndSwap("atomic", version, "third")
+	require.Nil(t, err)
+	assert.True(t, swapped)
+	swapped, err = engine.CompareAndSwap("atomic", version, "fourth")
+	require.Nil(t, err)
+	assert.False(t, swapped)
+	swapped, err = engine.CompareAndSwap("atomic missing", version, "fourth")
+	require.Nil(t, err)
+	assert.False(t, swapped)
+
+	value, err = engine.GetAndTouch("atomic", time.Hour)
+	require.Nil(t, err)
+	assert.Equal(t, "third", value)
+	_, err = engine.GetAndTouch("atomic missing", time.Hour)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, err = engine.GetWithVersion("atomic missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryCacheAtomic(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+	testAtomicEngine(t, mc)
+
+	ttl, err := mc.TTL("atomic")
+	require.Nil(t, err)
+	assert.Equal(t, time.Hour, ttl.Round(time.Minute))
+}
+
+func TestRedisCacheAtomic(t *testing.T) {
+	c, err := InitRedisCache[string]()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	c.Delete("atomic")
+	defer c.Delete("atomic")
+	testAtomicEngine(t, c.engine.(AtomicEngine))
+}
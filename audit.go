@@ -0,0 +1,92 @@
+package cachier
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditRecord describes a write operation sent to the engine
+type AuditRecord struct {
+	// Time is when the operation started
+	Time time.Time
+	// Op is OpSet, OpDelete or OpPurge
+	Op string
+	// Key is the key of the entry; keys deleted by DeletePredicate, DeleteWithPrefix and PurgePrefix without
+	// PrefixPurger are the keys listed by the engine, PurgePrefix with PrefixPurger records the prefix
+	// and Purge an empty key
+	Key string
+	// Size is the size of the stored value in bytes: the length of []byte and RawValue values and of the JSON
+	// encoding of other values, -1 if it cannot be encoded; 0 for deletes and purges
+	Size int
+	// Duration is how long the operation took; the keys written in a batch share the duration of the batch
+	Duration time.Duration
+	// Err is the result of the operation
+	Err error
+}
+
+// AuditSink receives the audit records; it is called synchronously by the writing goroutine,
+// so it must be fast and safe for concurrent use
+type AuditSink func(record AuditRecord)
+
+// WithAuditLog records every write operation the cache sends to the engine (Set, SetWithTTL, SetMulti,
+// the conditional writes, SetRaw, deletes and purges) to the sink, so what the cache wrote and when can be
+// reconstructed. The sizes of values other than []byte and RawValue are measured by encoding them to JSON.
+func WithAuditLog(sink AuditSink) Option {
+	return func(o *options) {
+		o.audit = sink
+	}
+}
+
+// auditStart returns the start time of an audited operation; zero time if the audit log is disabled
+func (c *Cache[T]) auditStart() time.Time {
+	if c.options.audit == nil {
+		return time.Time{}
+	}
+	return clockOrDefault(c.options.clock).Now()
+}
+
+// audit records the operation started at start if the audit log is enabled;
+// if it succeeded, it is reported to the watchers
+func (c *Cache[T]) audit(op string, key string, value interface{}, start time.Time, err error) {
+	if err == nil {
+		c.watchers.publish(op, key)
+	}
+	if c.options.audit == nil {
+		return
+	}
+	c.options.audit(AuditRecord{
+		Time:     start,
+		Op:       op,
+		Key:      key,
+		Size:     auditSize(value),
+		Duration: clockOrDefault(c.options.clock).Since(start),
+		Err:      err,
+	})
+}
+
+// audited runs the write operation and records it if the audit log is enabled
+func (c *Cache[T]) audited(op string, key string, value interface{}, fn func() error) error {
+	start := c.auditStart()
+	err := fn()
+	c.audit(op, key, value, start, err)
+	return err
+}
+
+// auditSize returns the size of the value in bytes
+func auditSize(value interface{}) int {
+	switch typedValue := value.(type) {
+	case nil:
+		return 0
+	case []byte:
+		return len(typedValue)
+	case *[]byte:
+		return len(*typedValue)
+	case RawValue:
+		return len(typedValue.RawBytes())
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return -1
+	}
+	return len(data)
+}
@@ -0,0 +1,82 @@
+package cachier_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditLog struct {
+	records []cachier.AuditRecord
+	mutex   sync.Mutex
+}
+
+func (l *auditLog) record(record cachier.AuditRecord) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.records = append(l.records, record)
+}
+
+func TestAuditLog(t *testing.T) {
+	log := &auditLog{}
+	engine := cachiertest.NewEngine()
+	cache := cachier.MakeCache[string](engine, cachier.WithAuditLog(log.record))
+
+	value := "hello"
+	require.Nil(t, cache.Set("a", &value))
+	require.Nil(t, cache.SetWithTTL("b", &value, time.Hour))
+	require.Nil(t, cache.SetMulti(map[string]*string{"c": &value, "d": &value}, "d"))
+	stored, err := cache.SetIfAbsent("a", &value)
+	require.Nil(t, err)
+	assert.False(t, stored)
+	require.Nil(t, cache.Delete("a"))
+	require.Nil(t, cache.DeleteKeys([]string{"c", "d"}))
+
+	engine.InjectFault(cachiertest.OpSet, cachiertest.Fault{Latency: 10 * time.Millisecond, Times: 1})
+	require.ErrorIs(t, cache.Set("e", &value), cachiertest.ErrInjected)
+	require.Nil(t, cache.Purge())
+
+	type entry struct {
+		op   string
+		key  string
+		size int
+		err  error
+	}
+	entries := make([]entry, 0, len(log.records))
+	for _, record := range log.records {
+		assert.False(t, record.Time.IsZero())
+		entries = append(entries, entry{record.Op, record.Key, record.Size, record.Err})
+	}
+	assert.Equal(t, []entry{
+		{cachier.OpSet, "a", 7, nil},
+		{cachier.OpSet, "b", 7, nil},
+		{cachier.OpSet, "d", 7, nil},
+		{cachier.OpSet, "c", 7, nil},
+		{cachier.OpDelete, "a", 0, nil},
+		{cachier.OpDelete, "c", 0, nil},
+		{cachier.OpDelete, "d", 0, nil},
+		{cachier.OpSet, "e", 7, cachiertest.ErrInjected},
+		{cachier.OpPurge, "", 0, nil},
+	}, entries)
+	assert.GreaterOrEqual(t, log.records[7].Duration, 10*time.Millisecond)
+}
+
+func TestAuditLogClock(t *testing.T) {
+	log := &auditLog{}
+	clock := cachiertest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := cachier.MakeCache[[]byte](cachier.NewMemoryCache(0, 0), cachier.WithClock(clock), cachier.WithAuditLog(log.record))
+
+	value := []byte{1, 2, 3}
+	require.Nil(t, cache.Set("a", &value))
+	require.Nil(t, cache.PurgePrefix("a"))
+
+	require.Len(t, log.records, 2)
+	assert.Equal(t, cachier.AuditRecord{Time: clock.Now(), Op: cachier.OpSet, Key: "a", Size: 3}, log.records[0])
+	// MemoryCache does not implement PrefixPurger, the keys are deleted one by one
+	assert.Equal(t, cachier.AuditRecord{Time: clock.Now(), Op: cachier.OpDelete, Key: "a"}, log.records[1])
+}
@@ -0,0 +1,180 @@
+// Package bench contains helpers for benchmarking cachier engines and compression providers.
+// The benchmarks themselves live in bench_test.go; run them with
+//
+//	go test -run=^$ -bench=. -benchmem ./bench/
+//
+// Redis benchmarks are skipped if Redis is not reachable at REDIS_HOST (default localhost:6379).
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/compression"
+	"github.com/go-redis/redis/v8"
+)
+
+// KeyPrefix is the prefix of all keys written to Redis by the benchmarks
+const KeyPrefix = "cachier:bench:"
+
+// Value is the cached type used by the benchmarks
+type Value struct {
+	ID      int
+	Payload string
+}
+
+// Provider describes a compression provider used by the benchmarks
+type Provider struct {
+	Name string
+	ID   byte
+}
+
+// Providers lists compression providers compared by the benchmarks; ID 0 means no compression
+var Providers = []Provider{
+	{Name: "none", ID: 0},
+	{Name: "zstd", ID: compression.ProviderIDZstd},
+	{Name: "s2", ID: compression.ProviderIDS2},
+	{Name: "lz4", ID: compression.ProviderIDLz4},
+}
+
+// Sizes lists payload sizes in bytes used by the benchmarks
+var Sizes = []int{256, 4 * 1024, 64 * 1024}
+
+var words = []string{"cache", "redis", "value", "key", "compression", "engine", "lru", "json", "zstd", "s2"}
+
+// Payload returns deterministic, moderately compressible text of the given size
+func Payload(size int) string {
+	r := rand.New(rand.NewSource(int64(size)))
+	var sb strings.Builder
+	sb.Grow(size + 16)
+	for sb.Len() < size {
+		sb.WriteString(words[r.Intn(len(words))])
+		sb.WriteByte(' ')
+	}
+	return sb.String()[:size]
+}
+
+// Marshal encodes values using JSON
+func Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes JSON encoded Value
+func Unmarshal(b []byte, value *interface{}) error {
+	var v Value
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*value = v
+	return nil
+}
+
+// NewCompressionEngine creates compression engine for given provider; nil for no compression
+func NewCompressionEngine(provider Provider) (*compression.Engine, error) {
+	return compression.NewEngine(provider.ID, nil)
+}
+
+// NewRedisClient connects to Redis at REDIS_HOST (default localhost:6379)
+func NewRedisClient() (*redis.Client, error) {
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		redisHost = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisHost,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewLRUCache creates LRU based cache of given size
+func NewLRUCache(size int, engine *compression.Engine) (*cachier.Cache[Value], error) {
+	lc, err := cachier.NewLRUCache(size, Marshal, Unmarshal, engine)
+	if err != nil {
+		return nil, err
+	}
+	return cachier.MakeCache[Value](lc), nil
+}
+
+// NewRedisCache creates Redis based cache storing keys with KeyPrefix
+func NewRedisCache(client *redis.Client, engine *compression.Engine) *cachier.Cache[Value] {
+	return cachier.MakeCache[Value](cachier.NewRedisCache(client, KeyPrefix, Marshal, Unmarshal, 0, engine))
+}
+
+// NewTieredCache creates Redis cache with LRU subcache
+func NewTieredCache(client *redis.Client, size int, engine *compression.Engine) (*cachier.Cache[Value], error) {
+	lru, err := NewLRUCache(size, engine)
+	if err != nil {
+		return nil, err
+	}
+	return cachier.MakeCache[Value](&cachier.CacheWithSubcache[Value]{
+		Cache:    NewRedisCache(client, engine),
+		Subcache: lru,
+	}), nil
+}
+
+// Keys returns n distinct keys
+func Keys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	return keys
+}
+
+// RunSet benchmarks Set of the value under rotating keys
+func RunSet(b *testing.B, cache *cachier.Cache[Value], keys []string, value *Value) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(value.Payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cache.Set(keys[i%len(keys)], value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// RunGet benchmarks Get of previously stored keys
+func RunGet(b *testing.B, cache *cachier.Cache[Value], keys []string, value *Value) {
+	for _, key := range keys {
+		if err := cache.Set(key, value); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(value.Payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.Get(keys[i%len(keys)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// RunGetOrCompute benchmarks GetOrCompute where roughly every second call is a miss
+func RunGetOrCompute(b *testing.B, cache *cachier.Cache[Value], keys []string, value *Value) {
+	if err := cache.Purge(); err != nil {
+		b.Fatal(err)
+	}
+	evaluator := func() (*Value, error) {
+		return value, nil
+	}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(value.Payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.GetOrCompute(keys[(i/2)%len(keys)], evaluator); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package bench
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/go-redis/redis/v8"
+)
+
+const lruSize = 1000
+
+var runners = []struct {
+	name string
+	run  func(*testing.B, *cachier.Cache[Value], []string, *Value)
+}{
+	{name: "Set", run: RunSet},
+	{name: "Get", run: RunGet},
+	{name: "GetOrCompute", run: RunGetOrCompute},
+}
+
+func benchmarkCaches(b *testing.B, newCache func(Provider) (*cachier.Cache[Value], error)) {
+	keys := Keys(lruSize / 2)
+	for _, provider := range Providers {
+		for _, size := range Sizes {
+			value := &Value{ID: size, Payload: Payload(size)}
+			for _, runner := range runners {
+				b.Run(fmt.Sprintf("%s/%dB/%s", provider.Name, size, runner.name), func(b *testing.B) {
+					cache, err := newCache(provider)
+					if err != nil {
+						b.Fatal(err)
+					}
+					defer cache.Purge()
+					runner.run(b, cache, keys, value)
+				})
+			}
+		}
+	}
+}
+
+func redisClient(b *testing.B) *redis.Client {
+	client, err := NewRedisClient()
+	if err != nil {
+		b.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	return client
+}
+
+func BenchmarkLRUCache(b *testing.B) {
+	benchmarkCaches(b, func(provider Provider) (*cachier.Cache[Value], error) {
+		engine, err := NewCompressionEngine(provider)
+		if err != nil {
+			return nil, err
+		}
+		return NewLRUCache(lruSize, engine)
+	})
+}
+
+func BenchmarkRedisCache(b *testing.B) {
+	client := redisClient(b)
+	defer client.Close()
+	benchmarkCaches(b, func(provider Provider) (*cachier.Cache[Value], error) {
+		engine, err := NewCompressionEngine(provider)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisCache(client, engine), nil
+	})
+}
+
+func BenchmarkTieredCache(b *testing.B) {
+	client := redisClient(b)
+	defer client.Close()
+	benchmarkCaches(b, func(provider Provider) (*cachier.Cache[Value], error) {
+		engine, err := NewCompressionEngine(provider)
+		if err != nil {
+			return nil, err
+		}
+		return NewTieredCache(client, lruSize, engine)
+	})
+}
+
+// BenchmarkMemoryCache measures the overhead of Cache itself, MemoryCache stores the values without serialization
+func BenchmarkMemoryCache(b *testing.B) {
+	keys := Keys(lruSize / 2)
+	value := &Value{ID: Sizes[0], Payload: Payload(Sizes[0])}
+	for _, runner := range runners {
+		b.Run(runner.name, func(b *testing.B) {
+			cache := cachier.MakeCache[Value](cachier.NewMemoryCache(0, 0))
+			runner.run(b, cache, keys, value)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -394,3 +395,89 @@ func TestLRUCacheWithCompressionGOB(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, a.Key, output.Key)
 }
+
+func TestRange(t *testing.T) {
+	c := InitLRUCache[int]()
+	for i := 0; i < 10; i++ {
+		value := i
+		require.Nil(t, c.Set(fmt.Sprintf("key:%d", i), &value))
+	}
+
+	keys := make([]string, 0)
+	err := c.Range(func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	require.Nil(t, err)
+	assert.Len(t, keys, 10)
+
+	count := 0
+	err = c.Range(func(key string) bool {
+		count++
+		return count < 3
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestToTyped(t *testing.T) {
+	value := 1
+	typed, err := toTyped[int](&value)
+	require.Nil(t, err)
+	assert.Same(t, &value, typed)
+
+	typed, err = toTyped[int](value)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *typed)
+
+	_, err = toTyped[int]("1")
+	assert.ErrorIs(t, err, ErrWrongDataType)
+	_, err = toTyped[int](nil)
+	assert.ErrorIs(t, err, ErrWrongDataType)
+
+	// T being a pointer itself
+	pointer, err := toTyped[*int](&value)
+	require.Nil(t, err)
+	assert.Same(t, &value, *pointer)
+}
+
+// reflectToTyped is the conversion done by Cache before toTyped, kept as the baseline of BenchmarkToTyped
+func reflectToTyped[T any](value interface{}) (*T, error) {
+	if reflect.ValueOf(value).Kind() == reflect.Ptr {
+		typedValue, ok := value.(*T)
+		if !ok {
+			return nil, ErrWrongDataType
+		}
+		return typedValue, nil
+	}
+	typedValue, ok := value.(T)
+	if !ok {
+		return nil, ErrWrongDataType
+	}
+	return &typedValue, nil
+}
+
+// BenchmarkToTyped measures the conversion done by Cache on every hit against the reflect based baseline,
+// for engines returning the stored pointers and engines returning decoded values:
+//
+//	go test -run='^$' -bench=ToTyped -benchmem .
+func BenchmarkToTyped(b *testing.B) {
+	type entry struct{ ID int }
+	values := map[string]interface{}{"pointer": &entry{ID: 1}, "value": entry{ID: 1}}
+	conversions := map[string]func(value interface{}) (*entry, error){
+		"reflect":    reflectToTyped[entry],
+		"typeswitch": toTyped[entry],
+	}
+	for valueName, value := range values {
+		for conversionName, convert := range conversions {
+			b.Run(valueName+"/"+conversionName, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := convert(value); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,54 +1,182 @@
 package cachier
 
-import "reflect"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
 
 // CacheWithSubcache is a Cache with L1 subcache.
 type CacheWithSubcache[T any] struct {
 	Cache    *Cache[T]
 	Subcache *Cache[T]
+	// SubcacheTTL (if > 0) limits how long values stay in the subcache, which bounds their staleness
+	// when other instances update the primary cache; values with a shorter TTL keep it
+	SubcacheTTL time.Duration
+	// SubcacheMaxValueSize (if > 0) keeps values larger than it (in bytes, as measured by SizeOf) out of
+	// the subcache, so a few huge entries do not evict the small ones; they are stored only in the primary cache
+	SubcacheMaxValueSize int
+	// SizeOf returns the size of the value for SubcacheMaxValueSize; nil means the length of its JSON encoding
+	SizeOf func(value *T) int
+
+	// promotions holds the keys being stored into the subcache
+	promotions sync.Map
 }
 
-// Get gets a cached value by key
+// Get gets a cached value by key.
+// Values missing in the subcache are read from the primary cache and returned immediately,
+// they are stored into the subcache in background (see Subcache.DrainWithin);
+// ErrNotFound and other errors of the primary cache are returned as they are.
 func (cs *CacheWithSubcache[T]) Get(key string) (interface{}, error) {
-	value, err := cs.Subcache.GetOrCompute(key, func() (*T, error) {
-		return cs.Cache.Get(key)
-	})
+	if value, err := cs.Subcache.Get(key); err == nil {
+		return *value, nil
+	}
+	value, err := cs.Cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	cs.promote(key, value)
+	return *value, nil
+}
 
-	return *value, err
+// promote stores the value read from the primary cache into the subcache in background,
+// so the hot path does not wait for the subcache to marshal and compress it.
+// Concurrent promotions of the same key are deduplicated.
+// Promotions exceeding the pending writes budget of the subcache (WithPendingWritesBudget) are skipped.
+func (cs *CacheWithSubcache[T]) promote(key string, value *T) {
+	size := cs.Subcache.pendingSize(value)
+	if cs.Subcache.overBudget(size) {
+		// the promotion is only an optimization, it is skipped when too many values wait to be written
+		return
+	}
+	if _, promoting := cs.promotions.LoadOrStore(key, struct{}{}); promoting {
+		return
+	}
+	cs.Subcache.writes.started(size)
+	go func() {
+		defer cs.Subcache.writes.done(size)
+		defer cs.promotions.Delete(key)
+		cs.setSubcache(key, value, 0)
+	}()
+}
+
+// setSubcache stores the value into the subcache with the shorter of ttl and SubcacheTTL (0 means no TTL);
+// values exceeding SubcacheMaxValueSize are removed from the subcache instead
+func (cs *CacheWithSubcache[T]) setSubcache(key string, value *T, ttl time.Duration) error {
+	if cs.bypassSubcache(value) {
+		return cs.Subcache.Delete(key)
+	}
+	if cs.SubcacheTTL > 0 && (ttl <= 0 || cs.SubcacheTTL < ttl) {
+		ttl = cs.SubcacheTTL
+	}
+	return cs.Subcache.SetWithTTL(key, value, ttl)
 }
 
-// Peek gets a cached key value without side-effects (i.e. without adding to L1 cache)
+// Peek gets a cached key value without side-effects (i.e. without adding to L1 cache).
+// The value has the same type as the one returned by Get regardless of the tier it was found in.
 func (cs *CacheWithSubcache[T]) Peek(key string) (interface{}, error) {
 	value, err := cs.Subcache.Peek(key)
-	if err == nil {
-		return value, err
+	if err != nil {
+		value, err = cs.Cache.Peek(key)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return cs.Cache.Peek(key)
+	return *value, nil
 }
 
 // Set stores a key-value pair into cache
 func (cs *CacheWithSubcache[T]) Set(key string, value interface{}) error {
 
-	var typedValue *T
-	if reflect.ValueOf(value).Kind() == reflect.Ptr {
-		value, ok := value.(*T)
-		if !ok {
-			return ErrWrongDataType
+	typedValue, err := toTyped[T](value)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.setSubcache(key, typedValue, 0); err != nil {
+		return err
+	}
+	return cs.Cache.Set(key, typedValue)
+}
+
+// bypassSubcache reports whether the value is too large for the subcache
+func (cs *CacheWithSubcache[T]) bypassSubcache(value *T) bool {
+	if cs.SubcacheMaxValueSize <= 0 {
+		return false
+	}
+	if cs.SizeOf != nil {
+		return cs.SizeOf(value) > cs.SubcacheMaxValueSize
+	}
+	encoded, err := json.Marshal(value)
+	// values which cannot be measured are cached as usual
+	return err == nil && len(encoded) > cs.SubcacheMaxValueSize
+}
+
+// SetMulti stores the key-value pairs into the primary cache (atomically if its engine supports it)
+// and then into the subcache
+func (cs *CacheWithSubcache[T]) SetMulti(values map[string]interface{}) error {
+	typedValues := make(map[string]*T, len(values))
+	for key, value := range values {
+		typedValue, err := cs.Cache.toTyped(value)
+		if err != nil {
+			return wrapKeyError(OpSet, key, err)
 		}
-		typedValue = value
+		typedValues[key] = typedValue
+	}
 
-	} else {
-		value, ok := value.(T)
-		if !ok {
-			return ErrWrongDataType
+	if err := cs.Cache.SetMulti(typedValues); err != nil {
+		return err
+	}
+	if cs.SubcacheTTL <= 0 && cs.SubcacheMaxValueSize <= 0 {
+		return cs.Subcache.SetMulti(typedValues)
+	}
+	for key, value := range typedValues {
+		if err := cs.setSubcache(key, value, 0); err != nil {
+			return err
 		}
-		typedValue = &value
 	}
+	return nil
+}
 
-	if err := cs.Subcache.Set(key, typedValue); err != nil {
+// SetWithTTL stores a key-value pair which expires after ttl into both tiers
+func (cs *CacheWithSubcache[T]) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	typedValue, err := cs.Cache.toTyped(value)
+	if err != nil {
 		return err
 	}
-	return cs.Cache.Set(key, typedValue)
+
+	if err := cs.setSubcache(key, typedValue, ttl); err != nil {
+		return err
+	}
+	return cs.Cache.SetWithTTL(key, typedValue, ttl)
+}
+
+// TTL returns the remaining time to live of the key in the primary cache
+func (cs *CacheWithSubcache[T]) TTL(key string) (time.Duration, error) {
+	return cs.Cache.TTL(key)
+}
+
+// ExportEntry exports the entry from the primary cache
+func (cs *CacheWithSubcache[T]) ExportEntry(key string) (*ExportedEntry, error) {
+	exporter, ok := cs.Cache.engine.(EntryExporter)
+	if !ok {
+		return nil, ErrExportNotSupported
+	}
+	return exporter.ExportEntry(key)
+}
+
+// ImportEntry imports the entry into the primary cache and invalidates it in the subcache
+func (cs *CacheWithSubcache[T]) ImportEntry(entry *ExportedEntry) error {
+	exporter, ok := cs.Cache.engine.(EntryExporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+	if err := exporter.ImportEntry(entry); err != nil {
+		return err
+	}
+	return cs.Subcache.Delete(entry.Key)
 }
 
 // Delete removes a key from cache
@@ -64,6 +192,48 @@ func (cs *CacheWithSubcache[T]) Keys() ([]string, error) {
 	return cs.Cache.Keys()
 }
 
+// Count returns the number of keys in the primary cache
+func (cs *CacheWithSubcache[T]) Count() (int, error) {
+	return cs.Cache.Count()
+}
+
+// Age returns how long ago the key was stored in the primary cache
+func (cs *CacheWithSubcache[T]) Age(key string) (time.Duration, error) {
+	ager, ok := engineAs[EntryAger](cs.Cache.engine)
+	if !ok {
+		return 0, ErrAgeUnknown
+	}
+	return ager.Age(key)
+}
+
+// KeysPage returns a page of keys of the primary cache
+func (cs *CacheWithSubcache[T]) KeysPage(cursor string, limit int) ([]string, string, error) {
+	return cs.Cache.KeysPage(cursor, limit)
+}
+
+// KeysPredicate returns the keys satisfying the given predicate
+func (cs *CacheWithSubcache[T]) KeysPredicate(pred Predicate) ([]string, error) {
+	return cs.Cache.KeysPredicate(pred)
+}
+
+// KeysWithPrefix returns the keys starting with given prefix
+func (cs *CacheWithSubcache[T]) KeysWithPrefix(prefix string) ([]string, error) {
+	return cs.Cache.KeysWithPrefix(prefix)
+}
+
+// RangeKeys calls fn for every key in the cache until fn returns false
+func (cs *CacheWithSubcache[T]) RangeKeys(fn func(key string) bool) error {
+	return cs.Cache.Range(fn)
+}
+
+// PurgePrefix removes all the records with keys starting with the given prefix from both tiers
+func (cs *CacheWithSubcache[T]) PurgePrefix(prefix string) error {
+	if err := cs.Cache.PurgePrefix(prefix); err != nil {
+		return err
+	}
+	return cs.Subcache.PurgePrefix(prefix)
+}
+
 // Purge removes all the records from the cache
 func (cs *CacheWithSubcache[T]) Purge() error {
 	keys, err := cs.Keys()
@@ -77,3 +247,51 @@ func (cs *CacheWithSubcache[T]) Purge() error {
 	}
 	return nil
 }
+
+// Ping checks both the primary cache and the subcache
+func (cs *CacheWithSubcache[T]) Ping(ctx context.Context) error {
+	if err := cs.Cache.Healthy(ctx); err != nil {
+		return err
+	}
+	return cs.Subcache.Healthy(ctx)
+}
+
+// Pin keeps the entry of the key in the subcache until Unpin, loading it from the primary cache if needed;
+// the engine of the subcache must implement Pinner. The entry of the primary cache is not affected.
+func (cs *CacheWithSubcache[T]) Pin(key string) error {
+	if _, err := cs.Subcache.Peek(key); errors.Is(err, ErrNotFound) {
+		value, err := cs.Cache.Get(key)
+		if err != nil {
+			return err
+		}
+		if err := cs.Subcache.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return cs.Subcache.Pin(key)
+}
+
+// Unpin lets the entry of the key be evicted from the subcache again
+func (cs *CacheWithSubcache[T]) Unpin(key string) error {
+	return cs.Subcache.Unpin(key)
+}
+
+// Pinned reports whether the key is pinned in the subcache
+func (cs *CacheWithSubcache[T]) Pinned(key string) bool {
+	return cs.Subcache.Pinned(key)
+}
+
+// JoinInvalidationBus makes the subcaches of all the processes joining the bus drop the keys deleted
+// or purged by any of them; the primary cache is shared, so it does not need to join
+func (cs *CacheWithSubcache[T]) JoinInvalidationBus(bus InvalidationBus) (leave func() error, err error) {
+	return cs.Subcache.JoinInvalidationBus(bus)
+}
+
+// Inspect returns the metadata of the entry from the subcache (Source is SourceSubcache) or from the primary cache
+func (cs *CacheWithSubcache[T]) Inspect(key string) (EntryInfo, error) {
+	if info, err := cs.Subcache.inspect(key); err == nil {
+		info.Source = SourceSubcache
+		return info, nil
+	}
+	return cs.Cache.inspect(key)
+}
@@ -0,0 +1,182 @@
+package cachier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func InitTieredLRUCache[T any]() (*Cache[T], *Cache[T], *Cache[T]) {
+	primary := InitLRUCache[T]()
+	subcache := InitLRUCache[T]()
+	return MakeCache[T](&CacheWithSubcache[T]{
+		Cache:    primary,
+		Subcache: subcache,
+	}), primary, subcache
+}
+
+func TestCacheWithSubcacheCountOverlap(t *testing.T) {
+	c, primary, subcache := InitTieredLRUCache[int]()
+
+	one, two := 1, 2
+	// "a" lives in both tiers, "b" only in the primary one
+	require.Nil(t, c.Set("a", &one))
+	require.Nil(t, primary.Set("b", &two))
+
+	count, err := c.CountPredicate(func(string) bool { return true })
+	require.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	// key deleted through the tiered cache is removed from both tiers
+	require.Nil(t, c.Delete("a"))
+	count, err = c.CountPredicate(func(string) bool { return true })
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	_, err = subcache.Get("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCacheWithSubcachePurgePrefix(t *testing.T) {
+	c, primary, subcache := InitTieredLRUCache[int]()
+
+	value := 1
+	for _, key := range []string{"tenant1:a", "tenant1:b", "tenant2:a"} {
+		require.Nil(t, c.Set(key, &value))
+	}
+
+	require.Nil(t, c.PurgePrefix("tenant1:"))
+	for _, tier := range []*Cache[int]{c, primary, subcache} {
+		keys, err := tier.Keys()
+		require.Nil(t, err)
+		assert.Equal(t, []string{"tenant2:a"}, keys)
+	}
+}
+
+func TestCacheWithSubcachePeekType(t *testing.T) {
+	_, primary, subcache := InitTieredLRUCache[int]()
+	cs := &CacheWithSubcache[int]{Cache: primary, Subcache: subcache}
+
+	one, two := 1, 2
+	require.Nil(t, subcache.Set("l1", &one))
+	require.Nil(t, primary.Set("l2", &two))
+
+	for key, expected := range map[string]int{"l1": 1, "l2": 2} {
+		got, err := cs.Get(key)
+		require.Nil(t, err)
+		peeked, err := cs.Peek(key)
+		require.Nil(t, err)
+		assert.IsType(t, got, peeked)
+		assert.Equal(t, expected, peeked)
+	}
+
+	_, err := cs.Peek("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPeekPointerValue(t *testing.T) {
+	c := InitLRUCache[int]()
+	value := 1
+	require.Nil(t, c.Set("key", &value))
+	peeked, err := c.Peek("key")
+	require.Nil(t, err)
+	assert.Equal(t, 1, *peeked)
+
+	require.Nil(t, c.engine.Set("other", "string"))
+	_, err = c.Peek("other")
+	assert.ErrorIs(t, err, ErrWrongDataType)
+}
+
+func TestCacheWithSubcacheGetMiss(t *testing.T) {
+	failing := func() *Cache[int] {
+		return MakeCache[int](WrapEngine(NewMemoryCache(0, 0), func(call *Call, next func(call *Call) error) error {
+			return ErrEngineUnavailable
+		}))
+	}
+
+	tests := []struct {
+		name     string
+		primary  *Cache[int]
+		subcache *Cache[int]
+		inL1     bool
+		inL2     bool
+		err      error
+	}{
+		{name: "L1 hit", primary: InitLRUCache[int](), subcache: InitLRUCache[int](), inL1: true},
+		{name: "L2 hit", primary: InitLRUCache[int](), subcache: InitLRUCache[int](), inL2: true},
+		{name: "miss", primary: InitLRUCache[int](), subcache: InitLRUCache[int](), err: ErrNotFound},
+		{name: "primary failing", primary: failing(), subcache: InitLRUCache[int](), err: ErrEngineUnavailable},
+		{name: "subcache failing", primary: InitLRUCache[int](), subcache: failing(), inL2: true},
+		{name: "both failing", primary: failing(), subcache: failing(), err: ErrEngineUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value := 1
+			if test.inL1 {
+				require.Nil(t, test.subcache.Set("key", &value))
+			}
+			if test.inL2 {
+				require.Nil(t, test.primary.Set("key", &value))
+			}
+			c := MakeCache[int](&CacheWithSubcache[int]{Cache: test.primary, Subcache: test.subcache})
+
+			output, err := c.Get("key")
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				assert.Nil(t, output)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, value, *output)
+		})
+	}
+}
+
+func TestCacheWithSubcachePromotion(t *testing.T) {
+	c, primary, subcache := InitTieredLRUCache[int]()
+	value := 1
+	require.Nil(t, primary.Set("key", &value))
+
+	for i := 0; i < 10; i++ {
+		output, err := c.Get("key")
+		require.Nil(t, err)
+		assert.Equal(t, value, *output)
+	}
+	remaining, err := subcache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+	assert.Zero(t, remaining)
+
+	output, err := subcache.Peek("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *output)
+}
+
+func TestCacheWithSubcacheMaxValueSize(t *testing.T) {
+	primary, subcache := InitLRUCache[string](), InitLRUCache[string]()
+	c := MakeCache[string](&CacheWithSubcache[string]{
+		Cache:                primary,
+		Subcache:             subcache,
+		SubcacheMaxValueSize: 10,
+	})
+
+	small, large := "small", "large value"
+	require.Nil(t, c.Set("small", &small))
+	require.Nil(t, c.Set("large", &small))
+	require.Nil(t, c.Set("large", &large))
+
+	_, err := subcache.Peek("small")
+	assert.Nil(t, err)
+	// the previous value is removed from the subcache
+	_, err = subcache.Peek("large")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	output, err := c.Get("large")
+	require.Nil(t, err)
+	assert.Equal(t, large, *output)
+	_, err = subcache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+	_, err = subcache.Peek("large")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
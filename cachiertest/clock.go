@@ -0,0 +1,89 @@
+package cachiertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/datasapiens/cachier"
+)
+
+// FakeClock is a cachier.Clock which only moves when Advance is called
+type FakeClock struct {
+	now     time.Time
+	tickers []*fakeTicker
+	mutex   sync.Mutex
+}
+
+// NewFakeClock creates a FakeClock set to the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Since returns the fake time elapsed since t
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// NewTicker creates a ticker which fires when the clock is advanced past its period
+func (c *FakeClock) NewTicker(d time.Duration) cachier.Ticker {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ticker := &fakeTicker{
+		clock:  c,
+		period: d,
+		next:   c.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// Advance moves the clock forward and fires the tickers which are due.
+// As with time.Ticker, ticks are dropped if the receiver is not ready.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	for _, ticker := range c.tickers {
+		for !ticker.next.After(c.now) {
+			select {
+			case ticker.c <- ticker.next:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.period)
+		}
+	}
+}
+
+func (c *FakeClock) removeTicker(t *fakeTicker) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, ticker := range c.tickers {
+		if ticker == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.removeTicker(t)
+}
@@ -0,0 +1,211 @@
+// Package cachiertest provides utilities for testing code which uses cachier,
+// e.g. an in-memory cachier.CacheEngine with fault injection.
+package cachiertest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/datasapiens/cachier"
+)
+
+// Operation identifies a CacheEngine method
+type Operation string
+
+// Operations of cachier.CacheEngine
+const (
+	OpGet    Operation = "get"
+	OpPeek   Operation = "peek"
+	OpSet    Operation = "set"
+	OpDelete Operation = "delete"
+	OpKeys   Operation = "keys"
+	OpPurge  Operation = "purge"
+)
+
+// ErrInjected is the default error returned by an injected fault
+var ErrInjected = errors.New("cachiertest: injected fault")
+
+// Fault describes a failure injected into an engine operation
+type Fault struct {
+	// Err is returned by the operation; if nil ErrInjected is used
+	Err error
+	// Latency delays the operation (also when it does not fail)
+	Latency time.Duration
+	// Keys limits the fault to operations on keys satisfying the predicate (nil means all keys)
+	Keys cachier.Predicate
+	// Every makes only every n-th matching call fail (0 or 1 means every call)
+	Every int
+	// Times removes the fault after it failed n times (0 means never)
+	Times int
+	// LatencyOnly makes the fault only delay the operation without failing it
+	LatencyOnly bool
+
+	calls    int
+	failures int
+}
+
+// Engine is an in-memory cachier.CacheEngine with fault injection
+type Engine struct {
+	data   map[string]interface{}
+	faults map[Operation]*Fault
+	calls  map[Operation]int
+	mutex  sync.Mutex
+}
+
+// NewEngine creates an empty Engine without any faults
+func NewEngine() *Engine {
+	return &Engine{
+		data:   make(map[string]interface{}),
+		faults: make(map[Operation]*Fault),
+		calls:  make(map[Operation]int),
+	}
+}
+
+// InjectFault registers a fault for the given operation, replacing the previous one
+func (e *Engine) InjectFault(op Operation, fault Fault) *Engine {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.faults[op] = &fault
+	return e
+}
+
+// ClearFault removes the fault registered for the given operation
+func (e *Engine) ClearFault(op Operation) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.faults, op)
+}
+
+// ClearFaults removes all the registered faults
+func (e *Engine) ClearFaults() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.faults = make(map[Operation]*Fault)
+}
+
+// Calls returns how many times the operation was called
+func (e *Engine) Calls(op Operation) int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.calls[op]
+}
+
+// Len returns the number of stored entries
+func (e *Engine) Len() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.data)
+}
+
+// Count returns the number of stored entries (cachier.Counter); it is not subject to faults
+func (e *Engine) Count() (int, error) {
+	return e.Len(), nil
+}
+
+// fault counts the call and returns the error to be returned by the operation, if any
+func (e *Engine) fault(op Operation, key string) error {
+	e.mutex.Lock()
+	e.calls[op]++
+	fault, found := e.faults[op]
+	if !found || (fault.Keys != nil && !fault.Keys(key)) {
+		e.mutex.Unlock()
+		return nil
+	}
+
+	latency := fault.Latency
+	fault.calls++
+	fail := !fault.LatencyOnly && (fault.Every <= 1 || fault.calls%fault.Every == 0)
+	if fail {
+		fault.failures++
+		if fault.Times > 0 && fault.failures >= fault.Times {
+			delete(e.faults, op)
+		}
+	}
+	err := fault.Err
+	e.mutex.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if !fail {
+		return nil
+	}
+	if err == nil {
+		return ErrInjected
+	}
+	return err
+}
+
+// Get gets a value by given key
+func (e *Engine) Get(key string) (interface{}, error) {
+	if err := e.fault(OpGet, key); err != nil {
+		return nil, err
+	}
+	return e.get(key)
+}
+
+// Peek gets a value by given key
+func (e *Engine) Peek(key string) (interface{}, error) {
+	if err := e.fault(OpPeek, key); err != nil {
+		return nil, err
+	}
+	return e.get(key)
+}
+
+func (e *Engine) get(key string) (interface{}, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	value, found := e.data[key]
+	if !found {
+		return nil, cachier.ErrNotFound
+	}
+	return value, nil
+}
+
+// Set stores given key-value pair
+func (e *Engine) Set(key string, value interface{}) error {
+	if err := e.fault(OpSet, key); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.data[key] = value
+	return nil
+}
+
+// Delete removes a key
+func (e *Engine) Delete(key string) error {
+	if err := e.fault(OpDelete, key); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.data, key)
+	return nil
+}
+
+// Keys returns all the keys
+func (e *Engine) Keys() ([]string, error) {
+	if err := e.fault(OpKeys, ""); err != nil {
+		return nil, err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	keys := make([]string, 0, len(e.data))
+	for key := range e.data {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Purge removes all the records
+func (e *Engine) Purge() error {
+	if err := e.fault(OpPurge, ""); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.data = make(map[string]interface{})
+	return nil
+}
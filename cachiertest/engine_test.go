@@ -0,0 +1,47 @@
+package cachiertest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngineFaults(t *testing.T) {
+	engine := NewEngine()
+	cache := cachier.MakeCache[int](engine)
+
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	require.Nil(t, cache.Set("b:1", &value))
+
+	errDown := errors.New("down")
+	engine.InjectFault(OpGet, Fault{Err: errDown, Times: 1})
+	_, err := cache.Get("a")
+	assert.ErrorIs(t, err, errDown)
+	// the fault is removed after the first failure
+	_, err = cache.Get("a")
+	assert.Nil(t, err)
+
+	engine.InjectFault(OpSet, Fault{Keys: func(key string) bool { return strings.HasPrefix(key, "b:") }})
+	assert.Nil(t, cache.Set("a", &value))
+	assert.ErrorIs(t, cache.Set("b:2", &value), ErrInjected)
+
+	engine.InjectFault(OpDelete, Fault{Every: 2})
+	assert.Nil(t, cache.Delete("a"))
+	assert.ErrorIs(t, cache.Delete("b:1"), ErrInjected)
+	assert.Equal(t, 1, engine.Len())
+
+	engine.ClearFaults()
+	engine.InjectFault(OpPeek, Fault{Latency: 10 * time.Millisecond, LatencyOnly: true})
+	start := time.Now()
+	_, err = engine.Peek("b:1")
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+
+	assert.Equal(t, 4, engine.Calls(OpSet))
+}
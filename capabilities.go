@@ -0,0 +1,157 @@
+package cachier
+
+import "time"
+
+// Capabilities lists the optional interfaces implemented by an engine, i.e. the fast paths Cache can use.
+// When a capability is missing Cache falls back to the CacheEngine methods:
+//
+//   - KeyIterator, KeysPager, KeysPrefixEngine, KeysPredicateEngine: the keys are listed by Keys and filtered
+//   - MultiGetter: GetMulti gets the keys one by one
+//   - MultiSetter: SetMulti stores the values one by one (not atomically)
+//   - MultiDeleter: DeleteKeys and the Delete* methods delete the keys one by one
+//   - PrefixPurger: PurgePrefix deletes the keys listed by KeysWithPrefix
+//   - Counter: CountPredicate(nil) counts the keys listed by Keys
+//   - CacheEngineTTL: SetWithTTL emulates the expiration in the process
+//   - Pinger: Healthy always succeeds
+//   - AtomicEngine, EntryAger, EntryInspector, RawEngine, MemoryReporter, ExpirationNotifier, EvictionReporter, Pinner:
+//     the features built on them return an error or report that they are not supported
+//
+// The wrapping engines (CircuitBreakerEngine, QuotaEngine, RateLimitedEngine, FailoverEngine, PartitionedEngine,
+// ReplicatedEngine and the engine returned by WrapEngine) forward CacheEngineTTL and EntryAger; Cache uses them
+// only if all the wrapped engines implement them, too (see EngineWrapper).
+type Capabilities struct {
+	KeyIterator         bool `json:"key_iterator"`
+	KeysPager           bool `json:"keys_pager"`
+	KeysPrefixEngine    bool `json:"keys_prefix"`
+	KeysPredicateEngine bool `json:"keys_predicate"`
+	MultiGetter         bool `json:"multi_getter"`
+	MultiSetter         bool `json:"multi_setter"`
+	MultiDeleter        bool `json:"multi_deleter"`
+	PrefixPurger        bool `json:"prefix_purger"`
+	Counter             bool `json:"counter"`
+	CacheEngineTTL      bool `json:"ttl"`
+	Pinger              bool `json:"pinger"`
+	AtomicEngine        bool `json:"atomic"`
+	EntryAger           bool `json:"entry_ager"`
+	EntryInspector      bool `json:"entry_inspector"`
+	RawEngine           bool `json:"raw"`
+	MemoryReporter      bool `json:"memory_reporter"`
+	ExpirationNotifier  bool `json:"expiration_notifier"`
+	EvictionReporter    bool `json:"eviction_reporter"`
+	Pinner              bool `json:"pinner"`
+}
+
+// EngineWrapper is implemented by the engines wrapping other engines; Unwrap returns the wrapped engine.
+// Engines wrapping several engines implement Unwrap() []CacheEngine instead.
+// The detection of the optional interfaces forwarded by the wrappers (CacheEngineTTL, EntryAger) follows Unwrap,
+// so they are used only if the wrapped engines implement them; the calls still go through the wrapper.
+type EngineWrapper interface {
+	Unwrap() CacheEngine
+}
+
+// engineAs returns the engine as the optional interface I if the engine and all the engines it wraps implement it
+func engineAs[I any](engine CacheEngine) (I, bool) {
+	var zero I
+	capability, ok := engine.(I)
+	if !ok {
+		return zero, false
+	}
+	switch wrapper := engine.(type) {
+	case EngineWrapper:
+		if _, ok := engineAs[I](wrapper.Unwrap()); !ok {
+			return zero, false
+		}
+	case interface{ Unwrap() []CacheEngine }:
+		for _, wrapped := range wrapper.Unwrap() {
+			if _, ok := engineAs[I](wrapped); !ok {
+				return zero, false
+			}
+		}
+	}
+	return capability, true
+}
+
+// setWithTTL stores the value with ttl into a wrapped engine; ErrTTLNotSupported is returned if it does not
+// implement CacheEngineTTL
+func setWithTTL(engine CacheEngine, key string, value interface{}, ttl time.Duration) error {
+	ttlEngine, ok := engine.(CacheEngineTTL)
+	if !ok {
+		return ErrTTLNotSupported
+	}
+	return ttlEngine.SetWithTTL(key, value, ttl)
+}
+
+// engineTTL returns the remaining TTL of the key in a wrapped engine; ErrTTLNotSupported is returned if it does not
+// implement CacheEngineTTL
+func engineTTL(engine CacheEngine, key string) (time.Duration, error) {
+	ttlEngine, ok := engine.(CacheEngineTTL)
+	if !ok {
+		return 0, ErrTTLNotSupported
+	}
+	return ttlEngine.TTL(key)
+}
+
+// engineAge returns the age of the key in a wrapped engine; ErrAgeUnknown is returned if it does not
+// implement EntryAger
+func engineAge(engine CacheEngine, key string) (time.Duration, error) {
+	ager, ok := engine.(EntryAger)
+	if !ok {
+		return 0, ErrAgeUnknown
+	}
+	return ager.Age(key)
+}
+
+// Counter is an optional interface of CacheEngine.
+// Engines implementing it count their keys without listing them.
+type Counter interface {
+	Count() (int, error)
+}
+
+// EngineCapabilities detects the optional interfaces implemented by the engine
+func EngineCapabilities(engine CacheEngine) Capabilities {
+	_, keyIterator := engine.(KeyIterator)
+	_, keysPager := engine.(KeysPager)
+	_, keysPrefix := engine.(KeysPrefixEngine)
+	_, keysPredicate := engine.(KeysPredicateEngine)
+	_, multiGetter := engine.(MultiGetter)
+	_, multiSetter := engine.(MultiSetter)
+	_, multiDeleter := engine.(MultiDeleter)
+	_, prefixPurger := engine.(PrefixPurger)
+	_, counter := engine.(Counter)
+	_, ttl := engineAs[CacheEngineTTL](engine)
+	_, pinger := engine.(Pinger)
+	_, atomic := engine.(AtomicEngine)
+	_, entryAger := engineAs[EntryAger](engine)
+	_, entryInspector := engine.(EntryInspector)
+	_, raw := engine.(RawEngine)
+	_, memoryReporter := engine.(MemoryReporter)
+	_, expirationNotifier := engine.(ExpirationNotifier)
+	_, evictionReporter := engine.(EvictionReporter)
+	_, pinner := engine.(Pinner)
+	return Capabilities{
+		KeyIterator:         keyIterator,
+		KeysPager:           keysPager,
+		KeysPrefixEngine:    keysPrefix,
+		KeysPredicateEngine: keysPredicate,
+		MultiGetter:         multiGetter,
+		MultiSetter:         multiSetter,
+		MultiDeleter:        multiDeleter,
+		PrefixPurger:        prefixPurger,
+		Counter:             counter,
+		CacheEngineTTL:      ttl,
+		Pinger:              pinger,
+		AtomicEngine:        atomic,
+		EntryAger:           entryAger,
+		EntryInspector:      entryInspector,
+		RawEngine:           raw,
+		MemoryReporter:      memoryReporter,
+		ExpirationNotifier:  expirationNotifier,
+		EvictionReporter:    evictionReporter,
+		Pinner:              pinner,
+	}
+}
+
+// Capabilities returns the optional interfaces implemented by the engine of the cache
+func (c *Cache[T]) Capabilities() Capabilities {
+	return EngineCapabilities(c.engine)
+}
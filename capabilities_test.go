@@ -0,0 +1,116 @@
+package cachier_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainEngine hides the optional interfaces of the wrapped engine
+type plainEngine struct {
+	cachier.CacheEngine
+}
+
+func TestCapabilities(t *testing.T) {
+	capabilities := cachier.EngineCapabilities(cachier.NewMemoryCache(0, 0))
+	assert.True(t, capabilities.Counter)
+	assert.True(t, capabilities.CacheEngineTTL)
+	assert.True(t, capabilities.AtomicEngine)
+	assert.False(t, capabilities.MultiGetter)
+
+	engine := cachiertest.NewEngine()
+	assert.Equal(t, cachier.Capabilities{}, cachier.MakeCache[int](plainEngine{engine}).Capabilities())
+}
+
+func TestCountWithCounter(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	counted := cachier.MakeCache[int](engine)
+	listed := cachier.MakeCache[int](plainEngine{engine})
+	for name, cache := range map[string]*cachier.Cache[int]{"counter": counted, "fallback": listed} {
+		t.Run(name, func(t *testing.T) {
+			require.Nil(t, cache.Purge())
+			for _, key := range []string{"a", "b", "c"} {
+				value := 1
+				require.Nil(t, cache.Set(key, &value))
+			}
+
+			count, err := cache.CountPredicate(nil)
+			require.Nil(t, err)
+			assert.Equal(t, 3, count)
+			count, err = cache.CountPredicate(func(key string) bool { return key != "a" })
+			require.Nil(t, err)
+			assert.Equal(t, 2, count)
+		})
+	}
+
+	// the counter does not list the keys
+	engine.InjectFault(cachiertest.OpKeys, cachiertest.Fault{})
+	_, err := counted.CountPredicate(nil)
+	assert.Nil(t, err)
+	_, err = listed.CountPredicate(nil)
+	assert.ErrorIs(t, err, cachiertest.ErrInjected)
+}
+
+func TestWrappersForwardCapabilities(t *testing.T) {
+	wrappers := map[string]func(engines ...cachier.CacheEngine) cachier.CacheEngine{
+		"circuit breaker": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.NewCircuitBreakerEngine(engines[0], cachier.CircuitBreakerOptions{})
+		},
+		"interceptors": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.WrapEngine(engines[0])
+		},
+		"quota": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.NewQuotaEngine(engines[0]).WithQuota(cachier.Quota{MaxKeys: 10})
+		},
+		"rate limit": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.NewRateLimitedEngine(engines[0], 0, 1)
+		},
+		"failover": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.NewFailoverEngine(engines[0], engines[1])
+		},
+		"partitioned": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.NewPartitionedEngine(engines, nil)
+		},
+		"replicated": func(engines ...cachier.CacheEngine) cachier.CacheEngine {
+			return cachier.NewReplicatedEngine(engines...).WithQuorum(len(engines))
+		},
+	}
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			engines := []*cachier.MemoryCache{cachier.NewMemoryCache(0, 0), cachier.NewMemoryCache(0, 0)}
+			cache := cachier.MakeCache[int](wrap(engines[0], engines[1]))
+			capabilities := cache.Capabilities()
+			assert.True(t, capabilities.CacheEngineTTL)
+			assert.True(t, capabilities.EntryAger)
+
+			value := 1
+			require.Nil(t, cache.SetWithTTL("key", &value, time.Minute))
+			stored := 0
+			for _, engine := range engines {
+				if ttl, err := engine.TTL("key"); err == nil {
+					// the TTL is stored by the wrapped engine, not emulated by the cache
+					assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+					stored++
+				}
+			}
+			assert.NotZero(t, stored)
+			ttl, err := cache.TTL("key")
+			require.Nil(t, err)
+			assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+			// the wrapped engines must support the capabilities, too
+			plain := cachier.MakeCache[int](wrap(plainEngine{engines[0]}, engines[1]))
+			capabilities = plain.Capabilities()
+			assert.False(t, capabilities.CacheEngineTTL)
+			assert.False(t, capabilities.EntryAger)
+			require.Nil(t, plain.SetWithTTL("plain", &value, time.Minute))
+			ttl, err = plain.TTL("plain")
+			require.Nil(t, err)
+			assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+		})
+	}
+}
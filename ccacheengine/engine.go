@@ -0,0 +1,260 @@
+// Package ccacheengine provides a cachier.CacheEngine using the layered cache of karlseguin/ccache,
+// an LRU cache with per-item TTL whose entries are grouped into layers which can be dropped at once.
+package ccacheengine
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/karlseguin/ccache/v2"
+)
+
+// noExpiration is the TTL of the entries stored without one, as ccache expires every entry
+const noExpiration = 100 * 365 * 24 * time.Hour
+
+// Engine is a cachier.CacheEngine storing the values as they are in a ccache.LayeredCache.
+// The part of the key up to the first separator (including it) is the primary key of the layer and the rest is
+// the secondary key, e.g. "user:1:name" with the separator ":" is stored under "user:" and "1:name".
+// Keys without the separator are stored in a layer of their own.
+// PurgePrefix of a whole layer (e.g. "user:") drops the layer without listing its keys.
+type Engine struct {
+	cache     *ccache.LayeredCache
+	separator string
+	ttl       time.Duration
+	mutex     sync.RWMutex
+	layers    map[string]struct{}
+}
+
+// New creates an Engine with the given ccache configuration (nil means ccache.Configure()).
+// Set stores the values for ttl; ttl <= 0 means the values do not expire.
+func New(config *ccache.Configuration, separator string, ttl time.Duration) *Engine {
+	if config == nil {
+		config = ccache.Configure()
+	}
+	if ttl <= 0 {
+		ttl = noExpiration
+	}
+	return &Engine{
+		cache:     ccache.Layered(config),
+		separator: separator,
+		ttl:       ttl,
+		layers:    make(map[string]struct{}),
+	}
+}
+
+// split splits the key into the primary key of its layer and the secondary key
+func (e *Engine) split(key string) (primary string, secondary string) {
+	if e.separator == "" {
+		return "", key
+	}
+	index := strings.Index(key, e.separator)
+	if index < 0 {
+		return "", key
+	}
+	index += len(e.separator)
+	return key[:index], key[index:]
+}
+
+// hasLayer reports whether the layer was written to
+func (e *Engine) hasLayer(primary string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	_, found := e.layers[primary]
+	return found
+}
+
+// layersWithPrefix returns the layers whose primary keys start with the prefix
+func (e *Engine) layersWithPrefix(prefix string) []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	layers := make([]string, 0, len(e.layers))
+	for primary := range e.layers {
+		if strings.HasPrefix(primary, prefix) {
+			layers = append(layers, primary)
+		}
+	}
+	return layers
+}
+
+// Get gets a value by given key
+func (e *Engine) Get(key string) (interface{}, error) {
+	item := e.cache.Get(e.split(key))
+	if item == nil || item.Expired() {
+		return nil, &cachier.KeyError{Key: key, Op: cachier.OpGet, Err: cachier.ErrNotFound}
+	}
+	return item.Value(), nil
+}
+
+// Peek gets a value by given key without promoting it in the LRU
+func (e *Engine) Peek(key string) (interface{}, error) {
+	primary, secondary := e.split(key)
+	if e.hasLayer(primary) {
+		item := e.cache.GetOrCreateSecondaryCache(primary).Get(secondary)
+		if item != nil && !item.Expired() {
+			return item.Value(), nil
+		}
+	}
+	return nil, &cachier.KeyError{Key: key, Op: cachier.OpGet, Err: cachier.ErrNotFound}
+}
+
+// Set stores given key-value pair into cache with the TTL of the engine
+func (e *Engine) Set(key string, value interface{}) error {
+	return e.SetWithTTL(key, value, e.ttl)
+}
+
+// SetWithTTL stores given key-value pair which expires after ttl instead of the TTL of the engine
+func (e *Engine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = noExpiration
+	}
+	primary, secondary := e.split(key)
+	// the layer is written to under the lock, so it cannot be dropped between being tracked and written
+	e.mutex.RLock()
+	if _, found := e.layers[primary]; found {
+		e.cache.Set(primary, secondary, value, ttl)
+		e.mutex.RUnlock()
+		return nil
+	}
+	e.mutex.RUnlock()
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.layers[primary] = struct{}{}
+	e.cache.Set(primary, secondary, value, ttl)
+	return nil
+}
+
+// TTL returns the remaining time to live of the key; 0 means the key does not expire
+func (e *Engine) TTL(key string) (time.Duration, error) {
+	item := e.cache.Get(e.split(key))
+	if item == nil || item.Expired() {
+		return 0, cachier.ErrNotFound
+	}
+	ttl := item.TTL()
+	if ttl > noExpiration/2 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Delete removes a key from cache; the layer is dropped with its last key
+func (e *Engine) Delete(key string) error {
+	primary, secondary := e.split(key)
+	if e.cache.Delete(primary, secondary) {
+		e.pruneLayer(primary)
+	}
+	return nil
+}
+
+// pruneLayer drops the layer if it holds no keys, so Peek and KeysWithPrefix do not walk it any more
+func (e *Engine) pruneLayer(primary string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, found := e.layers[primary]; !found {
+		return
+	}
+	empty := true
+	e.cache.ForEachFunc(primary, func(string, *ccache.Item) bool {
+		empty = false
+		return false
+	})
+	if empty {
+		delete(e.layers, primary)
+		e.cache.DeleteAll(primary)
+	}
+}
+
+// Keys returns all the not expired keys in cache
+func (e *Engine) Keys() ([]string, error) {
+	return e.KeysWithPrefix("")
+}
+
+// KeysWithPrefix returns the not expired keys starting with the prefix; only the layers the prefix can match
+// are walked
+func (e *Engine) KeysWithPrefix(prefix string) ([]string, error) {
+	primary, secondary := e.split(prefix)
+	layers := []string{primary}
+	if prefix == "" {
+		layers = e.layersWithPrefix("")
+	} else if primary == "" {
+		// the prefix matches the keys without the separator and the whole layers starting with it
+		layers = append(layers, e.layersWithPrefix(prefix)...)
+	}
+
+	var keys []string
+	for _, layer := range layers {
+		layerPrefix := secondary
+		if layer != primary {
+			layerPrefix = ""
+		}
+		empty := true
+		e.cache.ForEachFunc(layer, func(key string, item *ccache.Item) bool {
+			empty = false
+			if !item.Expired() && strings.HasPrefix(key, layerPrefix) {
+				keys = append(keys, layer+key)
+			}
+			return true
+		})
+		if empty {
+			// the keys of the layer were evicted
+			e.pruneLayer(layer)
+		}
+	}
+	return keys, nil
+}
+
+// PurgePrefix removes all the records with keys starting with the given prefix.
+// Whole layers matching the prefix are dropped at once, the other keys are deleted by the prefix of their
+// secondary keys.
+func (e *Engine) PurgePrefix(prefix string) error {
+	if prefix == "" {
+		return e.Purge()
+	}
+	primary, secondary := e.split(prefix)
+	if primary != "" {
+		if secondary == "" {
+			e.dropLayer(primary)
+		} else {
+			e.cache.DeletePrefix(primary, secondary)
+		}
+		return nil
+	}
+
+	e.cache.DeletePrefix("", prefix)
+	for _, layer := range e.layersWithPrefix(prefix) {
+		if layer != "" {
+			e.dropLayer(layer)
+		}
+	}
+	return nil
+}
+
+// dropLayer removes all the records of the layer
+func (e *Engine) dropLayer(primary string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.layers, primary)
+	e.cache.DeleteAll(primary)
+}
+
+// Purge removes all records from the cache
+func (e *Engine) Purge() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.layers = make(map[string]struct{})
+	e.cache.Clear()
+	return nil
+}
+
+// Ping always succeeds as Engine is in memory
+func (e *Engine) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops the background goroutine of ccache; the Engine must not be used afterwards
+func (e *Engine) Close() {
+	e.cache.Stop()
+}
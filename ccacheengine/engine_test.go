@@ -0,0 +1,128 @@
+package ccacheengine
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/karlseguin/ccache/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sortedKeys returns a function sorting the listed keys
+func sortedKeys(t *testing.T) func(keys []string, err error) []string {
+	return func(keys []string, err error) []string {
+		require.Nil(t, err)
+		sort.Strings(keys)
+		return keys
+	}
+}
+
+func TestEngine(t *testing.T) {
+	engine := New(ccache.Configure().MaxSize(100), ":", 0)
+	defer engine.Close()
+
+	require.Nil(t, engine.Set("user:1", "a"))
+	require.Nil(t, engine.Set("plain", "b"))
+	value, err := engine.Get("user:1")
+	require.Nil(t, err)
+	assert.Equal(t, "a", value)
+	value, err = engine.Peek("plain")
+	require.Nil(t, err)
+	assert.Equal(t, "b", value)
+	ttl, err := engine.TTL("user:1")
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	assert.Equal(t, []string{"plain", "user:1"}, sortedKeys(t)(engine.Keys()))
+
+	require.Nil(t, engine.Delete("user:1"))
+	_, err = engine.Get("user:1")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	_, err = engine.Peek("user:1")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	require.Nil(t, engine.Purge())
+	assert.Empty(t, sortedKeys(t)(engine.Keys()))
+}
+
+func TestEngineTTL(t *testing.T) {
+	engine := New(nil, ":", time.Minute)
+	defer engine.Close()
+
+	require.Nil(t, engine.Set("a:1", 1))
+	require.Nil(t, engine.SetWithTTL("a:2", 2, 10*time.Millisecond))
+	ttl, err := engine.TTL("a:1")
+	require.Nil(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = engine.Get("a:2")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	assert.Equal(t, []string{"a:1"}, sortedKeys(t)(engine.Keys()))
+}
+
+func TestEnginePurgePrefix(t *testing.T) {
+	engine := New(nil, ":", 0)
+	defer engine.Close()
+
+	for _, key := range []string{"user:1", "user:2:name", "users:1", "order:1", "user", "userless"} {
+		require.Nil(t, engine.Set(key, key))
+	}
+	assert.Equal(t, []string{"user:1", "user:2:name"}, sortedKeys(t)(engine.KeysWithPrefix("user:")))
+	assert.Equal(t, []string{"user:2:name"}, sortedKeys(t)(engine.KeysWithPrefix("user:2")))
+	assert.Equal(t, []string{"user", "user:1", "user:2:name", "userless", "users:1"},
+		sortedKeys(t)(engine.KeysWithPrefix("user")))
+
+	require.Nil(t, engine.PurgePrefix("user:2"))
+	assert.Equal(t, []string{"user:1"}, sortedKeys(t)(engine.KeysWithPrefix("user:")))
+
+	require.Nil(t, engine.PurgePrefix("user:"))
+	assert.Equal(t, []string{"order:1", "user", "userless", "users:1"}, sortedKeys(t)(engine.Keys()))
+
+	require.Nil(t, engine.PurgePrefix("users"))
+	assert.Equal(t, []string{"order:1", "user", "userless"}, sortedKeys(t)(engine.Keys()))
+
+	require.Nil(t, engine.PurgePrefix("user"))
+	assert.Equal(t, []string{"order:1"}, sortedKeys(t)(engine.Keys()))
+}
+
+func TestEngineDeletePrunesLayers(t *testing.T) {
+	engine := New(nil, ":", 0)
+	defer engine.Close()
+
+	require.Nil(t, engine.Set("user:1", 1))
+	require.Nil(t, engine.Set("user:2", 2))
+	require.Nil(t, engine.Set("order:1", 3))
+	require.Nil(t, engine.Delete("user:1"))
+	assert.True(t, engine.hasLayer("user:"))
+
+	require.Nil(t, engine.Delete("user:2"))
+	assert.False(t, engine.hasLayer("user:"))
+	assert.Equal(t, []string{"order:"}, engine.layersWithPrefix(""))
+	_, err := engine.Peek("user:2")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	// the layer is tracked again once written to
+	require.Nil(t, engine.Set("user:3", 3))
+	assert.Equal(t, []string{"order:1", "user:3"}, sortedKeys(t)(engine.Keys()))
+}
+
+func TestEngineCache(t *testing.T) {
+	engine := New(nil, ":", 0)
+	defer engine.Close()
+	cache := cachier.MakeCache[string](engine)
+
+	value := "value"
+	require.Nil(t, cache.Set("tenant:1:a", &value))
+	require.Nil(t, cache.SetWithTTL("tenant:1:b", &value, time.Minute))
+	got, err := cache.Get("tenant:1:a")
+	require.Nil(t, err)
+	assert.Equal(t, "value", *got)
+
+	require.Nil(t, cache.PurgePrefix("tenant:"))
+	_, err = cache.Get("tenant:1:b")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
@@ -0,0 +1,30 @@
+module github.com/datasapiens/cachier/ccacheengine
+
+go 1.20
+
+require (
+	github.com/datasapiens/cachier v0.0.0-00010101000000-000000000000
+	github.com/karlseguin/ccache/v2 v2.0.8
+	github.com/stretchr/testify v1.7.0
+)
+
+require (
+	github.com/DataDog/zstd v1.4.8 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/cloudflare/golz4 v0.0.0-20150217214814-ef862a3cdc58 // indirect
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/go-redis/redis/v8 v8.8.0 // indirect
+	github.com/golang/snappy v0.0.3 // indirect
+	github.com/hashicorp/golang-lru v0.5.4 // indirect
+	github.com/klauspost/compress v1.12.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v0.19.0 // indirect
+	go.opentelemetry.io/otel/metric v0.19.0 // indirect
+	go.opentelemetry.io/otel/trace v0.19.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/time v0.5.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+replace github.com/datasapiens/cachier => ../
@@ -0,0 +1,353 @@
+package cachier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by CircuitBreakerEngine while the circuit is open
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrEngineUnavailable)
+
+// CircuitState is the state of a CircuitBreakerEngine
+type CircuitState int
+
+// Circuit states
+const (
+	// CircuitClosed passes all operations to the engine
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all operations with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe operations through
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreakerOptions configures CircuitBreakerEngine
+type CircuitBreakerOptions struct {
+	// FailureRatio opens the circuit when failures/requests within Window reaches it (default 0.5)
+	FailureRatio float64
+	// MinRequests is the number of requests within Window needed before the circuit may open (default 10)
+	MinRequests int
+	// Window is the period in which requests and failures are counted (default 10s)
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before probing the engine (default 5s)
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of successful probes needed to close the circuit (default 1)
+	HalfOpenProbes int
+	// IsFailure decides whether an error counts as an engine failure.
+	// By default ErrNotFound, ErrSerialization, ErrCorrupted, ErrAgeUnknown and ErrTTLNotSupported are not failures.
+	IsFailure func(err error) bool
+	// OnStateChange is called whenever the circuit changes its state; it is called without holding the lock
+	// of the circuit, so it may use the engine
+	OnStateChange func(from, to CircuitState)
+	// Clock is used to measure the window and the open timeout (default SystemClock)
+	Clock Clock
+}
+
+// CircuitBreakerEngine wraps a CacheEngine with a circuit breaker.
+// When too many operations fail (e.g. Redis is down) the circuit opens and all operations
+// fail fast with ErrCircuitOpen instead of waiting for timeouts, so GetOrCompute falls back
+// to the evaluator immediately. After OpenTimeout probe operations are let through and
+// the circuit closes again when they succeed.
+type CircuitBreakerEngine struct {
+	engine  CacheEngine
+	options CircuitBreakerOptions
+
+	mutex       sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	probes      int
+	successes   int
+	// generation counts the state changes, so the results of operations allowed before one are ignored
+	generation uint64
+	// changes are the state changes to be reported by unlock
+	changes []circuitChange
+}
+
+type circuitChange struct {
+	from, to CircuitState
+}
+
+// NewCircuitBreakerEngine creates a CircuitBreakerEngine wrapping the engine
+func NewCircuitBreakerEngine(engine CacheEngine, options CircuitBreakerOptions) *CircuitBreakerEngine {
+	if options.FailureRatio <= 0 {
+		options.FailureRatio = 0.5
+	}
+	if options.MinRequests <= 0 {
+		options.MinRequests = 10
+	}
+	if options.Window <= 0 {
+		options.Window = 10 * time.Second
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = 5 * time.Second
+	}
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = 1
+	}
+	if options.IsFailure == nil {
+		options.IsFailure = isEngineFailure
+	}
+	options.Clock = clockOrDefault(options.Clock)
+
+	return &CircuitBreakerEngine{
+		engine:      engine,
+		options:     options,
+		windowStart: options.Clock.Now(),
+	}
+}
+
+// isEngineFailure reports whether err is caused by the engine rather than by the key or the data
+func isEngineFailure(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrSerialization) && !errors.Is(err, ErrCorrupted) &&
+		!errors.Is(err, ErrAgeUnknown) && !errors.Is(err, ErrTTLNotSupported)
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreakerEngine) State() CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.state == CircuitOpen && cb.options.Clock.Since(cb.openedAt) >= cb.options.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// Reset closes the circuit and forgets all counters
+func (cb *CircuitBreakerEngine) Reset() {
+	cb.mutex.Lock()
+	defer cb.unlock()
+	cb.setState(CircuitClosed)
+}
+
+// allow decides whether an operation may be passed to the engine and returns the generation of the state
+// it was allowed in, which is passed to record with its result
+func (cb *CircuitBreakerEngine) allow() (uint64, bool) {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.options.Clock.Since(cb.openedAt) < cb.options.OpenTimeout {
+			return 0, false
+		}
+		cb.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if cb.probes >= cb.options.HalfOpenProbes {
+			return 0, false
+		}
+		cb.probes++
+	}
+	return cb.generation, true
+}
+
+// record updates the counters with the result of an operation allowed in the generation.
+// Results of operations allowed before the last state change are ignored, e.g. a slow failure started
+// while the circuit was closed does not reopen the circuit after a successful probe.
+func (cb *CircuitBreakerEngine) record(generation uint64, err error) {
+	failed := err != nil && cb.options.IsFailure(err)
+
+	cb.mutex.Lock()
+	defer cb.unlock()
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.probes--
+		if failed {
+			cb.setState(CircuitOpen)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.options.HalfOpenProbes {
+			cb.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		now := cb.options.Clock.Now()
+		if now.Sub(cb.windowStart) >= cb.options.Window {
+			cb.windowStart = now
+			cb.requests = 0
+			cb.failures = 0
+		}
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+		if cb.requests >= cb.options.MinRequests &&
+			float64(cb.failures)/float64(cb.requests) >= cb.options.FailureRatio {
+			cb.setState(CircuitOpen)
+		}
+	}
+}
+
+// setState switches the state and resets the counters; the mutex must be held
+func (cb *CircuitBreakerEngine) setState(state CircuitState) {
+	from := cb.state
+	cb.state = state
+	cb.generation++
+	cb.requests = 0
+	cb.failures = 0
+	cb.probes = 0
+	cb.successes = 0
+	cb.windowStart = cb.options.Clock.Now()
+	if state == CircuitOpen {
+		cb.openedAt = cb.windowStart
+	}
+	if from != state && cb.options.OnStateChange != nil {
+		cb.changes = append(cb.changes, circuitChange{from: from, to: state})
+	}
+}
+
+// unlock releases the mutex and then reports the state changes made while it was held
+func (cb *CircuitBreakerEngine) unlock() {
+	changes := cb.changes
+	cb.changes = nil
+	cb.mutex.Unlock()
+	for _, change := range changes {
+		cb.options.OnStateChange(change.from, change.to)
+	}
+}
+
+// Get gets a value from the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) Get(key string) (interface{}, error) {
+	generation, allowed := cb.allow()
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+	value, err := cb.engine.Get(key)
+	cb.record(generation, err)
+	return value, err
+}
+
+// Peek gets a value from the engine without updating recency unless the circuit is open
+func (cb *CircuitBreakerEngine) Peek(key string) (interface{}, error) {
+	generation, allowed := cb.allow()
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+	value, err := cb.engine.Peek(key)
+	cb.record(generation, err)
+	return value, err
+}
+
+// Set stores a value into the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) Set(key string, value interface{}) error {
+	generation, allowed := cb.allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+	err := cb.engine.Set(key, value)
+	cb.record(generation, err)
+	return err
+}
+
+// SetWithTTL stores a value which expires after ttl into the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if _, ok := cb.engine.(CacheEngineTTL); !ok {
+		return ErrTTLNotSupported
+	}
+	generation, allowed := cb.allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+	err := setWithTTL(cb.engine, key, value, ttl)
+	cb.record(generation, err)
+	return err
+}
+
+// TTL returns the remaining time to live of the key in the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) TTL(key string) (time.Duration, error) {
+	if _, ok := cb.engine.(CacheEngineTTL); !ok {
+		return 0, ErrTTLNotSupported
+	}
+	generation, allowed := cb.allow()
+	if !allowed {
+		return 0, ErrCircuitOpen
+	}
+	ttl, err := engineTTL(cb.engine, key)
+	cb.record(generation, err)
+	return ttl, err
+}
+
+// Age returns how long ago the key was stored in the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) Age(key string) (time.Duration, error) {
+	if _, ok := cb.engine.(EntryAger); !ok {
+		return 0, ErrAgeUnknown
+	}
+	generation, allowed := cb.allow()
+	if !allowed {
+		return 0, ErrCircuitOpen
+	}
+	age, err := engineAge(cb.engine, key)
+	cb.record(generation, err)
+	return age, err
+}
+
+// Delete removes a value from the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) Delete(key string) error {
+	generation, allowed := cb.allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+	err := cb.engine.Delete(key)
+	cb.record(generation, err)
+	return err
+}
+
+// Keys lists the keys of the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) Keys() ([]string, error) {
+	generation, allowed := cb.allow()
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+	keys, err := cb.engine.Keys()
+	cb.record(generation, err)
+	return keys, err
+}
+
+// Purge removes all values from the engine unless the circuit is open
+func (cb *CircuitBreakerEngine) Purge() error {
+	generation, allowed := cb.allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+	err := cb.engine.Purge()
+	cb.record(generation, err)
+	return err
+}
+
+// Unwrap returns the wrapped engine
+func (cb *CircuitBreakerEngine) Unwrap() CacheEngine {
+	return cb.engine
+}
+
+// Ping fails with ErrCircuitOpen while the circuit is open, otherwise it checks the wrapped engine
+func (cb *CircuitBreakerEngine) Ping(ctx context.Context) error {
+	if cb.State() == CircuitOpen {
+		return ErrCircuitOpen
+	}
+	if pinger, ok := cb.engine.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
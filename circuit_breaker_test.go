@@ -0,0 +1,126 @@
+package cachier_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerEngine(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachiertest.NewEngine()
+	transitions := make([]string, 0)
+	cb := cachier.NewCircuitBreakerEngine(engine, cachier.CircuitBreakerOptions{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		OpenTimeout:  time.Second,
+		Clock:        clock,
+		OnStateChange: func(from, to cachier.CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	cache := cachier.MakeCache[int](cb)
+
+	// misses are not failures
+	for i := 0; i < 4; i++ {
+		_, err := cache.Get("missing")
+		assert.ErrorIs(t, err, cachier.ErrNotFound)
+	}
+	assert.Equal(t, cachier.CircuitClosed, cb.State())
+
+	engine.InjectFault(cachiertest.OpGet, cachiertest.Fault{})
+	for i := 0; i < 4; i++ {
+		_, err := cache.Get("key")
+		assert.ErrorIs(t, err, cachiertest.ErrInjected)
+	}
+	assert.Equal(t, cachier.CircuitOpen, cb.State())
+
+	calls := engine.Calls(cachiertest.OpGet)
+	_, err := cache.Get("key")
+	assert.ErrorIs(t, err, cachier.ErrCircuitOpen)
+	assert.ErrorIs(t, err, cachier.ErrEngineUnavailable)
+	assert.Equal(t, calls, engine.Calls(cachiertest.OpGet))
+
+	// failed probe opens the circuit again
+	clock.Advance(time.Second)
+	assert.Equal(t, cachier.CircuitHalfOpen, cb.State())
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, cachiertest.ErrInjected)
+	assert.Equal(t, cachier.CircuitOpen, cb.State())
+
+	// successful probe closes it
+	engine.ClearFaults()
+	clock.Advance(time.Second)
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	assert.Equal(t, cachier.CircuitClosed, cb.State())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+func TestCircuitBreakerEngineStateChangeUsesEngine(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	var cb *cachier.CircuitBreakerEngine
+	states := make([]cachier.CircuitState, 0)
+	cb = cachier.NewCircuitBreakerEngine(engine, cachier.CircuitBreakerOptions{
+		MinRequests: 1,
+		OnStateChange: func(from, to cachier.CircuitState) {
+			// the callback runs without the lock of the circuit
+			states = append(states, cb.State())
+		},
+	})
+	engine.InjectFault(cachiertest.OpGet, cachiertest.Fault{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cb.Get("key")
+		cb.Reset()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStateChange deadlocked")
+	}
+	assert.Equal(t, []cachier.CircuitState{cachier.CircuitOpen, cachier.CircuitClosed}, states)
+}
+
+func TestCircuitBreakerEngineIgnoresStaleResults(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachiertest.NewEngine()
+	cb := cachier.NewCircuitBreakerEngine(engine, cachier.CircuitBreakerOptions{
+		MinRequests: 1,
+		OpenTimeout: time.Second,
+		Clock:       clock,
+	})
+
+	engine.InjectFault(cachiertest.OpGet, cachiertest.Fault{Latency: 100 * time.Millisecond})
+	slow := make(chan error)
+	go func() {
+		_, err := cb.Get("slow")
+		slow <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the circuit opens and closes again while the slow operation runs
+	engine.InjectFault(cachiertest.OpPeek, cachiertest.Fault{})
+	_, err := cb.Peek("key")
+	assert.ErrorIs(t, err, cachiertest.ErrInjected)
+	assert.Equal(t, cachier.CircuitOpen, cb.State())
+	clock.Advance(time.Second)
+	assert.Nil(t, cb.Set("key", 1))
+	assert.Equal(t, cachier.CircuitClosed, cb.State())
+
+	// its failure was allowed by the previous closed circuit
+	assert.ErrorIs(t, <-slow, cachiertest.ErrInjected)
+	assert.Equal(t, cachier.CircuitClosed, cb.State())
+}
@@ -0,0 +1,54 @@
+package cachier
+
+import "time"
+
+// Clock abstracts time so time dependent behaviour (expiration, janitors) can be tested deterministically
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker abstracts time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the Clock using the system time
+type SystemClock struct{}
+
+// Now returns the current time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Since returns the time elapsed since t
+func (SystemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// NewTicker returns a Ticker backed by time.Ticker
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// clockOrDefault returns SystemClock if clock is nil
+func clockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock{}
+	}
+	return clock
+}
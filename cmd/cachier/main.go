@@ -0,0 +1,165 @@
+// Command cachier inspects entries stored in Redis by cachier.RedisCache.
+//
+// Usage:
+//
+//	cachier [flags] keys [prefix]
+//	cachier [flags] get <key>
+//
+// The get command strips the compression footer, decompresses the payload
+// and pretty-prints it when it is JSON. Other payloads (e.g. gob, which cannot be
+// decoded without the Go type) are printed as a hex dump.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/go-redis/redis/v8"
+)
+
+const scanCount = 1000
+
+type options struct {
+	keyPrefix   string
+	compression string
+	format      string
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:6379", "redis address")
+	password := flag.String("password", os.Getenv("REDIS_PASSWORD"), "redis password (default $REDIS_PASSWORD)")
+	db := flag.Int("db", 0, "redis database")
+	opts := options{}
+	flag.StringVar(&opts.keyPrefix, "key-prefix", "", "key prefix used by the RedisCache")
+	flag.StringVar(&opts.compression, "compression", "auto", "payload compression: auto, on, off")
+	flag.StringVar(&opts.format, "format", "auto", "output format: auto, json, raw, hex")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] keys [prefix] | get <key>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     *addr,
+		Password: *password,
+		DB:       *db,
+	})
+	defer client.Close()
+
+	var err error
+	switch flag.Arg(0) {
+	case "keys":
+		err = listKeys(context.Background(), os.Stdout, client, opts, flag.Arg(1))
+	case "get":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = getEntry(context.Background(), os.Stdout, client, opts, flag.Arg(1))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cachier:", err)
+		os.Exit(1)
+	}
+}
+
+func listKeys(ctx context.Context, w io.Writer, client *redis.Client, opts options, prefix string) error {
+	iter := client.Scan(ctx, 0, opts.keyPrefix+prefix+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		fmt.Fprintln(w, strings.TrimPrefix(iter.Val(), opts.keyPrefix))
+	}
+	return iter.Err()
+}
+
+func getEntry(ctx context.Context, w io.Writer, client *redis.Client, opts options, key string) error {
+	payload, err := client.Get(ctx, opts.keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return fmt.Errorf("key %s not found", key)
+	} else if err != nil {
+		return err
+	}
+
+	ttl, err := client.PTTL(ctx, opts.keyPrefix+key).Result()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "key: %s\nttl: %s\nstored size: %d B\n", key, formatTTL(ttl), len(payload))
+
+	data, err := decompress(payload, opts.compression)
+	if err != nil {
+		return err
+	}
+	if len(data) != len(payload) {
+		fmt.Fprintf(w, "decompressed size: %d B\n", len(data))
+	}
+	fmt.Fprintln(w)
+
+	return printPayload(w, data, opts.format)
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl < 0 {
+		return "none"
+	}
+	return ttl.String()
+}
+
+// decompress removes the compression footer and decompresses the payload
+func decompress(payload []byte, mode string) ([]byte, error) {
+	if mode == "off" || len(payload) == 0 {
+		return payload, nil
+	}
+
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := engine.Decompress(payload)
+	if err != nil {
+		if mode == "auto" {
+			// probably not compressed
+			return payload, nil
+		}
+		return nil, fmt.Errorf("cannot decompress payload: %w", err)
+	}
+	return data, nil
+}
+
+func printPayload(w io.Writer, data []byte, format string) error {
+	switch format {
+	case "raw":
+		_, err := w.Write(data)
+		return err
+	case "hex":
+		_, err := io.WriteString(w, hex.Dump(data))
+		return err
+	case "json", "auto":
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			if format == "json" {
+				return fmt.Errorf("payload is not valid JSON: %w", err)
+			}
+			_, err := io.WriteString(w, hex.Dump(data))
+			return err
+		}
+		out.WriteByte('\n')
+		_, err := out.WriteTo(w)
+		return err
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressAndPrint(t *testing.T) {
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	input := []byte(`{"key":"` + strings.Repeat("hello world", 200) + `"}`)
+	payload, err := engine.Compress(input)
+	require.Nil(t, err)
+
+	data, err := decompress(payload, "auto")
+	require.Nil(t, err)
+	assert.Equal(t, input, data)
+
+	var out bytes.Buffer
+	require.Nil(t, printPayload(&out, []byte(`{"a":1}`), "auto"))
+	assert.Equal(t, "{\n  \"a\": 1\n}\n", out.String())
+
+	out.Reset()
+	require.Nil(t, printPayload(&out, []byte{0xff, 0x01}, "auto"))
+	assert.Contains(t, out.String(), "ff 01")
+}
@@ -0,0 +1,176 @@
+package cachier
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrCodecNotRegistered is returned by CodecRegistry for values of types without a codec
+	ErrCodecNotRegistered = errors.New("no codec registered")
+	// ErrCodecConflict is returned by RegisterCodec if the type or the name is already registered
+	ErrCodecConflict = errors.New("codec already registered")
+)
+
+// Codec marshals and unmarshals values for the engines
+type Codec interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(b []byte, value *interface{}) error
+}
+
+// JSONCodec marshals and unmarshals T values with encoding/json
+type JSONCodec[T any] struct{}
+
+// Marshal encodes the value
+func (JSONCodec[T]) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes the value into a new T and stores its pointer into value,
+// so the engines return *T instead of generic JSON values
+func (JSONCodec[T]) Unmarshal(b []byte, value *interface{}) error {
+	var result T
+	if err := json.Unmarshal(b, &result); err != nil {
+		return err
+	}
+	*value = &result
+	return nil
+}
+
+// GobCodec marshals and unmarshals T values with encoding/gob for the engines,
+// e.g. NewRedisCache(client, prefix, codec.Marshal, codec.Unmarshal, ttl, compressionEngine)
+type GobCodec[T any] struct{}
+
+// NewGobCodec creates a GobCodec and registers T, []T and map[string]T with gob,
+// so they can also be encoded as interface values (e.g. in interface{} fields)
+func NewGobCodec[T any]() GobCodec[T] {
+	var value T
+	if reflect.TypeOf(value) != nil {
+		gob.Register(value)
+		gob.Register([]T{})
+		gob.Register(map[string]T{})
+	}
+	return GobCodec[T]{}
+}
+
+// Marshal encodes the value (T or *T)
+func (GobCodec[T]) Marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal decodes the value into a new T and stores its pointer into value
+func (GobCodec[T]) Unmarshal(b []byte, value *interface{}) error {
+	var result T
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&result); err != nil {
+		return err
+	}
+	*value = &result
+	return nil
+}
+
+// CodecRegistry selects the codec by the type of the value, so values of different types can be stored with different
+// serializations through the same engine, e.g. with Cache[A] and Cache[B] sharing a RedisCache.
+// The encoded values are prefixed with the name of the codec they were encoded with.
+type CodecRegistry struct {
+	fallback Codec
+
+	mutex  sync.RWMutex
+	byType map[reflect.Type]namedCodec
+	byName map[string]Codec
+}
+
+type namedCodec struct {
+	name  string
+	codec Codec
+}
+
+// NewCodecRegistry creates a CodecRegistry which encodes the values of unregistered types with fallback;
+// nil fallback means the unregistered types cannot be encoded (ErrCodecNotRegistered)
+func NewCodecRegistry(fallback Codec) *CodecRegistry {
+	return &CodecRegistry{
+		fallback: fallback,
+		byType:   make(map[reflect.Type]namedCodec),
+		byName:   make(map[string]Codec),
+	}
+}
+
+// RegisterCodec registers the codec for the values of T (and *T) under the given name, which is stored with
+// the encoded values, so it must not change once they are stored; it must have 1 to 255 bytes.
+func RegisterCodec[T any](registry *CodecRegistry, name string, codec Codec) error {
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("cachier: invalid codec name %q", name)
+	}
+	valueType := reflect.TypeOf((*T)(nil)).Elem()
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if _, found := registry.byType[valueType]; found {
+		return fmt.Errorf("%w: type %s", ErrCodecConflict, valueType)
+	}
+	if _, found := registry.byName[name]; found {
+		return fmt.Errorf("%w: name %s", ErrCodecConflict, name)
+	}
+	registry.byType[valueType] = namedCodec{name: name, codec: codec}
+	registry.byName[name] = codec
+	return nil
+}
+
+// Marshal encodes the value with the codec registered for its type
+func (r *CodecRegistry) Marshal(value interface{}) ([]byte, error) {
+	named, found := r.lookupType(reflect.TypeOf(value))
+	if !found {
+		return nil, fmt.Errorf("%w: type %T", ErrCodecNotRegistered, value)
+	}
+	encoded, err := named.codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, 1+len(named.name)+len(encoded))
+	b = append(b, byte(len(named.name)))
+	b = append(b, named.name...)
+	return append(b, encoded...), nil
+}
+
+// Unmarshal decodes the value with the codec it was encoded with
+func (r *CodecRegistry) Unmarshal(b []byte, value *interface{}) error {
+	if len(b) == 0 || len(b) < 1+int(b[0]) {
+		return ErrWrongDataType
+	}
+	name, encoded := string(b[1:1+b[0]]), b[1+b[0]:]
+	codec := r.fallback
+	if name != "" {
+		r.mutex.RLock()
+		codec = r.byName[name]
+		r.mutex.RUnlock()
+	}
+	if codec == nil {
+		return fmt.Errorf("%w: name %q", ErrCodecNotRegistered, name)
+	}
+	return codec.Unmarshal(encoded, value)
+}
+
+// lookupType returns the codec of the type, or of the type it points to, or the fallback codec with empty name
+func (r *CodecRegistry) lookupType(valueType reflect.Type) (namedCodec, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if valueType != nil {
+		if named, found := r.byType[valueType]; found {
+			return named, true
+		}
+		if valueType.Kind() == reflect.Pointer {
+			if named, found := r.byType[valueType.Elem()]; found {
+				return named, true
+			}
+		}
+	}
+	return namedCodec{codec: r.fallback}, r.fallback != nil
+}
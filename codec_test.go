@@ -0,0 +1,95 @@
+package cachier
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type gobCodecValue struct {
+	ID   int
+	Name string
+}
+
+func TestGobCodec(t *testing.T) {
+	codec := NewGobCodec[gobCodecValue]()
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, compression.CompressionParams{compression.CompressionParamMinInputLen: 0})
+	require.Nil(t, err)
+	lc, err := NewLRUCache(10, codec.Marshal, codec.Unmarshal, engine)
+	require.Nil(t, err)
+	cache := MakeCache[gobCodecValue](lc)
+
+	value := gobCodecValue{ID: 1, Name: "one"}
+	require.Nil(t, cache.Set("key", &value))
+	got, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *got)
+
+	var decoded interface{}
+	assert.NotNil(t, codec.Unmarshal([]byte("garbage"), &decoded))
+
+	// the registered types can be encoded as interface values
+	var buffer bytes.Buffer
+	wrapped := struct{ Values interface{} }{Values: []gobCodecValue{value}}
+	require.Nil(t, gob.NewEncoder(&buffer).Encode(wrapped))
+	var result struct{ Values interface{} }
+	require.Nil(t, gob.NewDecoder(&buffer).Decode(&result))
+	assert.Equal(t, []gobCodecValue{value}, result.Values)
+
+	// interface types are not registered
+	assert.NotPanics(t, func() { NewGobCodec[interface{}]() })
+}
+
+type jsonCodecValue struct {
+	Tags []string `json:"tags"`
+}
+
+func TestCodecRegistry(t *testing.T) {
+	registry := NewCodecRegistry(nil)
+	require.Nil(t, RegisterCodec[gobCodecValue](registry, "gob", NewGobCodec[gobCodecValue]()))
+	require.Nil(t, RegisterCodec[jsonCodecValue](registry, "json", JSONCodec[jsonCodecValue]{}))
+	assert.ErrorIs(t, RegisterCodec[gobCodecValue](registry, "other", JSONCodec[gobCodecValue]{}), ErrCodecConflict)
+	assert.ErrorIs(t, RegisterCodec[int](registry, "json", JSONCodec[int]{}), ErrCodecConflict)
+	assert.NotNil(t, RegisterCodec[int](registry, "", JSONCodec[int]{}))
+
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, compression.CompressionParams{compression.CompressionParamMinInputLen: 0})
+	require.Nil(t, err)
+	lc, err := NewLRUCache(10, registry.Marshal, registry.Unmarshal, engine)
+	require.Nil(t, err)
+	gobCache, jsonCache := MakeCache[gobCodecValue](lc), MakeCache[jsonCodecValue](lc)
+
+	gobValue := gobCodecValue{ID: 1, Name: "one"}
+	jsonValue := jsonCodecValue{Tags: []string{"a"}}
+	require.Nil(t, gobCache.Set("gob", &gobValue))
+	require.Nil(t, jsonCache.Set("json", &jsonValue))
+	gotGob, err := gobCache.Get("gob")
+	require.Nil(t, err)
+	assert.Equal(t, gobValue, *gotGob)
+	gotJSON, err := jsonCache.Get("json")
+	require.Nil(t, err)
+	assert.Equal(t, jsonValue, *gotJSON)
+
+	encoded, err := registry.Marshal(&jsonValue)
+	require.Nil(t, err)
+	assert.Equal(t, "\x04json{\"tags\":[\"a\"]}", string(encoded))
+
+	_, err = registry.Marshal(1)
+	assert.ErrorIs(t, err, ErrCodecNotRegistered)
+	var value interface{}
+	assert.ErrorIs(t, registry.Unmarshal([]byte("\x03xml<a/>"), &value), ErrCodecNotRegistered)
+	assert.ErrorIs(t, registry.Unmarshal([]byte("\x09json"), &value), ErrWrongDataType)
+}
+
+func TestCodecRegistryFallback(t *testing.T) {
+	registry := NewCodecRegistry(JSONCodec[map[string]int]{})
+	encoded, err := registry.Marshal(map[string]int{"a": 1})
+	require.Nil(t, err)
+	assert.Equal(t, "\x00{\"a\":1}", string(encoded))
+	var value interface{}
+	require.Nil(t, registry.Unmarshal(encoded, &value))
+	assert.Equal(t, &map[string]int{"a": 1}, value)
+}
@@ -0,0 +1,74 @@
+package compression
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize limits the size of the buffers returned to the pools, so a few huge values
+// do not keep large buffers alive
+const maxPooledBufferSize = 1 << 20
+
+// maxSizeHintRatio limits the capacity preallocated for decompressed data relative to the size of the compressed
+// data, so corrupted footers do not allocate huge buffers; data compressed better are still decompressed,
+// the buffer grows as needed
+const maxSizeHintRatio = 1 << 10
+
+// bufferPool holds the scratch buffers of the stream based providers
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, defaultNotCompressedBufferSize))
+	},
+}
+
+// scratchPool holds the scratch slices of the block based providers
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		scratch := make([]byte, 0, defaultNotCompressedBufferSize)
+		return &scratch
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buffer)
+	}
+}
+
+// getScratch returns an empty slice with the capacity of at least size
+func getScratch(size int) *[]byte {
+	scratch := scratchPool.Get().(*[]byte)
+	if cap(*scratch) < size {
+		*scratch = make([]byte, 0, size)
+	}
+	*scratch = (*scratch)[:0]
+	return scratch
+}
+
+func putScratch(scratch *[]byte) {
+	if cap(*scratch) <= maxPooledBufferSize {
+		scratchPool.Put(scratch)
+	}
+}
+
+// withFooterRoom copies the output of a provider into a new slice with room for the footer,
+// so addFooter does not reallocate it
+func withFooterRoom(output []byte) []byte {
+	result := make([]byte, len(output), len(output)+MaxFooterSize)
+	copy(result, output)
+	return result
+}
+
+// decompressedSizeHint returns the capacity preallocated for decompressing srcSize bytes into dstSize bytes
+func decompressedSizeHint(dstSize int, srcSize int) int {
+	if limit := srcSize * maxSizeHintRatio; dstSize > limit {
+		return limit
+	}
+	return dstSize
+}
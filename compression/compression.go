@@ -1,17 +1,25 @@
 package compression
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
+	"strconv"
 	"sync"
 )
 
 const providerIDLengthInByte = 1
 const originalSizeLengthInByte = 8
 const footerSizeInByte = providerIDLengthInByte + originalSizeLengthInByte
+
+// MaxFooterSize is the maximum size of the footer appended to compressed data
+const MaxFooterSize = footerSizeInByte
 const defaultNotCompressedBufferSize = 1024
 
+// maxDecompressedSize guards against allocating huge buffers for corrupted footers; the size
+// must also fit into int, which is smaller on 32-bit platforms
+const maxDecompressedSize uint64 = 1 << 32
+
 var byteOrder = binary.LittleEndian
 
 // Names of compression parameters
@@ -22,7 +30,8 @@ const (
 
 // Errors
 var (
-	ErrMissingFooter            = fmt.Errorf("corrupted input data; cannot extract footer")
+	ErrCorrupted                = fmt.Errorf("corrupted input data")
+	ErrMissingFooter            = fmt.Errorf("%w; cannot extract footer", ErrCorrupted)
 	ErrProviderNotFound         = fmt.Errorf("cannot find compression provider by ID")
 	ErrCompressionParamNotFound = fmt.Errorf("cannot find compression parameter by name")
 	ErrCompressionParamNotInt   = fmt.Errorf("compression parameter is not an integer type")
@@ -40,6 +49,11 @@ type Provider interface {
 // CompressionParams defines compression parameters used by providers
 type CompressionParams map[string]interface{}
 
+// ProviderParam returns the name of the parameter scoped to the provider, e.g. "minInputLen.1" for zstd
+func ProviderParam(name string, providerID byte) string {
+	return name + "." + strconv.Itoa(int(providerID))
+}
+
 // GetInt gets value from map and tries to parse it as integer
 func (c CompressionParams) GetInt(key string) (int, error) {
 	value, found := c[key]
@@ -71,6 +85,8 @@ type Engine struct {
 	defaultCompressionID byte
 	providers            map[byte]Provider
 	minInputSize         int
+	minInputSizes        map[byte]int
+	fallbacks            []Decoder
 	mutex                sync.RWMutex
 }
 
@@ -78,6 +94,8 @@ type Engine struct {
 // If providerID == 0 it means no compression so it is returned `nil,nil`;
 // defult not compressed buffer size - 1024 bytes
 // Supported providers: github.com/DataDog/zstd, github.com/cloudflare/golz4, github.com/klauspost/compress/s2,
+// github.com/klauspost/compress/zstd; without cgo (or with the purego build tag) zstd is provided by
+// github.com/klauspost/compress/zstd and lz4 is not available
 func NewEngine(defaultProviderID byte, params CompressionParams) (*Engine, error) {
 	if defaultProviderID == 0 {
 		// it means no compression, so no error is returned
@@ -104,11 +122,22 @@ func NewEngine(defaultProviderID byte, params CompressionParams) (*Engine, error
 		return nil, err
 	}
 
+	minInputSizes := make(map[byte]int)
+	for id := range providers {
+		size, err := params.GetInt(ProviderParam(CompressionParamMinInputLen, id))
+		if err == nil {
+			minInputSizes[id] = size
+		} else if err != ErrCompressionParamNotFound {
+			return nil, err
+		}
+	}
+
 	return &Engine{
 		noCompressionID:      0,
 		defaultCompressionID: defaultProvider.GetID(),
 		providers:            providers,
 		minInputSize:         minInputSize,
+		minInputSizes:        minInputSizes,
 	}, nil
 }
 
@@ -118,7 +147,7 @@ func (ce *Engine) Compress(input []byte) ([]byte, error) {
 	var provider Provider
 	ce.mutex.RLock()
 
-	if len(input) <= ce.minInputSize {
+	if len(input) <= ce.minInputSizeOf(ce.defaultCompressionID) {
 		provider = ce.providers[ce.noCompressionID]
 	} else {
 		provider = ce.providers[ce.defaultCompressionID]
@@ -141,7 +170,7 @@ func (ce *Engine) CompressWithProvider(input []byte, providerID byte) ([]byte, e
 	var provider Provider
 	ce.mutex.RLock()
 
-	if len(input) <= ce.minInputSize {
+	if len(input) <= ce.minInputSizeOf(providerID) {
 		provider = ce.providers[ce.noCompressionID]
 	} else {
 		ok := true
@@ -161,7 +190,25 @@ func (ce *Engine) CompressWithProvider(input []byte, providerID byte) ([]byte, e
 
 // Decompress extracts from input the information about used compression method.
 // If compression provider is found - the data are decompressed
+// If it fails, the fallback decoders are tried in the order they were added.
 func (ce *Engine) Decompress(input []byte) ([]byte, error) {
+	output, err := ce.decompress(input)
+	if err == nil {
+		return output, nil
+	}
+
+	ce.mutex.RLock()
+	fallbacks := ce.fallbacks
+	ce.mutex.RUnlock()
+	for _, fallback := range fallbacks {
+		if output, fallbackErr := fallback(input); fallbackErr == nil {
+			return output, nil
+		}
+	}
+	return nil, err
+}
+
+func (ce *Engine) decompress(input []byte) ([]byte, error) {
 	src, providerID, dstSize, err := ce.extractFooter(input)
 	if err != nil {
 		return nil, err
@@ -174,7 +221,38 @@ func (ce *Engine) Decompress(input []byte) ([]byte, error) {
 	}
 	ce.mutex.RUnlock()
 
-	return provider.Decompress(src, dstSize)
+	output, err := provider.Decompress(src, dstSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	return output, nil
+}
+
+// InspectFooter returns the ID of the provider used to compress data and the size of the decompressed data
+// without decompressing it. footer is the end of the data containing at least the whole footer (the last 9 bytes
+// or the whole data if it is shorter) and length is the length of the whole data.
+func (ce *Engine) InspectFooter(footer []byte, length int) (providerID byte, size int, err error) {
+	if len(footer) < providerIDLengthInByte {
+		return 0, 0, ErrMissingFooter
+	}
+	if footer[len(footer)-providerIDLengthInByte] == ce.noCompressionID {
+		return ce.noCompressionID, length - providerIDLengthInByte, nil
+	}
+	_, providerID, size, err = ce.extractFooter(footer)
+	return providerID, size, err
+}
+
+// Decoder decodes data in a format not supported by the providers, e.g. written by an older version of the library
+type Decoder func(input []byte) ([]byte, error)
+
+// AddFallbackDecoder adds a decoder tried by Decompress when the data cannot be decompressed,
+// so data written in a legacy format stays readable after upgrading the library
+func (ce *Engine) AddFallbackDecoder(decoder Decoder) *Engine {
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+
+	ce.fallbacks = append(ce.fallbacks[:len(ce.fallbacks):len(ce.fallbacks)], decoder)
+	return ce
 }
 
 // AddProvider adds compression provider to the list of supported providers
@@ -209,6 +287,27 @@ func (ce *Engine) SetMinInputSize(minInputSize int) *Engine {
 	return ce
 }
 
+// SetProviderMinInputSize sets min input buffer size of the provider overriding the one set by SetMinInputSize.
+// Buffers smaller than this value are not compressed by the provider
+func (ce *Engine) SetProviderMinInputSize(providerID byte, minInputSize int) *Engine {
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+
+	if ce.minInputSizes == nil {
+		ce.minInputSizes = make(map[byte]int)
+	}
+	ce.minInputSizes[providerID] = minInputSize
+	return ce
+}
+
+// minInputSizeOf returns min input buffer size of the provider; the mutex must be held
+func (ce *Engine) minInputSizeOf(providerID byte) int {
+	if minInputSize, ok := ce.minInputSizes[providerID]; ok {
+		return minInputSize
+	}
+	return ce.minInputSize
+}
+
 // SetDefaultProvider allows to set the defult provider by ID
 // The provider must be on the list of supported providers
 func (ce *Engine) SetDefaultProvider(id byte) error {
@@ -227,28 +326,21 @@ func (ce *Engine) SetDefaultProvider(id byte) error {
 	return nil
 }
 
-// addFooter addes footer to compressed data
+// addFooter addes footer to compressed data; providers leave room for it in their output
+// (see withFooterRoom), so it is appended without copying the data
 func (ce *Engine) addFooter(compressedInput []byte, providerID byte, inputLenght int) ([]byte, error) {
 	if providerID == ce.noCompressionID {
-		buff := bytes.NewBuffer(make([]byte, 0, providerIDLengthInByte))
-		err := buff.WriteByte(providerID)
-		if err != nil {
-			return nil, err
-		}
-		return append(compressedInput, buff.Bytes()...), nil
+		// the input is not compressed, copy it instead of appending to the caller's slice
+		output := make([]byte, len(compressedInput)+providerIDLengthInByte)
+		copy(output, compressedInput)
+		output[len(compressedInput)] = providerID
+		return output, nil
 	}
 
-	buff := bytes.NewBuffer(make([]byte, 0, footerSizeInByte))
-	err := binary.Write(buff, byteOrder, uint64(inputLenght))
-	if err != nil {
-		return nil, err
-	}
-	err = buff.WriteByte(providerID)
-	if err != nil {
-		return nil, err
-	}
-
-	return append(compressedInput, buff.Bytes()...), nil
+	var footer [footerSizeInByte]byte
+	byteOrder.PutUint64(footer[:originalSizeLengthInByte], uint64(inputLenght))
+	footer[originalSizeLengthInByte] = providerID
+	return append(compressedInput, footer[:]...), nil
 }
 
 // extractFooter extracts footer from comressed data and returs:
@@ -257,12 +349,12 @@ func (ce *Engine) addFooter(compressedInput []byte, providerID byte, inputLenght
 // - original size of compressed data
 // - error if data are corrupted
 func (ce *Engine) extractFooter(input []byte) ([]byte, byte, int, error) {
+	if len(input) < providerIDLengthInByte {
+		return nil, 0, 0, ErrMissingFooter
+	}
 	providerID := input[len(input)-providerIDLengthInByte]
 	if providerID == ce.noCompressionID {
 		inputLen := len(input)
-		if len(input) < providerIDLengthInByte {
-			return nil, 0, 0, ErrMissingFooter
-		}
 		return input[:inputLen-providerIDLengthInByte], providerID, inputLen - 1, nil
 	}
 
@@ -272,6 +364,9 @@ func (ce *Engine) extractFooter(input []byte) ([]byte, byte, int, error) {
 
 	output := input[:len(input)-footerSizeInByte]
 	dstSize := byteOrder.Uint64(input[len(input)-footerSizeInByte : len(input)-providerIDLengthInByte])
+	if dstSize > maxDecompressedSize || dstSize > math.MaxInt {
+		return nil, 0, 0, fmt.Errorf("%w; invalid decompressed size %d", ErrCorrupted, dstSize)
+	}
 
 	return output, providerID, int(dstSize), nil
 }
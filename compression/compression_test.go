@@ -1,12 +1,15 @@
 package compression
 
 import (
-	"fmt"
-	"io/ioutil"
+	"errors"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -120,6 +123,9 @@ func TestCompressionWithProvider(t *testing.T) {
 	assert.True(t, len(output) < len(input))
 	//compress the same data with other provider
 	output2, err := engine.CompressWithProvider(input, ProviderIDLz4)
+	if errors.Is(err, ErrProviderNotFound) {
+		t.Skip("lz4 is not available without cgo")
+	}
 	require.Nil(t, err)
 	// two compression method should results diffrent size of compressed input
 	assert.True(t, len(output) != len(output2))
@@ -130,81 +136,259 @@ func TestCompressionWithProvider(t *testing.T) {
 	assert.Equal(t, input, decompressedOutput)
 }
 
-func TestBigBufferCompression(t *testing.T) {
-	t.SkipNow()
-	repetitionPerFile := 10
-	testFiles := []string{
-		"/home/marcin/Downloads/cache/cache_samples/cache_wtf",
-		"/home/marcin/Downloads/cache/cache_samples/cache_products",
+var benchmarkProviders = []struct {
+	name       string
+	providerID byte
+}{
+	{
+		name:       "DataDog/zstd",
+		providerID: ProviderIDZstd,
+	},
+	{
+		name:       "klauspost/compress/s2",
+		providerID: ProviderIDS2,
+	},
+	{
+		name:       "cloudflare/golz4",
+		providerID: ProviderIDLz4,
+	},
+	{
+		name:       "klauspost/compress/zstd",
+		providerID: ProviderIDZstdGo,
+	},
+}
+
+// benchmarkSamples returns generated inputs and files listed in CACHIER_BENCH_SAMPLES (comma separated paths)
+func benchmarkSamples(b *testing.B) map[string][]byte {
+	samples := map[string][]byte{
+		"text-4KB":   []byte(strings.Repeat("hello world, ", 4*1024/13)),
+		"random-4KB": randTextBytes(4 * 1024),
+		"text-1MB":   []byte(strings.Repeat("hello world, ", 1024*1024/13)),
 	}
+	for _, path := range strings.Split(os.Getenv("CACHIER_BENCH_SAMPLES"), ",") {
+		if path == "" {
+			continue
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		samples[filepath.Base(path)] = buf
+	}
+	return samples
+}
 
-	var testData = []struct {
-		testName   string
-		providerID byte
-	}{
-		{
-			testName:   "DataDog/zstd",
-			providerID: ProviderIDZstd,
-		},
-		{
-			testName:   "klauspost/compress/s2",
-			providerID: ProviderIDS2,
-		},
-		{
-			testName:   "cloudflare/golz4",
-			providerID: ProviderIDLz4,
-		},
+func TestInspectFooter(t *testing.T) {
+	engine, err := NewEngine(ProviderIDS2, nil)
+	require.Nil(t, err)
+
+	input := []byte(strings.Repeat("a", 2000))
+	output, err := engine.Compress(input)
+	require.Nil(t, err)
+	providerID, size, err := engine.InspectFooter(output[len(output)-MaxFooterSize:], len(output))
+	require.Nil(t, err)
+	assert.Equal(t, byte(ProviderIDS2), providerID)
+	assert.Equal(t, len(input), size)
+	assert.Equal(t, "s2", GetProviderName(providerID))
+
+	output, err = engine.Compress(input[:10])
+	require.Nil(t, err)
+	providerID, size, err = engine.InspectFooter(output, len(output))
+	require.Nil(t, err)
+	assert.Equal(t, "none", GetProviderName(providerID))
+	assert.Equal(t, 10, size)
+
+	_, _, err = engine.InspectFooter(nil, 0)
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestOversizedFooter(t *testing.T) {
+	engine, err := NewEngine(ProviderIDZstd, nil)
+	require.Nil(t, err)
+
+	// the second size does not fit into int on 32-bit platforms
+	for _, size := range []uint64{maxDecompressedSize + 1, uint64(math.MaxInt) + 1} {
+		output, err := engine.Compress([]byte(strings.Repeat("a", 2000)))
+		require.Nil(t, err)
+		byteOrder.PutUint64(output[len(output)-footerSizeInByte:], size)
+		_, err = engine.Decompress(output)
+		assert.ErrorIs(t, err, ErrCorrupted, size)
 	}
+}
+
+func TestFallbackDecoder(t *testing.T) {
 	engine, err := NewEngine(ProviderIDZstd, nil)
 	require.Nil(t, err)
-	for _, tt := range testData {
-		t.Run(tt.testName, func(t *testing.T) {
-			t.Logf("---- %s ----", tt.testName)
-			engine.SetDefaultProvider(tt.providerID)
-			for _, path := range testFiles {
-				buf, err := ioutil.ReadFile(path)
-				require.Nil(t, err)
-				var compressionSum int64
-				var decompressionSum int64
-				t.Logf("File path %s", path)
-				t.Logf("Orginal size %s\n", ByteCount(len(buf)))
-				for a := 1; a <= repetitionPerFile; a++ {
-					start := time.Now()
-					compressedOutput, err := engine.Compress(buf)
-					elapsed := time.Since(start)
-					require.Nil(t, err)
-					compressionSum += elapsed.Milliseconds()
-					start = time.Now()
-					decompressedOutput, err := engine.Decompress(compressedOutput)
-					elapsed = time.Since(start)
-					require.Nil(t, err)
-					assert.Equal(t, len(buf), len(decompressedOutput))
-					decompressionSum += elapsed.Milliseconds()
-					if a == repetitionPerFile {
-						avgC := (float64(compressionSum)) / (float64(repetitionPerFile))
-						avgDC := (float64(decompressionSum)) / (float64(repetitionPerFile))
-						t.Logf("Avearge compression time %.2f ms\n", avgC)
-						t.Logf("Compressed size %s", ByteCount(len(compressedOutput)))
-						t.Logf("Avearge decompression time %.2f ms\n", avgDC)
-						t.Logf("Decmopressed size %s", ByteCount(len(decompressedOutput)))
 
-					}
-				}
+	input := []byte(strings.Repeat("a", 2000))
+	legacy, err := NewZstdCompressionService().Compress(input)
+	require.Nil(t, err)
+	_, err = engine.Decompress(legacy)
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	engine.AddFallbackDecoder(DecodeZstdWithoutFooter)
+	output, err := engine.Decompress(legacy)
+	require.Nil(t, err)
+	assert.Equal(t, input, output)
+
+	_, err = engine.Decompress([]byte("garbage"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestProviderMinInputSize(t *testing.T) {
+	engine, err := NewEngine(ProviderIDZstd, CompressionParams{
+		ProviderParam(CompressionParamMinInputLen, ProviderIDZstd): 256,
+		ProviderParam(CompressionParamMinInputLen, ProviderIDS2):   4096,
+	})
+	require.Nil(t, err)
+
+	input := []byte(strings.Repeat("a", 512))
+	output, err := engine.Compress(input)
+	require.Nil(t, err)
+	providerID, _, err := engine.InspectFooter(output, len(output))
+	require.Nil(t, err)
+	assert.Equal(t, byte(ProviderIDZstd), providerID)
+
+	output, err = engine.CompressWithProvider(input, ProviderIDS2)
+	require.Nil(t, err)
+	providerID, _, err = engine.InspectFooter(output, len(output))
+	require.Nil(t, err)
+	assert.Equal(t, byte(0), providerID)
+
+	engine.SetProviderMinInputSize(ProviderIDS2, 100)
+	output, err = engine.CompressWithProvider(input, ProviderIDS2)
+	require.Nil(t, err)
+	providerID, _, err = engine.InspectFooter(output, len(output))
+	require.Nil(t, err)
+	assert.Equal(t, byte(ProviderIDS2), providerID)
+}
+
+func TestZstdGoCompression(t *testing.T) {
+	engine, err := NewEngine(ProviderIDZstdGo, CompressionParams{
+		CompressionParamLevel:       5,
+		CompressionParamConcurrency: 4,
+		CompressionParamWindowSize:  1 << 20,
+	})
+	require.Nil(t, err)
+
+	input := []byte(strings.Repeat("hello world, ", 4<<20/13))
+	output, err := engine.Compress(input)
+	require.Nil(t, err)
+	assert.Less(t, len(output), len(input))
+	decompressed, err := engine.Decompress(output)
+	require.Nil(t, err)
+	assert.Equal(t, input, decompressed)
+
+	// both zstd providers produce the same format
+	output, err = engine.CompressWithProvider(input, ProviderIDZstd)
+	require.Nil(t, err)
+	decompressed, err = NewZstdGoCompressionService().Decompress(output[:len(output)-MaxFooterSize], len(input))
+	require.Nil(t, err)
+	assert.Equal(t, input, decompressed)
+
+	_, err = NewEngine(ProviderIDZstdGo, CompressionParams{CompressionParamWindowSize: 1000})
+	assert.NotNil(t, err)
+}
+
+func TestZstdGoCompressionConcurrent(t *testing.T) {
+	engine, err := NewEngine(ProviderIDZstdGo, nil)
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			input := []byte(strings.Repeat(strconv.Itoa(i), 4*1024))
+			for j := 0; j < 100; j++ {
+				output, err := engine.Compress(input)
+				assert.Nil(t, err)
+				decompressed, err := engine.Decompress(output)
+				assert.Nil(t, err)
+				assert.Equal(t, input, decompressed)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// raceEnabled is set by race_test.go
+var raceEnabled = false
+
+func TestCompressReusesBuffers(t *testing.T) {
+	input := []byte(strings.Repeat("hello world, ", 4*1024/13))
+	for _, test := range []struct {
+		providerID byte
+		maxAllocs  float64
+	}{
+		// the output with its footer is the only buffer allocated by the engine, s2 allocates its block headers
+		{providerID: ProviderIDZstdGo, maxAllocs: 1},
+		{providerID: ProviderIDS2, maxAllocs: 3},
+	} {
+		if raceEnabled {
+			break
+		}
+		engine, err := NewEngine(test.providerID, nil)
+		require.Nil(t, err)
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := engine.Compress(input); err != nil {
+				t.Fatal(err)
 			}
 		})
+		assert.LessOrEqual(t, allocs, test.maxAllocs, GetProviderName(test.providerID))
 	}
+
+	// not compressed data does not share the memory of the input
+	engine, err := NewEngine(ProviderIDS2, nil)
+	require.Nil(t, err)
+	input = make([]byte, 10, 20)
+	output, err := engine.Compress(input)
+	require.Nil(t, err)
+	output[0] = 1
+	assert.Equal(t, byte(0), input[0])
 }
 
-func ByteCount(b int) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
+func BenchmarkCompress(b *testing.B) {
+	samples := benchmarkSamples(b)
+	for _, provider := range benchmarkProviders {
+		engine, err := NewEngine(provider.providerID, nil)
+		require.Nil(b, err)
+		for name, input := range samples {
+			b.Run(provider.name+"/"+name, func(b *testing.B) {
+				output, err := engine.Compress(input)
+				require.Nil(b, err)
+				b.ReportMetric(float64(len(output))/float64(len(input)), "ratio")
+				b.ReportAllocs()
+				b.SetBytes(int64(len(input)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := engine.Compress(input); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
 	}
-	div, exp := int(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	samples := benchmarkSamples(b)
+	for _, provider := range benchmarkProviders {
+		engine, err := NewEngine(provider.providerID, nil)
+		require.Nil(b, err)
+		for name, input := range samples {
+			b.Run(provider.name+"/"+name, func(b *testing.B) {
+				compressed, err := engine.Compress(input)
+				require.Nil(b, err)
+				b.ReportAllocs()
+				b.SetBytes(int64(len(input)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := engine.Decompress(compressed); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
 	}
-	return fmt.Sprintf("%.1f %cB",
-		float64(b)/float64(div), "KMGTPE"[exp])
 }
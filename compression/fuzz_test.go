@@ -0,0 +1,116 @@
+package compression
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+)
+
+// availableProviders returns the IDs of the build in providers which work in this build
+func availableProviders(tb testing.TB, engine *Engine) []byte {
+	input := bytes.Repeat([]byte("abc"), 1024)
+	ids := make([]byte, 0)
+	for id := range engine.providers {
+		if _, err := engine.CompressWithProvider(input, id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	require.NotEmpty(tb, ids)
+	return ids
+}
+
+func newFuzzEngine(tb testing.TB) *Engine {
+	engine, err := NewEngine(ProviderIDZstd, CompressionParams{CompressionParamMinInputLen: 0})
+	require.Nil(tb, err)
+	return engine
+}
+
+func FuzzDecompress(f *testing.F) {
+	engine := newFuzzEngine(f)
+	f.Add([]byte{})
+	f.Add([]byte{0})
+	f.Add([]byte{ProviderIDZstd})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, ProviderIDS2})
+	for _, id := range availableProviders(f, engine) {
+		for _, input := range [][]byte{[]byte("a"), bytes.Repeat([]byte("hello world, "), 100), randTextBytes(2048)} {
+			output, err := engine.CompressWithProvider(input, id)
+			require.Nil(f, err)
+			f.Add(output)
+			f.Add(output[:len(output)/2])
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		output, err := engine.Decompress(data)
+		if err == nil && data[len(data)-1] == engine.noCompressionID {
+			require.Equal(t, data[:len(data)-1], output)
+		}
+		_, _, _ = engine.InspectFooter(data, len(data))
+	})
+}
+
+func FuzzCompressRoundTrip(f *testing.F) {
+	engine := newFuzzEngine(f)
+	providers := make([]byte, 0)
+	for _, id := range availableProviders(f, engine) {
+		// the decoder of cloudflare/golz4 corrupts some short inputs ending with overlapping matches
+		// (e.g. "hello world, hello world, hello world, hell00000000000"), so lz4 is not fuzzed
+		if id != ProviderIDLz4 {
+			providers = append(providers, id)
+		}
+	}
+	f.Add([]byte{}, byte(0))
+	f.Add([]byte("a"), byte(1))
+	f.Add(bytes.Repeat([]byte("hello world, "), 100), byte(2))
+	f.Add(randTextBytes(4096), byte(3))
+
+	f.Fuzz(func(t *testing.T, input []byte, provider byte) {
+		assertRoundTrip(t, engine, input, providers[int(provider)%len(providers)])
+	})
+}
+
+// TestCompressRoundTripProperty checks that Decompress returns the input of Compress for all the providers
+// and for inputs around the size thresholds
+func TestCompressRoundTripProperty(t *testing.T) {
+	engine := newFuzzEngine(t)
+	providers := availableProviders(t, engine)
+	for _, size := range []int{0, 1, 255, defaultNotCompressedBufferSize, 64 << 10, 1 << 20} {
+		for _, input := range [][]byte{randTextBytes(size), bytes.Repeat([]byte{0}, size), randBytes(size)} {
+			for _, id := range providers {
+				assertRoundTrip(t, engine, input, id)
+			}
+		}
+	}
+
+	err := quick.Check(func(input []byte, provider uint8) bool {
+		id := providers[int(provider)%len(providers)]
+		compressed, err := engine.CompressWithProvider(input, id)
+		if err != nil {
+			return false
+		}
+		output, err := engine.Decompress(compressed)
+		return err == nil && bytes.Equal(input, output)
+	}, nil)
+	require.Nil(t, err)
+}
+
+func assertRoundTrip(t *testing.T, engine *Engine, input []byte, providerID byte) {
+	compressed, err := engine.CompressWithProvider(input, providerID)
+	require.Nil(t, err, GetProviderName(providerID))
+	output, err := engine.Decompress(compressed)
+	require.Nil(t, err, GetProviderName(providerID))
+	require.True(t, bytes.Equal(input, output), "%s: %d bytes", GetProviderName(providerID), len(input))
+
+	providerID, size, err := engine.InspectFooter(compressed, len(compressed))
+	require.Nil(t, err)
+	require.Equal(t, len(input), size, GetProviderName(providerID))
+}
+
+func randBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
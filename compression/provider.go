@@ -3,10 +3,9 @@ package compression
 import (
 	"bytes"
 	"io"
+	"strconv"
 	"sync"
 
-	"github.com/DataDog/zstd"
-	lz4 "github.com/cloudflare/golz4"
 	"github.com/klauspost/compress/s2"
 )
 
@@ -15,12 +14,15 @@ const (
 	ProviderIDZstd = 1
 	ProviderIDS2   = 2
 	ProviderIDLz4  = 3
+	// ProviderIDZstdGo is the pure Go zstd provider (github.com/klauspost/compress/zstd)
+	ProviderIDZstdGo = 4
 )
 
 var providerNameToID = map[string]byte{
-	"zstd": ProviderIDZstd,
-	"s2":   ProviderIDS2,
-	"lz4":  ProviderIDLz4,
+	"zstd":   ProviderIDZstd,
+	"s2":     ProviderIDS2,
+	"lz4":    ProviderIDLz4,
+	"zstdgo": ProviderIDZstdGo,
 }
 
 func GetProviderID(name string) (byte, error) {
@@ -31,18 +33,33 @@ func GetProviderID(name string) (byte, error) {
 	return providerID, nil
 }
 
+// GetProviderName returns the name of the build in provider ("none" for no compression)
+// or the ID as a string for other providers
+func GetProviderName(providerID byte) string {
+	if providerID == 0 {
+		return "none"
+	}
+	for name, id := range providerNameToID {
+		if id == providerID {
+			return name
+		}
+	}
+	return strconv.Itoa(int(providerID))
+}
+
 func getBuildInProviders() map[byte]Provider {
 
 	noCompression := NewNoCompressionService()
-	zstdCompression := NewZstdCompressionService()
-	lz4Compression := NewLz4CompressionService()
 	s2Compression := NewS2CompressionService()
+	zstdGoCompression := NewZstdGoCompressionService()
 
 	providers := map[byte]Provider{
-		noCompression.GetID():   noCompression,
-		zstdCompression.GetID(): zstdCompression,
-		lz4Compression.GetID():  lz4Compression,
-		s2Compression.GetID():   s2Compression,
+		noCompression.GetID():     noCompression,
+		s2Compression.GetID():     s2Compression,
+		zstdGoCompression.GetID(): zstdGoCompression,
+	}
+	for _, provider := range cgoProviders() {
+		providers[provider.GetID()] = provider
 	}
 
 	return providers
@@ -55,14 +72,6 @@ func NewNoCompressionService() Provider {
 	}
 }
 
-// NewZstdCompressionService creates new instance of compression provider which uses github.com/DataDog/zstd compression method
-func NewZstdCompressionService() Provider {
-	return &zstdCompression{
-		id:               ProviderIDZstd,
-		compressionLevel: 3,
-	}
-}
-
 // NewS2CompressionService creates new instance of compression provider which uses github.com/klauspost/compress/s2 compression method
 func NewS2CompressionService() Provider {
 	return &s2Compression{
@@ -78,13 +87,6 @@ func NewS2CompressionService() Provider {
 	}
 }
 
-// NewS2CompressionService creates new instance of compression provider which uses github.com/klauspost/compress/s2 compression method
-func NewLz4CompressionService() Provider {
-	return &lz4Compression{
-		id: ProviderIDLz4,
-	}
-}
-
 type noCompression struct {
 	id byte
 }
@@ -108,52 +110,6 @@ func (c noCompression) GetID() byte {
 	return c.id
 }
 
-type zstdCompression struct {
-	id               byte
-	compressionLevel int
-}
-
-// Compress compresses src  using zstd method
-func (c zstdCompression) Compress(src []byte) ([]byte, error) {
-
-	output, err := zstd.CompressLevel(nil, src, c.compressionLevel)
-	if err != nil {
-		return nil, err
-	}
-
-	return output, nil
-}
-
-// Decompress decompresses src  using zstd method
-func (c zstdCompression) Decompress(src []byte, dstSize int) ([]byte, error) {
-	dst := make([]byte, 0, dstSize)
-	output, err := zstd.Decompress(dst, src)
-	if err != nil {
-		return nil, err
-	}
-	return output, nil
-}
-
-// GetID returns compression identifier.
-func (c zstdCompression) GetID() byte {
-	return c.id
-}
-
-// GetID returns compression identifier.
-func (c *zstdCompression) Configure(params CompressionParams) error {
-	if params == nil {
-		return ErrCompressionParamNil
-	}
-
-	level, err := params.GetInt(CompressionParamLevel)
-	if err != nil {
-		return err
-	}
-
-	c.compressionLevel = level
-	return nil
-}
-
 type s2Compression struct {
 	id          byte
 	writterPool *sync.Pool
@@ -164,8 +120,9 @@ type s2Compression struct {
 func (c s2Compression) Compress(src []byte) ([]byte, error) {
 	enc := c.writterPool.Get().(*s2.Writer)
 	defer c.writterPool.Put(enc)
-	var out bytes.Buffer
-	enc.Reset(&out)
+	out := getBuffer()
+	defer putBuffer(out)
+	enc.Reset(out)
 	err := enc.EncodeBuffer(src)
 	if err != nil {
 		enc.Close()
@@ -176,7 +133,7 @@ func (c s2Compression) Compress(src []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return out.Bytes(), err
+	return withFooterRoom(out.Bytes()), nil
 }
 
 // Decompress decompresses src  using s2 method
@@ -185,9 +142,10 @@ func (c s2Compression) Decompress(src []byte, dstSize int) ([]byte, error) {
 	defer c.readerPool.Put(dec)
 	r := bytes.NewReader(src)
 	dec.Reset(r)
-	var out bytes.Buffer
-	_, err := io.Copy(&out, dec)
-
+	out := bytes.NewBuffer(make([]byte, 0, decompressedSizeHint(dstSize, len(src))))
+	_, err := io.Copy(out, dec)
+	// do not keep a reference to src in the pool
+	dec.Reset(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -203,38 +161,3 @@ func (c s2Compression) GetID() byte {
 func (c *s2Compression) Configure(params CompressionParams) error {
 	return nil
 }
-
-type lz4Compression struct {
-	id byte
-}
-
-// Compress compresses src  using lz4 method poreted from C
-func (c lz4Compression) Compress(src []byte) ([]byte, error) {
-	output := make([]byte, lz4.CompressBound(src))
-	outSize, err := lz4.Compress(src, output)
-	if err != nil {
-		return nil, err
-	}
-
-	return output[:outSize], nil
-}
-
-// Decompress decompresses src  using lz4 method
-func (c lz4Compression) Decompress(src []byte, dstSize int) ([]byte, error) {
-	dst := make([]byte, dstSize)
-	err := lz4.Uncompress(src, dst)
-	if err != nil {
-		return nil, err
-	}
-
-	return dst, nil
-}
-
-// GetID returns compression identifier.
-func (c lz4Compression) GetID() byte {
-	return c.id
-}
-
-func (c *lz4Compression) Configure(params CompressionParams) error {
-	return nil
-}
@@ -0,0 +1,130 @@
+//go:build cgo && !purego
+
+package compression
+
+import (
+	"errors"
+
+	"github.com/DataDog/zstd"
+	lz4 "github.com/cloudflare/golz4"
+)
+
+// cgoProviders returns the build in providers using cgo: zstd and lz4
+func cgoProviders() []Provider {
+	return []Provider{NewZstdCompressionService(), NewLz4CompressionService()}
+}
+
+// NewZstdCompressionService creates new instance of compression provider which uses github.com/DataDog/zstd compression method
+func NewZstdCompressionService() Provider {
+	return &zstdCompression{
+		id:               ProviderIDZstd,
+		compressionLevel: 3,
+	}
+}
+
+// NewLz4CompressionService creates new instance of compression provider which uses github.com/cloudflare/golz4 compression method
+func NewLz4CompressionService() Provider {
+	return &lz4Compression{
+		id: ProviderIDLz4,
+	}
+}
+
+// DecodeZstdWithoutFooter is a fallback Decoder of data compressed by github.com/DataDog/zstd without the footer
+func DecodeZstdWithoutFooter(input []byte) ([]byte, error) {
+	return zstd.Decompress(nil, input)
+}
+
+type zstdCompression struct {
+	id               byte
+	compressionLevel int
+}
+
+// Compress compresses src  using zstd method
+func (c zstdCompression) Compress(src []byte) ([]byte, error) {
+	scratch := getScratch(zstd.CompressBound(len(src)))
+	defer putScratch(scratch)
+	output, err := zstd.CompressLevel(*scratch, src, c.compressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	return withFooterRoom(output), nil
+}
+
+// Decompress decompresses src  using zstd method
+func (c zstdCompression) Decompress(src []byte, dstSize int) ([]byte, error) {
+	// zstd.Decompress grows dst if it is too small; an empty dst would make it trust the size in the frame header
+	dst := make([]byte, decompressedSizeHint(dstSize, len(src))+1)
+	output, err := zstd.Decompress(dst, src)
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// GetID returns compression identifier.
+func (c zstdCompression) GetID() byte {
+	return c.id
+}
+
+// GetID returns compression identifier.
+func (c *zstdCompression) Configure(params CompressionParams) error {
+	if params == nil {
+		return ErrCompressionParamNil
+	}
+
+	level, err := params.GetIntWithDefault(CompressionParamLevel, c.compressionLevel)
+	if err != nil {
+		return err
+	}
+
+	c.compressionLevel = level
+	return nil
+}
+
+// lz4MaxCompressionRatio is the maximum ratio of lz4 compression (a byte of the input encodes
+// at most 255 bytes of a match)
+const lz4MaxCompressionRatio = 255
+
+var errLz4InvalidSize = errors.New("invalid lz4 compressed or decompressed size")
+
+type lz4Compression struct {
+	id byte
+}
+
+// Compress compresses src  using lz4 method poreted from C
+func (c lz4Compression) Compress(src []byte) ([]byte, error) {
+	scratch := getScratch(lz4.CompressBound(src))
+	defer putScratch(scratch)
+	output := (*scratch)[:cap(*scratch)]
+	outSize, err := lz4.Compress(src, output)
+	if err != nil {
+		return nil, err
+	}
+
+	return withFooterRoom(output[:outSize]), nil
+}
+
+// Decompress decompresses src  using lz4 method
+func (c lz4Compression) Decompress(src []byte, dstSize int) ([]byte, error) {
+	// golz4 passes nil pointers for empty slices to C
+	if len(src) == 0 || dstSize == 0 || dstSize/lz4MaxCompressionRatio > len(src) {
+		return nil, errLz4InvalidSize
+	}
+	dst := make([]byte, dstSize)
+	err := lz4.Uncompress(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
+// GetID returns compression identifier.
+func (c lz4Compression) GetID() byte {
+	return c.id
+}
+
+func (c *lz4Compression) Configure(params CompressionParams) error {
+	return nil
+}
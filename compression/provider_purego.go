@@ -0,0 +1,57 @@
+//go:build !cgo || purego
+
+package compression
+
+import "errors"
+
+// ErrProviderNotAvailable is returned by providers which are not available in pure Go builds
+var ErrProviderNotAvailable = errors.New("compression provider requires cgo")
+
+// cgoProviders returns the replacements of the build in providers using cgo: zstd is provided
+// by the pure Go implementation (the format is the same), lz4 is not available
+func cgoProviders() []Provider {
+	return []Provider{NewZstdCompressionService()}
+}
+
+// NewZstdCompressionService creates new instance of compression provider with the zstd ID which uses
+// the pure Go github.com/klauspost/compress/zstd compression method in builds without cgo
+func NewZstdCompressionService() Provider {
+	return newZstdGoCompression(ProviderIDZstd)
+}
+
+// NewLz4CompressionService creates new instance of the lz4 provider, which is not available in builds without cgo:
+// it fails with ErrProviderNotAvailable
+func NewLz4CompressionService() Provider {
+	return unavailableProvider{id: ProviderIDLz4}
+}
+
+// legacyZstd decodes the data of DecodeZstdWithoutFooter
+var legacyZstd = newZstdGoCompression(ProviderIDZstd)
+
+// DecodeZstdWithoutFooter is a fallback Decoder of data compressed by zstd without the footer
+func DecodeZstdWithoutFooter(input []byte) ([]byte, error) {
+	return legacyZstd.Decompress(input, 0)
+}
+
+type unavailableProvider struct {
+	id byte
+}
+
+// Compress fails with ErrProviderNotAvailable
+func (p unavailableProvider) Compress(src []byte) ([]byte, error) {
+	return nil, ErrProviderNotAvailable
+}
+
+// Decompress fails with ErrProviderNotAvailable
+func (p unavailableProvider) Decompress(src []byte, dstSize int) ([]byte, error) {
+	return nil, ErrProviderNotAvailable
+}
+
+// GetID returns compression identifier.
+func (p unavailableProvider) GetID() byte {
+	return p.id
+}
+
+func (p unavailableProvider) Configure(params CompressionParams) error {
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build race
+
+package compression
+
+// sync.Pool drops items randomly with the race detector, so allocations cannot be counted
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,147 @@
+package compression
+
+import (
+	"errors"
+	"sync"
+
+	kzstd "github.com/klauspost/compress/zstd"
+)
+
+// Names of parameters of the pure Go zstd provider
+const (
+	// CompressionParamConcurrency is the number of goroutines compressing one input
+	CompressionParamConcurrency = "concurrency"
+	// CompressionParamWindowSize is the window size in bytes; a power of 2 between 1 KB and 512 MB
+	CompressionParamWindowSize = "windowSize"
+)
+
+// zstdGoStreamMinInputSize is the min size of inputs compressed by several goroutines with concurrency > 1
+const zstdGoStreamMinInputSize = 1 << 20
+
+var errZstdSizeMismatch = errors.New("zstd frame content size does not match the size in the footer")
+
+type zstdGoCompression struct {
+	id byte
+	// encoder is shared by all the calls of Compress (EncodeAll is safe for concurrent use)
+	encoder *kzstd.Encoder
+	// writerPool holds the stream encoders compressing large inputs with concurrency > 1, nil otherwise
+	writerPool  *sync.Pool
+	decoder     *kzstd.Decoder
+	decoderErr  error
+	decoderOnce sync.Once
+}
+
+// NewZstdGoCompressionService creates new instance of compression provider which uses the pure Go
+// github.com/klauspost/compress/zstd compression method. Its output can be decompressed by the zstd provider and vice versa.
+func NewZstdGoCompressionService() Provider {
+	return newZstdGoCompression(ProviderIDZstdGo)
+}
+
+func newZstdGoCompression(id byte) *zstdGoCompression {
+	c := &zstdGoCompression{
+		id: id,
+	}
+	// the default options are valid
+	_ = c.setOptions(0, kzstd.WithEncoderLevel(kzstd.EncoderLevelFromZstd(3)))
+	return c
+}
+
+// setOptions validates the options and creates the encoders
+func (c *zstdGoCompression) setOptions(concurrency int, options ...kzstd.EOption) error {
+	encoder, err := kzstd.NewWriter(nil, options...)
+	if err != nil {
+		return err
+	}
+	c.encoder = encoder
+	c.writerPool = nil
+	if concurrency > 1 {
+		streamOptions := append(options[:len(options):len(options)], kzstd.WithEncoderConcurrency(concurrency))
+		c.writerPool = &sync.Pool{
+			New: func() interface{} {
+				// the options are validated by creating the shared encoder
+				writer, _ := kzstd.NewWriter(nil, streamOptions...)
+				return writer
+			}}
+	}
+	return nil
+}
+
+// Compress compresses src using the pure Go zstd method; with concurrency > 1 large inputs are compressed
+// by several goroutines
+func (c *zstdGoCompression) Compress(src []byte) ([]byte, error) {
+	if c.writerPool != nil && len(src) >= zstdGoStreamMinInputSize {
+		return c.compressStream(src)
+	}
+	scratch := getScratch(len(src))
+	defer putScratch(scratch)
+	*scratch = c.encoder.EncodeAll(src, *scratch)
+	return withFooterRoom(*scratch), nil
+}
+
+// compressStream compresses src by several goroutines
+func (c *zstdGoCompression) compressStream(src []byte) ([]byte, error) {
+	enc := c.writerPool.Get().(*kzstd.Encoder)
+	defer c.writerPool.Put(enc)
+	out := getBuffer()
+	defer putBuffer(out)
+	enc.Reset(out)
+	if _, err := enc.Write(src); err != nil {
+		enc.Close()
+		return nil, err
+	}
+	// Blocks until compression is done.
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return withFooterRoom(out.Bytes()), nil
+}
+
+// Decompress decompresses src using the pure Go zstd method
+func (c *zstdGoCompression) Decompress(src []byte, dstSize int) ([]byte, error) {
+	c.decoderOnce.Do(func() {
+		c.decoder, c.decoderErr = kzstd.NewReader(nil)
+	})
+	if c.decoderErr != nil {
+		return nil, c.decoderErr
+	}
+	// the decoder preallocates the size declared in the frame header, do not trust corrupted ones
+	var header kzstd.Header
+	if dstSize > 0 && header.Decode(src) == nil && header.HasFCS && header.FrameContentSize != uint64(dstSize) {
+		return nil, errZstdSizeMismatch
+	}
+	return c.decoder.DecodeAll(src, make([]byte, 0, decompressedSizeHint(dstSize, len(src))))
+}
+
+// GetID returns compression identifier.
+func (c *zstdGoCompression) GetID() byte {
+	return c.id
+}
+
+// Configure sets the compression level (level), the number of goroutines compressing one input (concurrency)
+// and the window size (windowSize)
+func (c *zstdGoCompression) Configure(params CompressionParams) error {
+	if params == nil {
+		return ErrCompressionParamNil
+	}
+
+	level, err := params.GetIntWithDefault(CompressionParamLevel, 3)
+	if err != nil {
+		return err
+	}
+	options := []kzstd.EOption{kzstd.WithEncoderLevel(kzstd.EncoderLevelFromZstd(level))}
+
+	concurrency, err := params.GetIntWithDefault(CompressionParamConcurrency, 0)
+	if err != nil {
+		return err
+	}
+
+	windowSize, err := params.GetIntWithDefault(CompressionParamWindowSize, 0)
+	if err != nil {
+		return err
+	}
+	if windowSize > 0 {
+		options = append(options, kzstd.WithWindowSize(windowSize))
+	}
+
+	return c.setOptions(concurrency, options...)
+}
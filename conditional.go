@@ -0,0 +1,194 @@
+package cachier
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrVersionsNotSupported is returned by GetWithVersion and CompareAndSwap of a cache whose engine
+	// does not implement AtomicEngine
+	ErrVersionsNotSupported = errors.New("engine does not support versions")
+	// ErrUpdateConflict is returned by Update when the entry kept being changed concurrently
+	ErrUpdateConflict = errors.New("update conflict")
+)
+
+// maxUpdateAttempts is the number of compare-and-swap attempts of Update
+const maxUpdateAttempts = 10
+
+// SetIfAbsent stores the value only if the key does not exist and reports whether it was stored.
+// If the engine implements AtomicEngine the check is atomic in the engine, otherwise only within this Cache.
+func (c *Cache[T]) SetIfAbsent(key string, value *T) (bool, error) {
+	if err := c.validate(key, value); err != nil {
+		return false, err
+	}
+	if err := c.writeDirectly(key); err != nil {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+	c.expiry.expire(c.engine, engineKey)
+
+	if atomicEngine, ok := c.engine.(AtomicEngine); ok {
+		start := c.auditStart()
+		stored, err := atomicEngine.SetIfAbsent(engineKey, value)
+		c.auditConditional(key, value, start, stored, err)
+		return stored, wrapKeyError(OpSet, key, err)
+	}
+
+	if _, err := c.engine.Peek(engineKey); err == nil {
+		return false, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+	c.expiry.forget(engineKey)
+	return true, wrapKeyError(OpSet, key, c.audited(OpSet, key, value, func() error {
+		return c.engine.Set(engineKey, value)
+	}))
+}
+
+// GetWithVersion gets a cached value by key together with its version for CompareAndSwap.
+// The engine must implement AtomicEngine.
+func (c *Cache[T]) GetWithVersion(key string) (*T, string, error) {
+	atomicEngine, ok := c.engine.(AtomicEngine)
+	if !ok {
+		return nil, "", wrapKeyError(OpGet, key, ErrVersionsNotSupported)
+	}
+
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return nil, "", wrapKeyError(OpGet, key, err)
+	}
+	if c.expiry.expire(c.engine, engineKey) {
+		return nil, "", wrapKeyError(OpGet, key, ErrNotFound)
+	}
+	value, version, err := atomicEngine.GetWithVersion(engineKey)
+	if err != nil {
+		return nil, "", wrapKeyError(OpGet, key, err)
+	}
+	typedValue, err := c.toTyped(value)
+	if err != nil {
+		return nil, "", wrapKeyError(OpGet, key, err)
+	}
+	return typedValue, version, nil
+}
+
+// CompareAndSwap stores the value only if the entry has not changed since GetWithVersion returned
+// the version and reports whether it was stored. The engine must implement AtomicEngine.
+func (c *Cache[T]) CompareAndSwap(key string, version string, value *T) (bool, error) {
+	atomicEngine, ok := c.engine.(AtomicEngine)
+	if !ok {
+		return false, wrapKeyError(OpSet, key, ErrVersionsNotSupported)
+	}
+	if err := c.validate(key, value); err != nil {
+		return false, err
+	}
+	if err := c.writeDirectly(key); err != nil {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+	if c.expiry.expire(c.engine, engineKey) {
+		return false, nil
+	}
+	start := c.auditStart()
+	swapped, err := atomicEngine.CompareAndSwap(engineKey, version, value)
+	c.auditConditional(key, value, start, swapped, err)
+	if swapped {
+		c.expiry.forget(engineKey)
+	}
+	return swapped, wrapKeyError(OpSet, key, err)
+}
+
+// Update performs a read-modify-write of the key: merge gets the current value (nil if the key
+// does not exist) and returns the value to be stored, which is returned by Update.
+// If merge returns an error or a nil value nothing is stored.
+// The key is locked within this Cache; if the engine implements AtomicEngine the write is also
+// a compare-and-swap, so merge is run again when the entry was changed by another process.
+func (c *Cache[T]) Update(key string, merge func(old *T) (*T, error)) (*T, error) {
+	if err := c.writeDirectly(key); err != nil {
+		return nil, wrapKeyError(OpSet, key, err)
+	}
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return nil, wrapKeyError(OpGet, key, err)
+	}
+	c.expiry.expire(c.engine, engineKey)
+
+	atomicEngine, ok := c.engine.(AtomicEngine)
+	if !ok {
+		old, err := c.engine.Peek(engineKey)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, wrapKeyError(OpGet, key, err)
+		}
+		value, _, err := c.merge(key, old, merge)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		c.expiry.forget(engineKey)
+		return value, wrapKeyError(OpSet, key, c.audited(OpSet, key, value, func() error {
+			return c.engine.Set(engineKey, value)
+		}))
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		old, version, err := atomicEngine.GetWithVersion(engineKey)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, wrapKeyError(OpGet, key, err)
+		}
+		value, found, err := c.merge(key, old, merge)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		var stored bool
+		start := c.auditStart()
+		if found {
+			stored, err = atomicEngine.CompareAndSwap(engineKey, version, value)
+		} else {
+			stored, err = atomicEngine.SetIfAbsent(engineKey, value)
+		}
+		c.auditConditional(key, value, start, stored, err)
+		if err != nil {
+			return nil, wrapKeyError(OpSet, key, err)
+		}
+		if stored {
+			return value, nil
+		}
+	}
+	return nil, wrapKeyError(OpSet, key, ErrUpdateConflict)
+}
+
+// merge runs the merge function of Update on the value returned by the engine (nil if not found)
+func (c *Cache[T]) merge(key string, old interface{}, merge func(old *T) (*T, error)) (value *T, found bool, err error) {
+	var typedOld *T
+	if old != nil {
+		if typedOld, err = c.toTyped(old); err != nil {
+			return nil, true, wrapKeyError(OpGet, key, err)
+		}
+	}
+	value, err = merge(typedOld)
+	if err == nil && value != nil {
+		err = c.validate(key, value)
+	}
+	return value, old != nil, err
+}
+
+// auditConditional records a conditional write if it stored the value or failed
+func (c *Cache[T]) auditConditional(key string, value *T, start time.Time, stored bool, err error) {
+	if stored || err != nil {
+		c.audit(OpSet, key, value, start, err)
+	}
+}
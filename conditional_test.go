@@ -0,0 +1,118 @@
+package cachier_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetIfAbsent(t *testing.T) {
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	engines := map[string]cachier.CacheEngine{
+		"memory": cachier.NewMemoryCache(0, 0),
+		"lru":    lc,
+	}
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			cache := cachier.MakeCache[string](engine)
+			first, second := "first", "second"
+
+			stored, err := cache.SetIfAbsent("key", &first)
+			require.Nil(t, err)
+			assert.True(t, stored)
+			stored, err = cache.SetIfAbsent("key", &second)
+			require.Nil(t, err)
+			assert.False(t, stored)
+
+			value, err := cache.Get("key")
+			require.Nil(t, err)
+			assert.Equal(t, first, *value)
+		})
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0))
+	initial, updated, concurrent := 1, 2, 3
+	require.Nil(t, cache.Set("key", &initial))
+
+	value, version, err := cache.GetWithVersion("key")
+	require.Nil(t, err)
+	assert.Equal(t, initial, *value)
+
+	require.Nil(t, cache.Set("key", &concurrent))
+	swapped, err := cache.CompareAndSwap("key", version, &updated)
+	require.Nil(t, err)
+	assert.False(t, swapped)
+
+	_, version, err = cache.GetWithVersion("key")
+	require.Nil(t, err)
+	swapped, err = cache.CompareAndSwap("key", version, &updated)
+	require.Nil(t, err)
+	assert.True(t, swapped)
+	value, err = cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, updated, *value)
+}
+
+func TestCompareAndSwapNotSupported(t *testing.T) {
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc)
+
+	_, _, err = cache.GetWithVersion("key")
+	assert.ErrorIs(t, err, cachier.ErrVersionsNotSupported)
+	value := 1
+	_, err = cache.CompareAndSwap("key", "1", &value)
+	assert.ErrorIs(t, err, cachier.ErrVersionsNotSupported)
+}
+
+func TestUpdate(t *testing.T) {
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	engines := map[string]cachier.CacheEngine{
+		"memory": cachier.NewMemoryCache(0, 0),
+		"lru":    lc,
+	}
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			cache := cachier.MakeCache[[]int](engine)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := cache.Update("list", func(old *[]int) (*[]int, error) {
+						var list []int
+						if old != nil {
+							list = append(list, *old...)
+						}
+						list = append(list, i)
+						return &list, nil
+					})
+					assert.Nil(t, err)
+				}(i)
+			}
+			wg.Wait()
+
+			list, err := cache.Get("list")
+			require.Nil(t, err)
+			assert.Len(t, *list, 20)
+
+			errMerge := errors.New("merge failed")
+			_, err = cache.Update("list", func(old *[]int) (*[]int, error) {
+				return nil, errMerge
+			})
+			assert.ErrorIs(t, err, errMerge)
+			list, err = cache.Get("list")
+			require.Nil(t, err)
+			assert.Len(t, *list, 20)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package cachier
+
+import (
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/go-redis/redis/v8"
+)
+
+// NewJSONRedisCache creates a Cache of T values stored in redis as JSON under keyPrefix, with the given TTL
+// (0 means no expiration) and compression engine (nil means no compression)
+func NewJSONRedisCache[T any](redisClient *redis.Client, keyPrefix string, ttl time.Duration, compressionEngine *compression.Engine, opts ...Option) *Cache[T] {
+	codec := JSONCodec[T]{}
+	rc := NewRedisCache(redisClient, keyPrefix, codec.Marshal, codec.Unmarshal, ttl, compressionEngine)
+	return MakeCache[T](rc, opts...)
+}
+
+// NewGobLRUCache creates a Cache of T values stored in an LRUCache of the given size. With the compression engine
+// the values are stored gob encoded and compressed, without it (nil) they are stored as they are.
+func NewGobLRUCache[T any](size int, compressionEngine *compression.Engine, opts ...Option) (*Cache[T], error) {
+	codec := NewGobCodec[T]()
+	lc, err := NewLRUCache(size, codec.Marshal, codec.Unmarshal, compressionEngine)
+	if err != nil {
+		return nil, err
+	}
+	return MakeCache[T](lc, opts...), nil
+}
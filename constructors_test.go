@@ -0,0 +1,55 @@
+package cachier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type constructorValue struct {
+	ID   int
+	Tags []string
+}
+
+func TestNewJSONRedisCache(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	compressionEngine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	cache := NewJSONRedisCache[constructorValue](redisClient, "json-constructor:", time.Minute, compressionEngine)
+	defer cache.Purge()
+
+	value := constructorValue{ID: 1, Tags: []string{"a", "b"}}
+	require.Nil(t, cache.Set("key", &value))
+	got, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *got)
+	ttl, err := cache.TTL("key")
+	require.Nil(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+}
+
+func TestNewGobLRUCache(t *testing.T) {
+	compressionEngine, err := compression.NewEngine(compression.ProviderIDZstd, compression.CompressionParams{compression.CompressionParamMinInputLen: 0})
+	require.Nil(t, err)
+	for name, engine := range map[string]*compression.Engine{"compressed": compressionEngine, "plain": nil} {
+		t.Run(name, func(t *testing.T) {
+			cache, err := NewGobLRUCache[constructorValue](10, engine, WithMaxLinkDepth(4))
+			require.Nil(t, err)
+
+			value := constructorValue{ID: 2, Tags: []string{"c"}}
+			require.Nil(t, cache.Set("key", &value))
+			got, err := cache.Get("key")
+			require.Nil(t, err)
+			assert.Equal(t, value, *got)
+		})
+	}
+
+	_, err = NewGobLRUCache[int](0, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,189 @@
+package cachier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultLockPollInterval is how often a process waiting for another process' computation checks the cache
+const defaultLockPollInterval = 25 * time.Millisecond
+
+// DistributedLocker provides locks shared by all the processes using the same cache
+type DistributedLocker interface {
+	// Lock tries to acquire the lock for the key without blocking.
+	// The lock expires after ttl unless it is released by calling unlock.
+	Lock(key string, ttl time.Duration) (unlock func() error, acquired bool, err error)
+}
+
+type distributedLockOptions struct {
+	locker       DistributedLocker
+	ttl          time.Duration
+	wait         time.Duration
+	pollInterval time.Duration
+	// serveStale makes GetOrCompute return stale values (WithSoftTTL) unless it holds the lock (WithLeases)
+	serveStale bool
+}
+
+// WithDistributedLock makes GetOrCompute compute a missing key in one process only.
+// The process holding the lock (at most lockTTL) computes and stores the value, other processes
+// poll the cache for at most wait and compute the value themselves if it does not appear.
+// If the locker fails the value is computed without the lock.
+func WithDistributedLock(locker DistributedLocker, lockTTL time.Duration, wait time.Duration) Option {
+	return func(o *options) {
+		o.distributedLock = &distributedLockOptions{
+			locker:       locker,
+			ttl:          lockTTL,
+			wait:         wait,
+			pollInterval: defaultLockPollInterval,
+		}
+	}
+}
+
+// computeWithDistributedLock computes the missing key in the process holding the distributed lock
+func (c *Cache[T]) computeWithDistributedLock(key string, evaluator func() (*T, time.Duration, error)) (*T, error) {
+	lockOptions := c.options.distributedLock
+	lockKey, err := c.engineKey(key)
+	if err != nil {
+		return c.compute(key, evaluator)
+	}
+	unlock, acquired, err := lockOptions.locker.Lock(lockKey, lockOptions.ttl)
+	if err != nil {
+		return c.compute(key, evaluator)
+	}
+
+	if acquired {
+		defer unlock()
+		// the value could be stored by the previous lock holder
+		if value, err := c.Get(key); err == nil {
+			return value, nil
+		}
+
+		value, ttl, err := evaluator()
+		if err != nil {
+			return nil, err
+		}
+		// stored synchronously so the waiting processes can see it before the lock is released
+		c.SetWithTTL(key, value, ttl)
+		return value, nil
+	}
+
+	deadline := time.Now().Add(lockOptions.wait)
+	for time.Now().Before(deadline) {
+		time.Sleep(lockOptions.pollInterval)
+		if value, err := c.Get(key); err == nil {
+			return value, nil
+		}
+	}
+
+	return c.compute(key, evaluator)
+}
+
+// LocalLocker is a DistributedLocker for Cache instances within one process.
+// Sharing it (with WithDistributedLock) between caches of different types wrapping the same engine
+// makes them compute a missing key once; use RedisLocker to deduplicate across processes.
+type LocalLocker struct {
+	mutex  sync.Mutex
+	locks  map[string]localLock
+	tokens uint64
+	// sweepAt is the number of locks at which the expired ones are removed
+	sweepAt int
+}
+
+// minLocalLockSweep is the smallest number of locks at which LocalLocker removes the expired ones
+const minLocalLockSweep = 64
+
+type localLock struct {
+	token     uint64
+	expiresAt time.Time
+}
+
+// NewLocalLocker creates a LocalLocker
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{
+		locks:   make(map[string]localLock),
+		sweepAt: minLocalLockSweep,
+	}
+}
+
+// Lock tries to acquire the lock; the lock is released only by its owner or when it expires
+func (l *LocalLocker) Lock(key string, ttl time.Duration) (func() error, bool, error) {
+	now := time.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if lock, found := l.locks[key]; found && now.Before(lock.expiresAt) {
+		return nil, false, nil
+	}
+
+	if len(l.locks) >= l.sweepAt {
+		l.sweep(now)
+	}
+	l.tokens++
+	token := l.tokens
+	l.locks[key] = localLock{token: token, expiresAt: now.Add(ttl)}
+	return func() error {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if l.locks[key].token == token {
+			delete(l.locks, key)
+		}
+		return nil
+	}, true, nil
+}
+
+// sweep removes the expired locks which were never released; the next sweep happens once the number
+// of locks doubles so Lock stays amortized O(1)
+func (l *LocalLocker) sweep(now time.Time) {
+	for key, lock := range l.locks {
+		if !now.Before(lock.expiresAt) {
+			delete(l.locks, key)
+		}
+	}
+	l.sweepAt = 2 * len(l.locks)
+	if l.sweepAt < minLocalLockSweep {
+		l.sweepAt = minLocalLockSweep
+	}
+}
+
+// RedisLocker is a DistributedLocker using redis SET NX PX
+type RedisLocker struct {
+	redisClient *redis.Client
+	keyPrefix   string
+}
+
+var redisUnlockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// NewRedisLocker creates a RedisLocker storing the locks under keyPrefix
+func NewRedisLocker(redisClient *redis.Client, keyPrefix string) *RedisLocker {
+	return &RedisLocker{
+		redisClient: redisClient,
+		keyPrefix:   keyPrefix,
+	}
+}
+
+// Lock tries to acquire the lock; the lock is released only by its owner
+func (l *RedisLocker) Lock(key string, ttl time.Duration) (func() error, bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+	tokenString := hex.EncodeToString(token)
+	lockKey := l.keyPrefix + key
+
+	acquired, err := l.redisClient.SetNX(ctx, lockKey, tokenString, ttl).Result()
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	return func() error {
+		return redisUnlockScript.Run(ctx, l.redisClient, []string{lockKey}, tokenString).Err()
+	}, true, nil
+}
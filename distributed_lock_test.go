@@ -0,0 +1,76 @@
+package cachier
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrComputeDistributedLock(t *testing.T) {
+	engine := NewMemoryCache(0, 0)
+	locker := NewLocalLocker()
+
+	var evaluations int32
+	evaluator := func() (*int, error) {
+		atomic.AddInt32(&evaluations, 1)
+		time.Sleep(50 * time.Millisecond)
+		value := 42
+		return &value, nil
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		// every cache simulates a different process sharing the engine
+		cache := MakeCache[int](engine, WithDistributedLock(locker, time.Second, time.Second))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrCompute("key", evaluator)
+			require.Nil(t, err)
+			assert.Equal(t, 42, *value)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&evaluations))
+}
+
+func TestLocalLocker(t *testing.T) {
+	locker := NewLocalLocker()
+	unlock, acquired, err := locker.Lock("key", time.Hour)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+	_, acquired, _ = locker.Lock("key", time.Hour)
+	assert.False(t, acquired)
+	require.Nil(t, unlock())
+
+	_, acquired, _ = locker.Lock("key", time.Millisecond)
+	assert.True(t, acquired)
+	time.Sleep(2 * time.Millisecond)
+	unlock, acquired, _ = locker.Lock("key", time.Hour)
+	assert.True(t, acquired)
+	require.Nil(t, unlock())
+}
+
+func TestLocalLockerRemovesExpiredLocks(t *testing.T) {
+	locker := NewLocalLocker()
+	// locks of crashed owners are never released
+	for i := 0; i < 10*minLocalLockSweep; i++ {
+		_, acquired, err := locker.Lock(fmt.Sprintf("key%d", i), time.Millisecond)
+		require.Nil(t, err)
+		assert.True(t, acquired)
+	}
+	time.Sleep(2 * time.Millisecond)
+	for i := 0; i < 10*minLocalLockSweep; i++ {
+		locker.Lock(fmt.Sprintf("other%d", i), time.Hour)
+	}
+
+	locker.mutex.Lock()
+	defer locker.mutex.Unlock()
+	assert.Len(t, locker.locks, 10*minLocalLockSweep)
+}
@@ -0,0 +1,91 @@
+package cachier
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPendingWritesBudget is returned by the writes held while the writes are paused (see PauseWrites)
+// when the values pending to be written would exceed the budget set by WithPendingWritesBudget
+var ErrPendingWritesBudget = errors.New("pending writes budget exceeded")
+
+// backgroundWrites counts the values computed by GetOrCompute which are still being written
+type backgroundWrites struct {
+	pending atomic.Int64
+	// bytes is the size of the pending values, measured only with WithPendingWritesBudget
+	bytes atomic.Int64
+	// finished is signalled (without blocking) whenever a write finishes
+	finished chan struct{}
+}
+
+func newBackgroundWrites() *backgroundWrites {
+	return &backgroundWrites{
+		finished: make(chan struct{}, 1),
+	}
+}
+
+func (w *backgroundWrites) started(size int64) {
+	w.pending.Add(1)
+	w.bytes.Add(size)
+}
+
+func (w *backgroundWrites) done(size int64) {
+	w.pending.Add(-1)
+	w.bytes.Add(-size)
+	select {
+	case w.finished <- struct{}{}:
+	default:
+	}
+}
+
+// WithPendingWritesBudget limits the approximate size (see WithBytesLimit of RateLimitedEngine) of the values
+// kept in memory until they are written to the engine: the values computed by GetOrCompute are stored
+// synchronously instead of in background and the writes held while the writes are paused fail with
+// ErrPendingWritesBudget once the budget is used up, so an engine outage cannot exhaust the memory of the process.
+// The values are measured only with the budget; see Stats for the pending writes.
+func WithPendingWritesBudget(bytes int64) Option {
+	return func(o *options) {
+		o.pendingBudget = bytes
+	}
+}
+
+// pendingSize returns the size of a value to be written counted by the pending writes budget,
+// 0 without the budget
+func (c *Cache[T]) pendingSize(value *T) int64 {
+	if c.options.pendingBudget <= 0 || value == nil {
+		return 0
+	}
+	if size := valueLen(value); size > 0 {
+		return int64(size)
+	}
+	return 0
+}
+
+// overBudget reports whether writing a value of the size would exceed the pending writes budget
+func (c *Cache[T]) overBudget(size int64) bool {
+	budget := c.options.pendingBudget
+	return budget > 0 && c.writes.bytes.Load()+c.held.bytes.Load()+size > budget
+}
+
+// DrainWithin waits until the values computed by GetOrCompute are written to the engine,
+// e.g. before the process exits. progress (can be nil) is called with the number of remaining
+// writes whenever it changes. If ctx is done first, the number of remaining writes is returned with ctx.Err().
+func (c *Cache[T]) DrainWithin(ctx context.Context, progress func(remaining int)) (int, error) {
+	last := -1
+	for {
+		remaining := int(c.writes.pending.Load())
+		if remaining == 0 {
+			return 0, nil
+		}
+		if progress != nil && remaining != last {
+			progress(remaining)
+			last = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return remaining, ctx.Err()
+		case <-c.writes.finished:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package cachier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainWithin(t *testing.T) {
+	engine := cachiertest.NewEngine().InjectFault(cachiertest.OpSet, cachiertest.Fault{Latency: 50 * time.Millisecond, LatencyOnly: true})
+	cache := cachier.MakeCache[int](engine)
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := cache.GetOrCompute(key, func() (*int, error) {
+			value := 1
+			return &value, nil
+		})
+		require.Nil(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	remaining, err := cache.DrainWithin(ctx, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 3, remaining)
+
+	var reported []int
+	remaining, err = cache.DrainWithin(context.Background(), func(remaining int) {
+		reported = append(reported, remaining)
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 0, remaining)
+	assert.Equal(t, 3, reported[0])
+	assert.Equal(t, 3, engine.Len())
+}
+
+func TestPendingWritesBudget(t *testing.T) {
+	engine := cachiertest.NewEngine().InjectFault(cachiertest.OpSet, cachiertest.Fault{Latency: 50 * time.Millisecond, LatencyOnly: true})
+	cache := cachier.MakeCache[string](engine, cachier.WithPendingWritesBudget(10))
+	evaluator := func() (*string, error) {
+		value := "12345678"
+		return &value, nil
+	}
+
+	_, err := cache.GetOrCompute("x", evaluator)
+	require.Nil(t, err)
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.PendingWrites)
+	assert.Equal(t, int64(8), stats.PendingBytes)
+
+	// the next value does not fit, so it is written before GetOrCompute returns
+	_, err = cache.GetOrCompute("y", evaluator)
+	require.Nil(t, err)
+	_, err = engine.Peek("y")
+	require.Nil(t, err)
+
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+	assert.Zero(t, cache.Stats().PendingBytes)
+}
+
+func TestPendingWritesBudgetOfHeldWrites(t *testing.T) {
+	cache := cachier.MakeCache[string](cachier.NewMemoryCache(0, 0), cachier.WithPendingWritesBudget(10))
+	five, one := "12345", "1"
+
+	cache.PauseWrites()
+	require.Nil(t, cache.Set("a", &five))
+	require.Nil(t, cache.Set("b", &five))
+	assert.ErrorIs(t, cache.Set("c", &one), cachier.ErrPendingWritesBudget)
+	// replacing a held value counts only the difference
+	require.Nil(t, cache.Set("a", &one))
+	require.Nil(t, cache.Delete("b"))
+	require.Nil(t, cache.Set("c", &five))
+	stats := cache.Stats()
+	assert.Equal(t, 3, stats.PendingWrites)
+	assert.Equal(t, int64(6), stats.PendingBytes)
+
+	require.Nil(t, cache.ResumeWrites())
+	stats = cache.Stats()
+	assert.Zero(t, stats.PendingWrites)
+	assert.Zero(t, stats.PendingBytes)
+}
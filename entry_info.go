@@ -0,0 +1,70 @@
+package cachier
+
+import "time"
+
+// Sources of entries reported by EntryInfo
+const (
+	SourceEngine   = "engine"
+	SourceSubcache = "subcache"
+)
+
+// EntryInfo describes a stored entry; fields unknown to the engine are left zero
+type EntryInfo struct {
+	// StoredAt is when the entry was stored
+	StoredAt time.Time
+	// TTL is the remaining time to live; 0 means the entry does not expire
+	TTL time.Duration
+	// Size is the size of the stored payload in bytes (0 for values stored as they are)
+	Size int
+	// UncompressedSize is the size of the marshaled value before compression
+	UncompressedSize int
+	// Compression is the name of the compression provider ("none" if the payload was too small to compress)
+	Compression string
+	// Source is the tier the entry was found in: SourceEngine or SourceSubcache (for CacheWithSubcache)
+	Source string
+}
+
+// GetWithInfo gets a cached value by key together with the metadata of the entry
+func (c *Cache[T]) GetWithInfo(key string) (*T, EntryInfo, error) {
+	info, err := c.inspect(key)
+	if err != nil {
+		return nil, EntryInfo{}, err
+	}
+	value, err := c.Get(key)
+	if err != nil {
+		return nil, EntryInfo{}, err
+	}
+	return value, info, nil
+}
+
+// inspect returns the metadata of the entry using EntryInspector if the engine implements it,
+// otherwise only TTL and StoredAt (if the engine implements EntryAger) are filled
+func (c *Cache[T]) inspect(key string) (EntryInfo, error) {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return EntryInfo{}, wrapKeyError(OpPeek, key, err)
+	}
+
+	if inspector, ok := c.engine.(EntryInspector); ok {
+		info, err := inspector.Inspect(engineKey)
+		if err != nil {
+			return EntryInfo{}, wrapKeyError(OpPeek, key, err)
+		}
+		if ttl, found := c.expiry.ttl(engineKey); found {
+			info.TTL = ttl
+		}
+		return info, nil
+	}
+
+	ttl, err := c.TTL(key)
+	if err != nil {
+		return EntryInfo{}, err
+	}
+	info := EntryInfo{TTL: ttl, Source: SourceEngine}
+	if ager, ok := engineAs[EntryAger](c.engine); ok {
+		if age, err := ager.Age(engineKey); err == nil {
+			info.StoredAt = clockOrDefault(c.options.clock).Now().Add(-age)
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,62 @@
+package cachier_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWithInfoCompressed(t *testing.T) {
+	ce, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	lc, err := cachier.NewLRUCache(10, json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, ce)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[string](lc)
+
+	value := strings.Repeat("a", 2000)
+	require.Nil(t, cache.Set("key", &value))
+	output, info, err := cache.GetWithInfo("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *output)
+	assert.Equal(t, "zstd", info.Compression)
+	assert.Equal(t, len(value)+2, info.UncompressedSize)
+	assert.Less(t, info.Size, info.UncompressedSize)
+	assert.Equal(t, cachier.SourceEngine, info.Source)
+	assert.False(t, info.StoredAt.IsZero())
+
+	_, _, err = cache.GetWithInfo("missing")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
+
+func TestGetWithInfoSubcache(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	primary := cachier.MakeCache[int](cachier.NewMemoryCacheWithClock(time.Hour, 0, clock))
+	subcache := cachier.MakeCache[int](cachier.NewMemoryCacheWithClock(time.Minute, 0, clock))
+	cache := cachier.MakeCache[int](&cachier.CacheWithSubcache[int]{Cache: primary, Subcache: subcache})
+
+	value := 1
+	require.Nil(t, primary.Set("key", &value))
+	clock.Advance(time.Second)
+
+	_, info, err := cache.GetWithInfo("key")
+	require.Nil(t, err)
+	assert.Equal(t, cachier.SourceEngine, info.Source)
+	assert.Equal(t, time.Hour-time.Second, info.TTL)
+	assert.Equal(t, clock.Now().Add(-time.Second), info.StoredAt)
+
+	// the value is stored into the subcache by Get in background
+	require.Eventually(t, func() bool {
+		_, info, err = cache.GetWithInfo("key")
+		return err == nil && info.Source == cachier.SourceSubcache
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, time.Minute, info.TTL)
+}
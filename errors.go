@@ -0,0 +1,63 @@
+package cachier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Operations reported in KeyError and Call
+const (
+	OpGet    = "get"
+	OpPeek   = "peek"
+	OpSet    = "set"
+	OpDelete = "delete"
+	OpKeys   = "keys"
+	OpPurge  = "purge"
+)
+
+// KeyError records the key and the operation which failed.
+// It wraps the underlying error, so errors.Is(err, ErrNotFound) keeps working.
+type KeyError struct {
+	Key string
+	Op  string
+	Err error
+}
+
+// Error returns the error message including the operation and the key
+func (e *KeyError) Error() string {
+	return "cachier: " + e.Op + " " + e.Key + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// serializationError marks err as ErrSerialization
+func serializationError(err error) error {
+	return fmt.Errorf("%w: %w", ErrSerialization, err)
+}
+
+// engineError marks err as ErrEngineUnavailable if it is caused by the engine being unreachable
+func engineError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrEngineUnavailable, err)
+	}
+	return err
+}
+
+// wrapKeyError wraps err in KeyError unless it is nil or already a KeyError
+func wrapKeyError(op string, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var keyErr *KeyError
+	if errors.As(err, &keyErr) {
+		return err
+	}
+	return &KeyError{Key: key, Op: op, Err: err}
+}
@@ -0,0 +1,62 @@
+package cachier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyError(t *testing.T) {
+	c := InitLRUCache[int]()
+	_, err := c.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var keyErr *KeyError
+	assert.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "missing", keyErr.Key)
+	assert.Equal(t, OpGet, keyErr.Op)
+	assert.Equal(t, "cachier: get missing: key not found", err.Error())
+
+	// KeyError returned by the engine is not wrapped again
+	assert.Equal(t, err, wrapKeyError(OpPeek, "other", err))
+}
+
+func TestErrorKinds(t *testing.T) {
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	assert.Nil(t, err)
+
+	lc, err := NewLRUCache(10,
+		func(value interface{}) ([]byte, error) {
+			return nil, errors.New("cannot marshal")
+		},
+		func(b []byte, value *interface{}) error {
+			return errors.New("cannot unmarshal")
+		},
+		engine)
+	assert.Nil(t, err)
+	c := MakeCache[int](lc)
+
+	value := 1
+	assert.ErrorIs(t, c.Set("key", &value), ErrSerialization)
+
+	lc.store.Add("corrupted", []byte{})
+	_, err = c.Get("corrupted")
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	lc.store.Add("undecodable", []byte{'x', 0})
+	_, err = c.Get("undecodable")
+	assert.ErrorIs(t, err, ErrSerialization)
+
+	_, err = engine.Decompress([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, compression.ProviderIDZstd})
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestEngineUnavailable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	rc := NewRedisCache(client, "", nil, nil, 0, nil)
+	_, err := rc.Get("key")
+	assert.ErrorIs(t, err, ErrEngineUnavailable)
+}
@@ -0,0 +1,335 @@
+package cachier
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// EvictionPolicy decides which entries an in-memory engine (LRUCache) evicts when it is full.
+// The engine calls it under its lock, so it does not need to be safe for concurrent use.
+type EvictionPolicy interface {
+	// Touch records that the key was stored or read
+	Touch(key string)
+	// Admit reports whether the new key is stored into the full cache in place of victim
+	Admit(key string, victim string) bool
+	// Victim returns the key to be evicted next; ok is false if the policy tracks no keys
+	Victim() (key string, ok bool)
+	// Remove forgets the key, which was deleted or evicted
+	Remove(key string)
+}
+
+// entryStore holds the entries of LRUCache and evicts them when it is full
+type entryStore interface {
+	// Add stores the value and reports whether it was stored (the policy may refuse new keys)
+	Add(key string, value interface{}) bool
+	Get(key string) (interface{}, bool)
+	Peek(key string) (interface{}, bool)
+	Remove(key string)
+	Keys() []string
+	Len() int
+	Purge()
+}
+
+// lruStore is the default entryStore of hashicorp's golang-lru cache
+type lruStore struct {
+	lru *lru.Cache
+}
+
+func newLRUStore(size int, onEvicted func(key interface{}, value interface{})) (*lruStore, error) {
+	lruHashicorp, err := lru.NewWithEvict(size, onEvicted)
+	if err != nil {
+		return nil, err
+	}
+	return &lruStore{lru: lruHashicorp}, nil
+}
+
+func (s *lruStore) Add(key string, value interface{}) bool {
+	s.lru.Add(key, value)
+	return true
+}
+
+func (s *lruStore) Get(key string) (interface{}, bool) {
+	return s.lru.Get(key)
+}
+
+func (s *lruStore) Peek(key string) (interface{}, bool) {
+	return s.lru.Peek(key)
+}
+
+func (s *lruStore) Remove(key string) {
+	s.lru.Remove(key)
+}
+
+// Keys returns the keys from the oldest to the newest
+func (s *lruStore) Keys() []string {
+	lruKeys := s.lru.Keys()
+	keys := make([]string, len(lruKeys))
+	for i, key := range lruKeys {
+		keys[i] = key.(string)
+	}
+	return keys
+}
+
+func (s *lruStore) Len() int {
+	return s.lru.Len()
+}
+
+func (s *lruStore) Purge() {
+	s.lru.Purge()
+}
+
+// policyStore is an entryStore evicting the entries chosen by an EvictionPolicy
+type policyStore struct {
+	mutex     sync.Mutex
+	size      int
+	policy    EvictionPolicy
+	entries   map[string]interface{}
+	onEvicted func(key interface{}, value interface{})
+}
+
+func newPolicyStore(size int, policy EvictionPolicy, onEvicted func(key interface{}, value interface{})) *policyStore {
+	return &policyStore{
+		size:      size,
+		policy:    policy,
+		entries:   make(map[string]interface{}),
+		onEvicted: onEvicted,
+	}
+}
+
+func (s *policyStore) Add(key string, value interface{}) bool {
+	s.mutex.Lock()
+	if _, found := s.entries[key]; found || len(s.entries) < s.size {
+		s.entries[key] = value
+		s.policy.Touch(key)
+		s.mutex.Unlock()
+		return true
+	}
+
+	victim, ok := s.policy.Victim()
+	if ok && !s.policy.Admit(key, victim) {
+		s.mutex.Unlock()
+		return false
+	}
+	var victimValue interface{}
+	evicted := false
+	if ok {
+		victimValue, evicted = s.entries[victim]
+		delete(s.entries, victim)
+		s.policy.Remove(victim)
+	}
+	s.entries[key] = value
+	s.policy.Touch(key)
+	s.mutex.Unlock()
+
+	if evicted && s.onEvicted != nil {
+		s.onEvicted(victim, victimValue)
+	}
+	return true
+}
+
+func (s *policyStore) Get(key string) (interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	value, found := s.entries[key]
+	if found {
+		s.policy.Touch(key)
+	}
+	return value, found
+}
+
+func (s *policyStore) Peek(key string) (interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	value, found := s.entries[key]
+	return value, found
+}
+
+func (s *policyStore) Remove(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, found := s.entries[key]; found {
+		delete(s.entries, key)
+		s.policy.Remove(key)
+	}
+}
+
+func (s *policyStore) Keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	keys := make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *policyStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.entries)
+}
+
+func (s *policyStore) Purge() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key := range s.entries {
+		s.policy.Remove(key)
+	}
+	s.entries = make(map[string]interface{})
+}
+
+// listPolicy keeps the keys in a list from the newest to the oldest and evicts the oldest one
+type listPolicy struct {
+	order    *list.List
+	elements map[string]*list.Element
+	// moveOnTouch moves touched keys to the front (LRU), otherwise only new keys are added (FIFO)
+	moveOnTouch bool
+}
+
+// NewLRUPolicy creates an EvictionPolicy evicting the least recently used key
+func NewLRUPolicy() EvictionPolicy {
+	return &listPolicy{order: list.New(), elements: make(map[string]*list.Element), moveOnTouch: true}
+}
+
+// NewFIFOPolicy creates an EvictionPolicy evicting the key stored first; reads do not matter
+func NewFIFOPolicy() EvictionPolicy {
+	return &listPolicy{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+func (p *listPolicy) Touch(key string) {
+	if element, found := p.elements[key]; found {
+		if p.moveOnTouch {
+			p.order.MoveToFront(element)
+		}
+		return
+	}
+	p.elements[key] = p.order.PushFront(key)
+}
+
+func (p *listPolicy) Admit(key string, victim string) bool {
+	return true
+}
+
+func (p *listPolicy) Victim() (string, bool) {
+	element := p.order.Back()
+	if element == nil {
+		return "", false
+	}
+	return element.Value.(string), true
+}
+
+func (p *listPolicy) Remove(key string) {
+	if element, found := p.elements[key]; found {
+		p.order.Remove(element)
+		delete(p.elements, key)
+	}
+}
+
+// lfuPolicy evicts the least frequently used key; among keys with the same frequency the least recently used one
+type lfuPolicy struct {
+	entries lfuHeap
+	byKey   map[string]*lfuEntry
+	ticks   uint64
+	// halveEvery (if > 0) is the number of touches after which all the frequencies are halved
+	halveEvery uint64
+}
+
+type lfuEntry struct {
+	key       string
+	frequency uint64
+	lastTouch uint64
+	index     int
+}
+
+// NewLFUPolicy creates an EvictionPolicy evicting the least frequently used key
+func NewLFUPolicy() EvictionPolicy {
+	return &lfuPolicy{byKey: make(map[string]*lfuEntry)}
+}
+
+// NewAgingLFUPolicy creates an EvictionPolicy evicting the least frequently used key, which halves
+// the frequencies of all the keys after every halveEvery touches, so keys which were popular long ago
+// do not stay forever
+func NewAgingLFUPolicy(halveEvery int) EvictionPolicy {
+	policy := &lfuPolicy{byKey: make(map[string]*lfuEntry)}
+	if halveEvery > 0 {
+		policy.halveEvery = uint64(halveEvery)
+	}
+	return policy
+}
+
+func (p *lfuPolicy) Touch(key string) {
+	p.ticks++
+	if p.halveEvery > 0 && p.ticks%p.halveEvery == 0 {
+		p.age()
+	}
+	if entry, found := p.byKey[key]; found {
+		entry.frequency++
+		entry.lastTouch = p.ticks
+		heap.Fix(&p.entries, entry.index)
+		return
+	}
+	entry := &lfuEntry{key: key, frequency: 1, lastTouch: p.ticks}
+	p.byKey[key] = entry
+	heap.Push(&p.entries, entry)
+}
+
+// age halves the frequencies of all the keys
+func (p *lfuPolicy) age() {
+	for _, entry := range p.entries {
+		entry.frequency /= 2
+	}
+	heap.Init(&p.entries)
+}
+
+func (p *lfuPolicy) Admit(key string, victim string) bool {
+	return true
+}
+
+func (p *lfuPolicy) Victim() (string, bool) {
+	if len(p.entries) == 0 {
+		return "", false
+	}
+	return p.entries[0].key, true
+}
+
+func (p *lfuPolicy) Remove(key string) {
+	if entry, found := p.byKey[key]; found {
+		heap.Remove(&p.entries, entry.index)
+		delete(p.byKey, key)
+	}
+}
+
+// lfuHeap orders the entries from the least frequently and least recently used one
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].frequency != h[j].frequency {
+		return h[i].frequency < h[j].frequency
+	}
+	return h[i].lastTouch < h[j].lastTouch
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	entry := x.(*lfuEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,129 @@
+package cachier_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicyCache(t *testing.T, size int, policy cachier.EvictionPolicy) (*cachier.LRUCache, *cachier.Cache[int]) {
+	lc, err := cachier.NewLRUCache(size, nil, nil, nil)
+	require.Nil(t, err)
+	lc.WithEvictionPolicy(policy)
+	return lc, cachier.MakeCache[int](lc)
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	for name, test := range map[string]struct {
+		policy cachier.EvictionPolicy
+		kept   []string
+	}{
+		// "a" is read most often but least recently, so only LFU keeps it; FIFO evicts it as the oldest one
+		"lru":  {policy: cachier.NewLRUPolicy(), kept: []string{"b", "c", "d"}},
+		"fifo": {policy: cachier.NewFIFOPolicy(), kept: []string{"b", "c", "d"}},
+		"lfu":  {policy: cachier.NewLFUPolicy(), kept: []string{"a", "c", "d"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			lc, cache := newPolicyCache(t, 3, test.policy)
+			value := 1
+			for _, key := range []string{"a", "b", "c"} {
+				require.Nil(t, cache.Set(key, &value))
+			}
+			for i := 0; i < 3; i++ {
+				_, err := cache.Get("a")
+				require.Nil(t, err)
+			}
+			for _, key := range []string{"b", "c"} {
+				_, err := cache.Get(key)
+				require.Nil(t, err)
+			}
+			require.Nil(t, cache.Set("d", &value))
+
+			keys, err := cache.Keys()
+			require.Nil(t, err)
+			assert.ElementsMatch(t, test.kept, keys)
+			assert.Equal(t, uint64(1), lc.EvictionStats().Evicted)
+		})
+	}
+}
+
+// refusingPolicy is an LRU policy which does not admit new keys into the full cache
+type refusingPolicy struct {
+	cachier.EvictionPolicy
+}
+
+func (refusingPolicy) Admit(key string, victim string) bool {
+	return false
+}
+
+func TestEvictionPolicyAdmission(t *testing.T) {
+	lc, err := cachier.NewLRUCache(2, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc)
+	value := 1
+	for i := 0; i < 2; i++ {
+		require.Nil(t, cache.Set(fmt.Sprint(i), &value))
+	}
+	// the stored entries are kept when the policy is changed
+	lc.WithEvictionPolicy(refusingPolicy{cachier.NewLRUPolicy()})
+
+	require.Nil(t, cache.Set("new", &value))
+	_, err = cache.Get("new")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"0", "1"}, keys)
+
+	require.Nil(t, cache.Delete("0"))
+	require.Nil(t, cache.Set("new", &value))
+	_, err = cache.Get("new")
+	assert.Nil(t, err)
+	require.Nil(t, cache.Purge())
+	count, err := cache.Count()
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestLFUCacheScan(t *testing.T) {
+	lc, err := cachier.NewLFUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc)
+	value := 1
+	hot := []string{"hot1", "hot2", "hot3"}
+	for _, key := range hot {
+		require.Nil(t, cache.Set(key, &value))
+		for i := 0; i < 5; i++ {
+			_, err := cache.Get(key)
+			require.Nil(t, err)
+		}
+	}
+
+	// a scan of many keys read once does not flush the hot keys
+	for i := 0; i < 50; i++ {
+		require.Nil(t, cache.Set(fmt.Sprint("scan", i), &value))
+	}
+	for _, key := range hot {
+		_, err := cache.Get(key)
+		assert.Nil(t, err, key)
+	}
+}
+
+func TestAgingLFUPolicy(t *testing.T) {
+	_, cache := newPolicyCache(t, 2, cachier.NewAgingLFUPolicy(4))
+	value := 1
+	require.Nil(t, cache.Set("old", &value))
+	for i := 0; i < 6; i++ {
+		cache.Get("old")
+	}
+	// the frequency of "old" was halved twice, so it is not higher than the one of the recently read "new"
+	require.Nil(t, cache.Set("new", &value))
+	cache.Get("new")
+	require.Nil(t, cache.Set("next", &value))
+
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"new", "next"}, keys)
+}
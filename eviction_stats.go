@@ -0,0 +1,118 @@
+package cachier
+
+import (
+	"sync"
+	"time"
+)
+
+// evictionAgeBounds are the upper bounds of the age buckets of EvictionStats
+var evictionAgeBounds = []time.Duration{time.Second, time.Minute, 10 * time.Minute, time.Hour}
+
+// EvictionStats describes the entries evicted by an engine to make room for new ones
+// (deleted and purged entries are not counted)
+type EvictionStats struct {
+	// Evicted is the number of evicted entries
+	Evicted uint64 `json:"evicted"`
+	// AgeBounds are the upper bounds of the age buckets
+	AgeBounds []time.Duration `json:"age_bounds"`
+	// EvictedByAge counts the evicted entries by the time since they were stored: EvictedByAge[i] counts
+	// the entries younger than AgeBounds[i] not counted by the previous buckets, the last one counts the rest
+	EvictedByAge []uint64 `json:"evicted_by_age"`
+}
+
+// evictionTracker tracks when the entries were stored and counts their evictions
+type evictionTracker struct {
+	clock    Clock
+	mutex    sync.Mutex
+	storedAt map[string]time.Time
+	evicted  uint64
+	byAge    []uint64
+}
+
+func newEvictionTracker() *evictionTracker {
+	return &evictionTracker{
+		clock:    clockOrDefault(nil),
+		storedAt: make(map[string]time.Time),
+		byAge:    make([]uint64, len(evictionAgeBounds)+1),
+	}
+}
+
+// added records that the key was stored
+func (t *evictionTracker) added(key string) {
+	now := t.clock.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.storedAt[key] = now
+}
+
+// removed forgets the key; it must be called before the key is removed from the engine,
+// so the removal is not counted as an eviction
+func (t *evictionTracker) removed(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.storedAt, key)
+}
+
+// restored remembers when the key was stored, e.g. after it was moved within the engine
+func (t *evictionTracker) restored(key string, storedAt time.Time) {
+	if storedAt.IsZero() {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.storedAt[key] = storedAt
+}
+
+// purged forgets all the keys; it must be called before the engine is purged
+func (t *evictionTracker) purged() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.storedAt = make(map[string]time.Time)
+}
+
+// storedAtOf returns when the key was stored (zero time if unknown)
+func (t *evictionTracker) storedAtOf(key string) time.Time {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.storedAt[key]
+}
+
+// onEvicted is the eviction callback of the engine; keys which were not forgotten before are counted
+func (t *evictionTracker) onEvicted(key interface{}, value interface{}) {
+	now := t.clock.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	storedAt, found := t.storedAt[key.(string)]
+	if !found {
+		return
+	}
+	delete(t.storedAt, key.(string))
+
+	t.evicted++
+	age := now.Sub(storedAt)
+	bucket := 0
+	for bucket < len(evictionAgeBounds) && age >= evictionAgeBounds[bucket] {
+		bucket++
+	}
+	t.byAge[bucket]++
+}
+
+func (t *evictionTracker) stats() EvictionStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return EvictionStats{
+		Evicted:      t.evicted,
+		AgeBounds:    append([]time.Duration(nil), evictionAgeBounds...),
+		EvictedByAge: append([]uint64(nil), t.byAge...),
+	}
+}
+
+// EvictionStats returns the eviction statistics of the engine; ok is false if the engine
+// does not implement EvictionReporter
+func (c *Cache[T]) EvictionStats() (stats EvictionStats, ok bool) {
+	reporter, ok := c.engine.(EvictionReporter)
+	if !ok {
+		return EvictionStats{}, false
+	}
+	return reporter.EvictionStats(), true
+}
@@ -0,0 +1,37 @@
+package cachier_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUEvictionStats(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	lc, err := cachier.NewLRUCache(2, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc.WithClock(clock))
+
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	require.Nil(t, cache.Set("b", &value))
+	require.Nil(t, cache.Delete("b"))
+	require.Nil(t, cache.Set("c", &value))
+	clock.Advance(2 * time.Minute)
+	require.Nil(t, cache.Set("d", &value))
+	require.Nil(t, cache.Set("e", &value))
+	require.Nil(t, cache.Purge())
+
+	stats, ok := cache.EvictionStats()
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), stats.Evicted)
+	assert.Equal(t, []uint64{0, 0, 2, 0, 0}, stats.EvictedByAge)
+	assert.Len(t, stats.AgeBounds, len(stats.EvictedByAge)-1)
+
+	_, ok = cachier.MakeCache[int](cachier.NewMemoryCache(0, 0)).EvictionStats()
+	assert.False(t, ok)
+}
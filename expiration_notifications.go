@@ -0,0 +1,17 @@
+package cachier
+
+import "errors"
+
+// ErrNotificationsNotSupported is returned by OnExpired if the engine does not implement ExpirationNotifier
+var ErrNotificationsNotSupported = errors.New("engine does not report expired keys")
+
+// OnExpired calls fn for every key expired or evicted by the engine (e.g. to recompute it proactively)
+// until stop is called. The keys are the keys of the engine as listed by Keys, so keys mapped by
+// WithKeyHasher are reported hashed. fn is called from a single goroutine and should not block for long.
+func (c *Cache[T]) OnExpired(fn func(key string)) (stop func() error, err error) {
+	notifier, ok := c.engine.(ExpirationNotifier)
+	if !ok {
+		return nil, ErrNotificationsNotSupported
+	}
+	return notifier.NotifyExpired(fn)
+}
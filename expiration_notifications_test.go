@@ -0,0 +1,54 @@
+package cachier
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnExpiredNotSupported(t *testing.T) {
+	_, err := InitLRUCache[int]().OnExpired(func(string) {})
+	assert.ErrorIs(t, err, ErrNotificationsNotSupported)
+}
+
+func TestRedisOnExpired(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "expired:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil)
+	cache := MakeCache[int](rc)
+
+	expired := make(chan string, 10)
+	stop, err := cache.OnExpired(func(key string) { expired <- key })
+	require.Nil(t, err)
+
+	// the notifications are published as redis does it, so the test does not depend on the server configuration
+	db := redisClient.Options().DB
+	for _, key := range []string{"other:a", "expired:a" + chunkKeySeparator + "0", "expired:a", "expired:b"} {
+		require.Nil(t, redisClient.Publish(ctx, fmt.Sprintf("__keyevent@%d__:expired", db), key).Err())
+	}
+	require.Nil(t, redisClient.Publish(ctx, fmt.Sprintf("__keyevent@%d__:evicted", db), "expired:c").Err())
+
+	for _, key := range []string{"a", "b", "c"} {
+		select {
+		case got := <-expired:
+			assert.Equal(t, key, got)
+		case <-time.After(time.Second):
+			t.Fatalf("key %s not reported", key)
+		}
+	}
+
+	require.Nil(t, stop())
+	select {
+	case key := <-expired:
+		t.Fatalf("unexpected key %s", key)
+	default:
+	}
+}
@@ -0,0 +1,115 @@
+package cachier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// exportFormatVersion is the version of the stream written by Cache.Export
+const exportFormatVersion = 1
+
+// ExportedEntry is a cache entry in its stored (marshaled and possibly compressed) form
+type ExportedEntry struct {
+	Key     string        `json:"key"`
+	TTL     time.Duration `json:"ttl,omitempty"`
+	Payload []byte        `json:"payload"`
+}
+
+// EntryExporter is an optional interface of CacheEngine.
+// Engines implementing it can copy entries without decoding them, which is used by Cache.Export and Cache.Import.
+// The payload is the marshaled value compressed by the engine's compression.Engine (if any),
+// so entries can be imported only into engines using the same marshaling and compression.
+type EntryExporter interface {
+	ExportEntry(key string) (*ExportedEntry, error)
+	ImportEntry(entry *ExportedEntry) error
+}
+
+type exportHeader struct {
+	Version int `json:"version"`
+}
+
+// Export writes all the cache entries to w as a stream of JSON lines (key, remaining TTL, stored payload).
+// Entries which disappear during the export are skipped.
+func (c *Cache[T]) Export(ctx context.Context, w io.Writer) error {
+	exporter, ok := c.engine.(EntryExporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	if err := encoder.Encode(exportHeader{Version: exportFormatVersion}); err != nil {
+		return err
+	}
+
+	var exportErr error
+	err := c.Range(func(key string) bool {
+		if exportErr = ctx.Err(); exportErr != nil {
+			return false
+		}
+
+		entry, err := exporter.ExportEntry(key)
+		if errors.Is(err, ErrNotFound) {
+			return true
+		} else if err != nil {
+			exportErr = fmt.Errorf("exporting key %s: %w", key, err)
+			return false
+		}
+
+		exportErr = encoder.Encode(entry)
+		return exportErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if exportErr != nil {
+		return exportErr
+	}
+
+	return bw.Flush()
+}
+
+// Import reads entries written by Export from r and stores them into cache
+func (c *Cache[T]) Import(ctx context.Context, r io.Reader) error {
+	exporter, ok := c.engine.(EntryExporter)
+	if !ok {
+		return ErrExportNotSupported
+	}
+	if err := c.writeAllDirectly(); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	var header exportHeader
+	if err := decoder.Decode(&header); err != nil {
+		return err
+	}
+	if header.Version != exportFormatVersion {
+		return ErrExportFormat
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var entry ExportedEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		lock := c.lockKey(entry.Key)
+		err := exporter.ImportEntry(&entry)
+		c.unlock(lock)
+		if err != nil {
+			return fmt.Errorf("importing key %s: %w", entry.Key, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cachier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initCompressedLRUCache(t *testing.T) *Cache[string] {
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	lc, err := NewLRUCache(100,
+		func(value interface{}) ([]byte, error) {
+			return json.Marshal(value)
+		},
+		func(b []byte, value *interface{}) error {
+			return json.Unmarshal(b, value)
+		},
+		engine)
+	require.Nil(t, err)
+	return MakeCache[string](lc)
+}
+
+func TestExportImport(t *testing.T) {
+	source := initCompressedLRUCache(t)
+	long := strings.Repeat("hello world", 200)
+	short := "hello"
+	require.Nil(t, source.Set("long", &long))
+	require.Nil(t, source.Set("short", &short))
+
+	var buf bytes.Buffer
+	require.Nil(t, source.Export(context.Background(), &buf))
+
+	target := initCompressedLRUCache(t)
+	require.Nil(t, target.Import(context.Background(), &buf))
+
+	output, err := target.Get("long")
+	require.Nil(t, err)
+	assert.Equal(t, long, *output)
+	output, err = target.Get("short")
+	require.Nil(t, err)
+	assert.Equal(t, short, *output)
+}
+
+func TestExportNotSupported(t *testing.T) {
+	c := MakeCache[int](NewMemoryCache(0, 0))
+	assert.Equal(t, ErrExportNotSupported, c.Export(context.Background(), &bytes.Buffer{}))
+}
+
+func TestImportUncompressedLRU(t *testing.T) {
+	codec := JSONCodec[string]{}
+	source, err := NewLRUCache(100, codec.Marshal, codec.Unmarshal, nil)
+	require.Nil(t, err)
+	target, err := NewLRUCache(100, codec.Marshal, codec.Unmarshal, nil)
+	require.Nil(t, err)
+	value := "hello"
+	require.Nil(t, source.Set("key", &value))
+
+	// the values are stored decoded, so the payloads cannot be imported
+	entry, err := source.ExportEntry("key")
+	require.Nil(t, err)
+	assert.ErrorIs(t, target.ImportEntry(entry), ErrExportNotSupported)
+	var buf bytes.Buffer
+	require.Nil(t, MakeCache[string](source).Export(context.Background(), &buf))
+	assert.ErrorIs(t, MakeCache[string](target).Import(context.Background(), &buf), ErrExportNotSupported)
+
+	// the entries are moved by value instead
+	stored, err := moveEntry("key", source, target)
+	require.Nil(t, err)
+	assert.True(t, stored)
+	output, err := MakeCache[string](target).Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *output)
+}
@@ -0,0 +1,370 @@
+package cachier
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// FailoverEngine is a CacheEngine which uses the primary engine and falls back to the secondary one
+// when the primary fails (e.g. Redis outage with LRU-only degradation).
+// Keys written or deleted in the secondary engine while the primary is failing are remembered and
+// replayed into the primary engine (with their remaining TTL) once it recovers; they are then removed from
+// the secondary engine. Keys written to the primary engine during the replay are not overwritten by it.
+// Errors which are not engine failures (e.g. ErrNotFound, ErrSerialization, ErrCorrupted) do not cause failover.
+type FailoverEngine struct {
+	primary   CacheEngine
+	secondary CacheEngine
+	logger    Logger
+
+	mutex   sync.Mutex
+	failing bool
+	purged  bool
+	// dirty holds keys modified in the secondary engine; true for Set, false for Delete
+	dirty map[string]bool
+	// replaying holds the keys not replayed yet by the running Reconcile with the locks serializing their replay
+	// with the writes to the primary engine
+	replaying map[string]*sync.Mutex
+}
+
+// NewFailoverEngine creates a FailoverEngine
+func NewFailoverEngine(primary, secondary CacheEngine) *FailoverEngine {
+	return &FailoverEngine{
+		primary:   primary,
+		secondary: secondary,
+		logger:    DummyLogger{},
+		dirty:     make(map[string]bool),
+	}
+}
+
+// WithLogger sets the logger used to report failover and reconciliation
+func (fe *FailoverEngine) WithLogger(logger Logger) *FailoverEngine {
+	fe.logger = loggerOrDefault(logger)
+	return fe
+}
+
+// Failing reports whether the primary engine is considered failing
+func (fe *FailoverEngine) Failing() bool {
+	fe.mutex.Lock()
+	defer fe.mutex.Unlock()
+	return fe.failing
+}
+
+// failed marks the primary engine failing if err is an engine failure and reports it
+func (fe *FailoverEngine) failed(err error) bool {
+	if err == nil || !isEngineFailure(err) {
+		return false
+	}
+	fe.mutex.Lock()
+	if !fe.failing {
+		fe.logger.Warn("failover: primary engine failed, using secondary engine: ", err)
+	}
+	fe.failing = true
+	fe.mutex.Unlock()
+	return true
+}
+
+// succeeded reconciles the engines if the primary engine has just recovered and reports it
+func (fe *FailoverEngine) succeeded() bool {
+	if !fe.Failing() {
+		return false
+	}
+	if err := fe.Reconcile(); err != nil {
+		fe.logger.Error("failover: reconciliation failed: ", err)
+		return false
+	}
+	return true
+}
+
+// forget drops a key written to the primary engine after its recovery from the changes to be replayed
+func (fe *FailoverEngine) forget(key string) {
+	fe.mutex.Lock()
+	_, found := fe.dirty[key]
+	delete(fe.dirty, key)
+	fe.mutex.Unlock()
+	if found {
+		fe.secondary.Delete(key)
+	}
+}
+
+// lockReplay waits for the replay of the key if Reconcile has not replayed it yet and returns the function
+// to be called once the key was written to the primary engine; the replay of the key is then skipped
+func (fe *FailoverEngine) lockReplay(key string) func() {
+	fe.mutex.Lock()
+	mutex := fe.replaying[key]
+	fe.mutex.Unlock()
+	if mutex == nil {
+		return func() {}
+	}
+	mutex.Lock()
+	return func() {
+		fe.mutex.Lock()
+		if fe.replaying[key] == mutex {
+			delete(fe.replaying, key)
+		}
+		fe.mutex.Unlock()
+		mutex.Unlock()
+	}
+}
+
+// markDirty remembers a key modified in the secondary engine
+func (fe *FailoverEngine) markDirty(key string, set bool) {
+	fe.mutex.Lock()
+	defer fe.mutex.Unlock()
+	fe.dirty[key] = set
+}
+
+// Reconcile replays the changes made in the secondary engine into the primary engine.
+// It is called automatically by the first successful primary operation after a failure.
+func (fe *FailoverEngine) Reconcile() error {
+	fe.mutex.Lock()
+	if !fe.failing || fe.replaying != nil {
+		fe.mutex.Unlock()
+		return nil
+	}
+	dirty, purged := fe.dirty, fe.purged
+	fe.dirty = make(map[string]bool)
+	fe.purged = false
+	fe.failing = false
+	fe.replaying = make(map[string]*sync.Mutex, len(dirty))
+	for key := range dirty {
+		fe.replaying[key] = &sync.Mutex{}
+	}
+	fe.mutex.Unlock()
+
+	restore := func(err error) error {
+		fe.mutex.Lock()
+		defer fe.mutex.Unlock()
+		fe.failing = true
+		fe.purged = fe.purged || purged
+		for key, set := range dirty {
+			// keys written to the primary engine meanwhile are not replayed anymore
+			if _, found := fe.replaying[key]; !found {
+				continue
+			}
+			if _, found := fe.dirty[key]; !found {
+				fe.dirty[key] = set
+			}
+		}
+		fe.replaying = nil
+		return err
+	}
+
+	if purged {
+		if err := fe.primary.Purge(); err != nil {
+			return restore(err)
+		}
+		purged = false
+	}
+
+	for key, set := range dirty {
+		if err := fe.replay(key, set); err != nil {
+			return restore(err)
+		}
+		delete(dirty, key)
+	}
+
+	fe.mutex.Lock()
+	fe.replaying = nil
+	fe.mutex.Unlock()
+	fe.logger.Print("failover: primary engine recovered")
+	return nil
+}
+
+// replay copies the key from the secondary engine into the primary one holding its replay lock unless the key
+// was written to the primary engine since Reconcile started. An error is returned only if the primary engine
+// fails again.
+func (fe *FailoverEngine) replay(key string, set bool) error {
+	fe.mutex.Lock()
+	mutex := fe.replaying[key]
+	fe.mutex.Unlock()
+	if mutex != nil {
+		mutex.Lock()
+		defer mutex.Unlock()
+	}
+	fe.mutex.Lock()
+	current := mutex != nil && fe.replaying[key] == mutex
+	_, dirty := fe.dirty[key]
+	fe.mutex.Unlock()
+	if !current {
+		if !dirty {
+			// the stale value must not be served by the next failover
+			fe.secondary.Delete(key)
+		}
+		return nil
+	}
+
+	var err error
+	if set {
+		err = fe.replaySet(key)
+	} else {
+		err = fe.primary.Delete(key)
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		if isEngineFailure(err) {
+			return err
+		}
+		fe.logger.Error("failover: cannot reconcile key ", key, ": ", err)
+	}
+	fe.secondary.Delete(key)
+
+	fe.mutex.Lock()
+	delete(fe.replaying, key)
+	fe.mutex.Unlock()
+	return nil
+}
+
+// replaySet copies the value of the key with its remaining TTL from the secondary engine into the primary one
+func (fe *FailoverEngine) replaySet(key string) error {
+	value, err := fe.secondary.Peek(key)
+	if errors.Is(err, ErrNotFound) {
+		return fe.primary.Delete(key)
+	} else if err != nil {
+		return err
+	}
+	ttl, err := engineTTL(fe.secondary, key)
+	if errors.Is(err, ErrNotFound) {
+		// expired meanwhile
+		return fe.primary.Delete(key)
+	}
+	if err == nil && ttl > 0 {
+		if err = setWithTTL(fe.primary, key, value, ttl); !errors.Is(err, ErrTTLNotSupported) {
+			return err
+		}
+	}
+	return fe.primary.Set(key, value)
+}
+
+// Get gets a value from the primary engine or from the secondary one if the primary fails
+func (fe *FailoverEngine) Get(key string) (interface{}, error) {
+	value, err := fe.primary.Get(key)
+	if fe.failed(err) {
+		return fe.secondary.Get(key)
+	}
+	if fe.succeeded() {
+		return fe.primary.Get(key)
+	}
+	return value, err
+}
+
+// Peek gets a value from the primary engine or from the secondary one if the primary fails
+func (fe *FailoverEngine) Peek(key string) (interface{}, error) {
+	value, err := fe.primary.Peek(key)
+	if fe.failed(err) {
+		return fe.secondary.Peek(key)
+	}
+	if fe.succeeded() {
+		return fe.primary.Peek(key)
+	}
+	return value, err
+}
+
+// Set stores a value into the primary engine or into the secondary one if the primary fails
+func (fe *FailoverEngine) Set(key string, value interface{}) error {
+	return fe.set(key, func(engine CacheEngine) error {
+		return engine.Set(key, value)
+	})
+}
+
+// SetWithTTL stores a value which expires after ttl like Set; the TTL is kept when the value is replayed
+// into the primary engine by Reconcile if both engines implement CacheEngineTTL
+func (fe *FailoverEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	return fe.set(key, func(engine CacheEngine) error {
+		return setWithTTL(engine, key, value, ttl)
+	})
+}
+
+// set stores a value into the primary engine using store or into the secondary one if the primary fails
+func (fe *FailoverEngine) set(key string, store func(engine CacheEngine) error) error {
+	replayed := fe.lockReplay(key)
+	err := store(fe.primary)
+	replayed()
+	if fe.failed(err) {
+		fe.markDirty(key, true)
+		return store(fe.secondary)
+	}
+	if err == nil {
+		fe.forget(key)
+	}
+	fe.succeeded()
+	return err
+}
+
+// TTL returns the remaining time to live of the key in the primary engine or in the secondary one if the primary fails
+func (fe *FailoverEngine) TTL(key string) (time.Duration, error) {
+	ttl, err := engineTTL(fe.primary, key)
+	if fe.failed(err) {
+		return engineTTL(fe.secondary, key)
+	}
+	if fe.succeeded() {
+		return engineTTL(fe.primary, key)
+	}
+	return ttl, err
+}
+
+// Age returns how long ago the key was stored in the primary engine or in the secondary one if the primary fails
+func (fe *FailoverEngine) Age(key string) (time.Duration, error) {
+	age, err := engineAge(fe.primary, key)
+	if fe.failed(err) {
+		return engineAge(fe.secondary, key)
+	}
+	if fe.succeeded() {
+		return engineAge(fe.primary, key)
+	}
+	return age, err
+}
+
+// Delete removes a value from the primary engine or from the secondary one if the primary fails
+func (fe *FailoverEngine) Delete(key string) error {
+	replayed := fe.lockReplay(key)
+	err := fe.primary.Delete(key)
+	replayed()
+	if fe.failed(err) {
+		fe.markDirty(key, false)
+		return fe.secondary.Delete(key)
+	}
+	if err == nil {
+		fe.forget(key)
+	}
+	fe.succeeded()
+	return err
+}
+
+// Keys lists the keys of the primary engine or of the secondary one if the primary fails
+func (fe *FailoverEngine) Keys() ([]string, error) {
+	keys, err := fe.primary.Keys()
+	if fe.failed(err) {
+		return fe.secondary.Keys()
+	}
+	if fe.succeeded() {
+		return fe.primary.Keys()
+	}
+	return keys, err
+}
+
+// Unwrap returns the primary and the secondary engine
+func (fe *FailoverEngine) Unwrap() []CacheEngine {
+	return []CacheEngine{fe.primary, fe.secondary}
+}
+
+// Purge removes all values from the primary engine or from the secondary one if the primary fails
+func (fe *FailoverEngine) Purge() error {
+	err := fe.primary.Purge()
+	if fe.failed(err) {
+		fe.mutex.Lock()
+		fe.purged = true
+		fe.dirty = make(map[string]bool)
+		fe.mutex.Unlock()
+		return fe.secondary.Purge()
+	}
+	if err == nil && fe.Failing() {
+		// everything written during the outage is gone now
+		fe.mutex.Lock()
+		fe.purged = false
+		fe.dirty = make(map[string]bool)
+		fe.mutex.Unlock()
+		fe.secondary.Purge()
+	}
+	fe.succeeded()
+	return err
+}
@@ -0,0 +1,127 @@
+package cachier_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverEngine(t *testing.T) {
+	primary := cachiertest.NewEngine()
+	secondary := cachiertest.NewEngine()
+	fe := cachier.NewFailoverEngine(primary, secondary)
+	cache := cachier.MakeCache[string](fe)
+
+	a, b := "a", "b"
+	require.Nil(t, cache.Set("a", &a))
+	require.Nil(t, cache.Set("b", &b))
+	assert.Equal(t, 0, secondary.Len())
+
+	for _, op := range []cachiertest.Operation{cachiertest.OpGet, cachiertest.OpSet, cachiertest.OpDelete} {
+		primary.InjectFault(op, cachiertest.Fault{})
+	}
+
+	_, err := cache.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	assert.True(t, fe.Failing())
+
+	c := "c"
+	require.Nil(t, cache.Set("c", &c))
+	require.Nil(t, cache.Delete("b"))
+	value, err := cache.Get("c")
+	require.Nil(t, err)
+	assert.Equal(t, c, *value)
+
+	primary.ClearFaults()
+	value, err = cache.Get("c")
+	require.Nil(t, err)
+	assert.Equal(t, c, *value)
+	assert.False(t, fe.Failing())
+	assert.Equal(t, 0, secondary.Len())
+
+	_, err = cache.Get("b")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	value, err = cache.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, a, *value)
+}
+
+// ttlEngine is a cachiertest.Engine which records the TTLs instead of expiring the keys
+type ttlEngine struct {
+	*cachiertest.Engine
+	mutex sync.Mutex
+	ttls  map[string]time.Duration
+}
+
+func newTTLEngine() *ttlEngine {
+	return &ttlEngine{Engine: cachiertest.NewEngine(), ttls: make(map[string]time.Duration)}
+}
+
+func (e *ttlEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if err := e.Set(key, value); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.ttls[key] = ttl
+	return nil
+}
+
+func (e *ttlEngine) TTL(key string) (time.Duration, error) {
+	if _, err := e.Peek(key); err != nil {
+		return 0, err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.ttls[key], nil
+}
+
+func TestFailoverEngineReconcileKeepsTTL(t *testing.T) {
+	primary, secondary := newTTLEngine(), newTTLEngine()
+	fe := cachier.NewFailoverEngine(primary, secondary)
+
+	primary.InjectFault(cachiertest.OpSet, cachiertest.Fault{})
+	require.Nil(t, fe.SetWithTTL("a", "a", time.Minute))
+	require.Nil(t, fe.Set("b", "b"))
+	assert.True(t, fe.Failing())
+
+	primary.ClearFaults()
+	require.Nil(t, fe.Reconcile())
+	ttl, err := primary.TTL("a")
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, ttl)
+	ttl, err = primary.TTL("b")
+	require.Nil(t, err)
+	assert.Zero(t, ttl)
+	assert.Equal(t, 0, secondary.Len())
+}
+
+func TestFailoverEngineReconcileKeepsNewerWrites(t *testing.T) {
+	primary, secondary := cachiertest.NewEngine(), cachiertest.NewEngine()
+	fe := cachier.NewFailoverEngine(primary, secondary)
+
+	primary.InjectFault(cachiertest.OpSet, cachiertest.Fault{})
+	require.Nil(t, fe.Set("a", "stale"))
+	primary.ClearFaults()
+
+	// the replay reads the stale value slowly while the key is written again
+	secondary.InjectFault(cachiertest.OpPeek, cachiertest.Fault{Latency: 100 * time.Millisecond, LatencyOnly: true})
+	reconciled := make(chan error)
+	go func() {
+		reconciled <- fe.Reconcile()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.Nil(t, fe.Set("a", "fresh"))
+	require.Nil(t, <-reconciled)
+
+	value, err := primary.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, "fresh", value)
+	assert.Equal(t, 0, secondary.Len())
+	assert.False(t, fe.Failing())
+}
@@ -0,0 +1,34 @@
+package cachier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEach(t *testing.T) {
+	lc, err := NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	c := MakeCache[int](lc)
+
+	one, two := 1, 2
+	require.Nil(t, c.Set("one", &one))
+	require.Nil(t, c.Set("two", &two))
+	require.Nil(t, lc.Set("broken", "not an int"))
+
+	values := make(map[string]int)
+	failed := make([]string, 0)
+	err = c.ForEach(func(key string, value *int) bool {
+		values[key] = *value
+		return true
+	}, ForEachOptions{
+		OnError: func(key string, err error) {
+			assert.ErrorIs(t, err, ErrWrongDataType)
+			failed = append(failed, key)
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]int{"one": 1, "two": 2}, values)
+	assert.Equal(t, []string{"broken"}, failed)
+}
@@ -0,0 +1,298 @@
+package cachier
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrFrozen is returned by the writes which cannot be held while the cache is frozen
+// (conditional writes, SetRaw, bulk deletes, purges and imports)
+var ErrFrozen = errors.New("cache is frozen")
+
+// heldWrite is a Set, SetWithTTL or Delete (deleted is set) held while the writes are paused
+type heldWrite[T any] struct {
+	key     string
+	value   *T
+	ttl     time.Duration
+	deleted bool
+	seq     uint64
+	// size is the size of the value counted by the pending writes budget
+	size int64
+}
+
+// notFound returns ErrNotFound for held deletes
+func (w heldWrite[T]) notFound() error {
+	if w.deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// heldWrites keeps the latest held write of every key while the writes are paused until they are resumed
+type heldWrites[T any] struct {
+	// paused makes Set, SetWithTTL, SetMulti and Delete held
+	paused atomic.Bool
+	// frozen makes the writes which cannot be held fail with ErrFrozen
+	frozen atomic.Bool
+	// active is set while the writes are paused or held writes are left to flush
+	active atomic.Bool
+	mutex  sync.Mutex
+	writes map[string]heldWrite[T]
+	seq    uint64
+	// bytes is the size of the held values
+	bytes atomic.Int64
+}
+
+func newHeldWrites[T any]() *heldWrites[T] {
+	return &heldWrites[T]{
+		writes: make(map[string]heldWrite[T]),
+	}
+}
+
+// pause starts holding the writes; frozen also rejects the writes which cannot be held
+func (h *heldWrites[T]) pause(frozen bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.active.Store(true)
+	h.paused.Store(true)
+	if frozen {
+		h.frozen.Store(true)
+	}
+}
+
+// hold keeps the writes if the writes are paused and reports whether they are.
+// Otherwise the writes go to the engine directly and supersede the held writes of their keys (see direct).
+func (h *heldWrites[T]) hold(writes ...heldWrite[T]) bool {
+	if !h.active.Load() {
+		return false
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.paused.Load() {
+		for _, write := range writes {
+			h.forget(write.key)
+		}
+		return false
+	}
+	for _, write := range writes {
+		h.seq++
+		write.seq = h.seq
+		h.bytes.Add(write.size - h.writes[write.key].size)
+		h.writes[write.key] = write
+	}
+	return true
+}
+
+// direct reports whether the cache is frozen for the writes of the keys which cannot be held.
+// If the writes are not paused anymore, the held writes of the keys which were not flushed yet are dropped,
+// so ResumeWrites does not overwrite the direct writes with them.
+func (h *heldWrites[T]) direct(keys ...string) bool {
+	if !h.active.Load() {
+		return false
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.frozen.Load() {
+		return true
+	}
+	if !h.paused.Load() {
+		for _, key := range keys {
+			h.forget(key)
+		}
+	}
+	return false
+}
+
+// forget drops the held write of the key; the mutex must be held
+func (h *heldWrites[T]) forget(key string) {
+	h.bytes.Add(-h.writes[key].size)
+	delete(h.writes, key)
+	if len(h.writes) == 0 && !h.paused.Load() {
+		h.active.Store(false)
+	}
+}
+
+// count returns the number of the held writes
+func (h *heldWrites[T]) count() int {
+	if !h.active.Load() {
+		return 0
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.writes)
+}
+
+// lookup returns the held write of the key
+func (h *heldWrites[T]) lookup(key string) (heldWrite[T], bool) {
+	if !h.active.Load() {
+		return heldWrite[T]{}, false
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	write, found := h.writes[key]
+	return write, found
+}
+
+// resume stops holding the writes and returns the held ones (see pending).
+// They are kept (and returned by lookup) until they are flushed or superseded by direct writes.
+func (h *heldWrites[T]) resume() []heldWrite[T] {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.paused.Store(false)
+	h.frozen.Store(false)
+	if len(h.writes) == 0 {
+		h.active.Store(false)
+	}
+	return h.pending()
+}
+
+// pending returns the held writes in the order they were made; the mutex must be held
+func (h *heldWrites[T]) pending() []heldWrite[T] {
+	writes := make([]heldWrite[T], 0, len(h.writes))
+	for _, write := range h.writes {
+		writes = append(writes, write)
+	}
+	sort.Slice(writes, func(i, j int) bool { return writes[i].seq < writes[j].seq })
+	return writes
+}
+
+// current reports whether the write is still held, i.e. it was not superseded by a direct write
+func (h *heldWrites[T]) current(write heldWrite[T]) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	held, found := h.writes[write.key]
+	return found && held.seq == write.seq
+}
+
+// release forgets the flushed write unless the key was written again meanwhile
+func (h *heldWrites[T]) release(write heldWrite[T]) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.writes[write.key].seq == write.seq {
+		h.forget(write.key)
+	}
+}
+
+// hold keeps the writes while the writes are paused (see heldWrites.hold) unless the values would exceed
+// the pending writes budget
+func (c *Cache[T]) hold(writes ...heldWrite[T]) (bool, error) {
+	if c.options.pendingBudget > 0 && c.WritesPaused() {
+		var size int64
+		for i := range writes {
+			writes[i].size = c.pendingSize(writes[i].value)
+			// the held write of the key is replaced
+			previous, _ := c.held.lookup(writes[i].key)
+			size += writes[i].size - previous.size
+		}
+		if size > 0 && c.overBudget(size) {
+			return false, ErrPendingWritesBudget
+		}
+	}
+	return c.held.hold(writes...), nil
+}
+
+// PauseWrites stops sending Set, SetWithTTL, SetMulti and Delete (also of the values computed by GetOrCompute)
+// to the engine, e.g. during an engine failover: the writes are held in memory and reads return the held values
+// before asking the engine. Only the latest write of each key is kept. The writes which cannot be held
+// (conditional writes, SetRaw, bulk deletes, purges and imports) still go to the engine after the held writes
+// they could conflict with are flushed. Keys, Count and the other listings see only the engine.
+func (c *Cache[T]) PauseWrites() {
+	c.held.pause(false)
+}
+
+// ResumeWrites sends the writes held since PauseWrites (or Freeze) to the engine in the order they were made
+// (TTLs start now). New writes go to the engine right away; a held write of a key written meanwhile is dropped.
+// Writes failing in the engine are dropped and their errors are returned joined.
+func (c *Cache[T]) ResumeWrites() error {
+	return c.flushHeldWrites(c.held.resume())
+}
+
+// WritesPaused reports whether the writes are held, i.e. after PauseWrites or Freeze until ResumeWrites or Unfreeze
+func (c *Cache[T]) WritesPaused() bool {
+	return c.held.paused.Load()
+}
+
+// flushHeldWrites sends the held writes to the engine and returns their errors joined
+func (c *Cache[T]) flushHeldWrites(writes []heldWrite[T]) error {
+	var errs []error
+	for _, write := range writes {
+		if err := c.flushHeld(write); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushHeld sends the held write to the engine unless the key was written directly since it was taken.
+// The key lock is held from the check until the write is done, so direct writes (which supersede the held write
+// before taking the lock) cannot be overwritten by it.
+func (c *Cache[T]) flushHeld(write heldWrite[T]) error {
+	lock := c.lockKey(write.key)
+	var err error
+	if c.held.current(write) {
+		if write.deleted {
+			err = c.deleteUnlocked(write.key)
+		} else if write.ttl > 0 {
+			err = c.setWithTTLUnlocked(write.key, write.value, write.ttl)
+		} else {
+			err = c.setUnlocked(write.key, write.value)
+		}
+		c.held.release(write)
+	}
+	c.unlock(lock)
+	if err == nil && write.deleted {
+		c.publishInvalidation(OpDelete, write.key)
+	}
+	return err
+}
+
+// writeDirectly returns ErrFrozen if the cache is frozen, otherwise the held writes of the keys are flushed first,
+// so the writes which cannot be held (conditional writes, SetRaw, DeleteKeys) see and override them
+func (c *Cache[T]) writeDirectly(keys ...string) error {
+	if c.held.direct(keys...) {
+		return ErrFrozen
+	}
+	for _, key := range keys {
+		if write, found := c.held.lookup(key); found {
+			if err := c.flushHeld(write); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeAllDirectly is writeDirectly for the writes of any key (bulk deletes, purges, imports):
+// all the held writes are flushed first
+func (c *Cache[T]) writeAllDirectly() error {
+	if c.held.direct() {
+		return ErrFrozen
+	}
+	if !c.held.active.Load() {
+		return nil
+	}
+	c.held.mutex.Lock()
+	writes := c.held.pending()
+	c.held.mutex.Unlock()
+	return c.flushHeldWrites(writes)
+}
+
+// Freeze puts the cache into maintenance mode, e.g. while the engine is migrated or restarted: the writes are
+// paused (see PauseWrites) and the writes which cannot be held fail with ErrFrozen, so the engine is not used
+// for writes at all.
+func (c *Cache[T]) Freeze() {
+	c.held.pause(true)
+}
+
+// Unfreeze ends the maintenance mode and resumes the writes (see ResumeWrites)
+func (c *Cache[T]) Unfreeze() error {
+	return c.ResumeWrites()
+}
+
+// Frozen reports whether the cache is frozen, i.e. after Freeze until Unfreeze
+func (c *Cache[T]) Frozen() bool {
+	return c.held.frozen.Load()
+}
@@ -0,0 +1,176 @@
+package cachier_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	engine := cachier.NewMemoryCache(0, 0)
+	cache := cachier.MakeCache[int](engine)
+	// engineView reads the engine directly
+	engineView := cachier.MakeCache[int](engine)
+
+	one, two := 1, 2
+	require.Nil(t, cache.Set("a", &one))
+	require.Nil(t, cache.Set("b", &one))
+	cache.Freeze()
+	assert.True(t, cache.Frozen())
+
+	require.Nil(t, cache.Set("a", &two))
+	require.Nil(t, cache.Delete("b"))
+	require.Nil(t, cache.SetMulti(map[string]*int{"c": &one}))
+	computed, err := cache.GetOrCompute("d", func() (*int, error) { return &two, nil })
+	require.Nil(t, err)
+	assert.Equal(t, 2, *computed)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+
+	// the reads see the held writes, the engine does not
+	got, err := cache.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 2, *got)
+	_, err = cache.Peek("b")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	values, err := cache.GetMulti([]string{"a", "b", "c", "d"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]*int{"a": &two, "c": &one, "d": &two}, values)
+	keys, err := engineView.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+	got, err = engineView.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 1, *got)
+
+	_, err = cache.SetIfAbsent("e", &one)
+	assert.ErrorIs(t, err, cachier.ErrFrozen)
+	assert.ErrorIs(t, cache.Purge(), cachier.ErrFrozen)
+	err = cache.DeleteKeys([]string{"a"})
+	assert.ErrorIs(t, err, cachier.ErrFrozen)
+	var keyErr *cachier.KeyError
+	require.ErrorAs(t, err, &keyErr)
+	assert.Equal(t, "a", keyErr.Key)
+
+	require.Nil(t, cache.Unfreeze())
+	assert.False(t, cache.Frozen())
+	keys, err = engineView.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "c", "d"}, keys)
+	got, err = engineView.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 2, *got)
+
+	// writes go to the engine again
+	require.Nil(t, cache.Delete("c"))
+	_, err = engineView.Get("c")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	require.Nil(t, cache.Unfreeze())
+}
+
+func TestUnfreezeWhileWriting(t *testing.T) {
+	engine := cachier.NewMemoryCache(0, 0)
+	cache := cachier.MakeCache[int](engine)
+	engineView := cachier.MakeCache[int](engine)
+
+	cache.Freeze()
+	for i := 0; i < 1000; i++ {
+		value := i
+		require.Nil(t, cache.Set(fmt.Sprint("key", i), &value))
+	}
+
+	unfrozen := make(chan struct{})
+	written := make(chan int)
+	go func() {
+		last, afterUnfreeze := 0, 0
+		for afterUnfreeze < 100 {
+			select {
+			case <-unfrozen:
+				afterUnfreeze++
+			default:
+			}
+			last++
+			value := last
+			if err := cache.Set("key0", &value); err != nil {
+				t.Error(err)
+			}
+		}
+		written <- last
+	}()
+
+	require.Nil(t, cache.Unfreeze())
+	close(unfrozen)
+	last := <-written
+
+	got, err := engineView.Get("key0")
+	require.Nil(t, err)
+	assert.Equal(t, last, *got)
+	got, err = cache.Get("key0")
+	require.Nil(t, err)
+	assert.Equal(t, last, *got)
+	got, err = engineView.Get("key999")
+	require.Nil(t, err)
+	assert.Equal(t, 999, *got)
+}
+
+func TestFreezeDeleteKeysReportsUserKey(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0), cachier.WithKeyHasher(func(key string) string {
+		return "hashed:" + key
+	}))
+
+	cache.Freeze()
+	err := cache.DeleteKeys([]string{"b", "a"})
+	assert.ErrorIs(t, err, cachier.ErrFrozen)
+	var keyErr *cachier.KeyError
+	require.ErrorAs(t, err, &keyErr)
+	assert.Equal(t, "a", keyErr.Key)
+	require.Nil(t, cache.Unfreeze())
+}
+
+func TestPauseWrites(t *testing.T) {
+	engine := cachier.NewMemoryCache(0, 0)
+	cache := cachier.MakeCache[int](engine)
+	engineView := cachier.MakeCache[int](engine)
+
+	one, two := 1, 2
+	cache.PauseWrites()
+	assert.True(t, cache.WritesPaused())
+	assert.False(t, cache.Frozen())
+	require.Nil(t, cache.Set("a", &one))
+	require.Nil(t, cache.Set("b", &one))
+	_, err := engineView.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	got, err := cache.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 1, *got)
+
+	// the conditional write sees the held value, which is flushed first
+	stored, err := cache.SetIfAbsent("a", &two)
+	require.Nil(t, err)
+	assert.False(t, stored)
+	got, err = engineView.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 1, *got)
+	_, err = engineView.Get("b")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	require.Nil(t, cache.Set("b", &two))
+	require.Nil(t, cache.ResumeWrites())
+	assert.False(t, cache.WritesPaused())
+	got, err = engineView.Get("b")
+	require.Nil(t, err)
+	assert.Equal(t, 2, *got)
+
+	// ResumeWrites also ends the maintenance mode
+	cache.Freeze()
+	assert.True(t, cache.WritesPaused())
+	require.Nil(t, cache.Delete("b"))
+	require.Nil(t, cache.ResumeWrites())
+	assert.False(t, cache.Frozen())
+	_, err = engineView.Get("b")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
@@ -0,0 +1,160 @@
+package cachier
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrGenerationsDisabled is returned by BumpGeneration of a cache created without WithGenerations
+	ErrGenerationsDisabled = errors.New("generations are not enabled")
+	// ErrGenerationPrefix is returned by the prefix operations of a cache with WithGenerations when the prefix
+	// does not extend beyond its scope, so the scopes of the matching keys (and their generations) are not known
+	ErrGenerationPrefix = errors.New("prefix must extend beyond its scope")
+)
+
+// GenerationStore keeps the generation numbers of scopes
+type GenerationStore interface {
+	// Generation returns the current generation of the scope (0 if it was never bumped)
+	Generation(scope string) (int64, error)
+	// Bump increments the generation of the scope and returns the new one
+	Bump(scope string) (int64, error)
+}
+
+// generationOptions holds the configuration of WithGenerations
+type generationOptions struct {
+	store   GenerationStore
+	scopeOf func(key string) string
+}
+
+// WithGenerations embeds the generation number of the key's scope into every stored key,
+// so Cache.BumpGeneration(scope) invalidates all the keys of the scope at once.
+// scopeOf returns the scope of the key, which must be a prefix of the key (e.g. "tenant:42"); keys with
+// an empty scope are stored unchanged. If scopeOf is nil the part of the key before the first ':' is used.
+// Entries of old generations are not deleted, they are left to expire or to be evicted by the engine.
+// The key "tenant:42:user:1" of the scope "tenant:42" in generation 3 is stored as "tenant:42#3:user:1".
+// Keys and the other listings return the stored keys. KeysWithPrefix, DeleteWithPrefix and PurgePrefix embed
+// the generation into the prefix, so it must extend beyond its scope (e.g. "tenant:42:user:" or "tenant:42:"),
+// other prefixes except "" are refused with ErrGenerationPrefix.
+func WithGenerations(store GenerationStore, scopeOf func(key string) string) Option {
+	if scopeOf == nil {
+		scopeOf = func(key string) string {
+			scope, _, _ := strings.Cut(key, ":")
+			return scope
+		}
+	}
+	return func(o *options) {
+		o.generations = &generationOptions{
+			store:   store,
+			scopeOf: scopeOf,
+		}
+	}
+}
+
+// embed returns the key with the generation of its scope embedded
+func (g *generationOptions) embed(key string) (string, error) {
+	scope := g.scopeOf(key)
+	if scope == "" || !strings.HasPrefix(key, scope) {
+		return key, nil
+	}
+	generation, err := g.store.Generation(scope)
+	if err != nil {
+		return "", err
+	}
+	return scope + "#" + strconv.FormatInt(generation, 10) + key[len(scope):], nil
+}
+
+// embedPrefix returns the prefix of the stored keys of the keys starting with prefix
+func (g *generationOptions) embedPrefix(prefix string) (string, error) {
+	scope := g.scopeOf(prefix)
+	if scope == "" {
+		return prefix, nil
+	}
+	if len(prefix) <= len(scope) || !strings.HasPrefix(prefix, scope) {
+		return "", ErrGenerationPrefix
+	}
+	return g.embed(prefix)
+}
+
+// enginePrefix returns the prefix of the keys stored in the engine (see WithGenerations)
+func (c *Cache[T]) enginePrefix(prefix string) (string, error) {
+	if c.options.generations == nil {
+		return prefix, nil
+	}
+	return c.options.generations.embedPrefix(prefix)
+}
+
+// BumpGeneration invalidates all the keys of the scope by incrementing its generation
+func (c *Cache[T]) BumpGeneration(scope string) error {
+	if c.options.generations == nil {
+		return ErrGenerationsDisabled
+	}
+	_, err := c.options.generations.store.Bump(scope)
+	return err
+}
+
+// MemoryGenerationStore is a GenerationStore for a single process
+type MemoryGenerationStore struct {
+	generations map[string]int64
+	mutex       sync.RWMutex
+}
+
+// NewMemoryGenerationStore creates a MemoryGenerationStore
+func NewMemoryGenerationStore() *MemoryGenerationStore {
+	return &MemoryGenerationStore{
+		generations: make(map[string]int64),
+	}
+}
+
+// Generation returns the current generation of the scope
+func (s *MemoryGenerationStore) Generation(scope string) (int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.generations[scope], nil
+}
+
+// Bump increments the generation of the scope
+func (s *MemoryGenerationStore) Bump(scope string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.generations[scope]++
+	return s.generations[scope], nil
+}
+
+// RedisGenerationStore is a GenerationStore shared by processes using redis INCR
+type RedisGenerationStore struct {
+	redisClient *redis.Client
+	keyPrefix   string
+}
+
+// NewRedisGenerationStore creates a RedisGenerationStore storing the generations under keyPrefix
+func NewRedisGenerationStore(redisClient *redis.Client, keyPrefix string) *RedisGenerationStore {
+	return &RedisGenerationStore{
+		redisClient: redisClient,
+		keyPrefix:   keyPrefix,
+	}
+}
+
+// Generation returns the current generation of the scope
+func (s *RedisGenerationStore) Generation(scope string) (int64, error) {
+	generation, err := s.redisClient.Get(ctx, s.keyPrefix+scope).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, engineError(err)
+	}
+	return generation, nil
+}
+
+// Bump increments the generation of the scope
+func (s *RedisGenerationStore) Bump(scope string) (int64, error) {
+	generation, err := s.redisClient.Incr(ctx, s.keyPrefix+scope).Result()
+	if err != nil {
+		return 0, engineError(err)
+	}
+	return generation, nil
+}
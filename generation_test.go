@@ -0,0 +1,76 @@
+package cachier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpGeneration(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+	cache := MakeCache[int](mc, WithGenerations(NewMemoryGenerationStore(), nil))
+
+	value := 1
+	require.Nil(t, cache.Set("tenant1:a", &value))
+	require.Nil(t, cache.Set("tenant2:a", &value))
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"tenant1#0:a", "tenant2#0:a"}, keys)
+
+	require.Nil(t, cache.BumpGeneration("tenant1"))
+	_, err = cache.Get("tenant1:a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	output, err := cache.Get("tenant2:a")
+	require.Nil(t, err)
+	assert.Equal(t, 1, *output)
+
+	require.Nil(t, cache.Set("tenant1:a", &value))
+	_, err = cache.Get("tenant1:a")
+	assert.Nil(t, err)
+
+	assert.ErrorIs(t, MakeCache[int](mc).BumpGeneration("tenant1"), ErrGenerationsDisabled)
+}
+
+func TestGenerationPrefixes(t *testing.T) {
+	engines := map[string]CacheEngine{"memory": NewMemoryCache(0, 0)}
+	if redisClient, err := InitRedis(); err == nil {
+		// implements KeysPrefixEngine and PrefixPurger
+		codec := JSONCodec[int]{}
+		engines["redis"] = NewRedisCache(redisClient, "generations:", codec.Marshal, codec.Unmarshal, time.Minute, nil)
+	}
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			require.Nil(t, engine.Purge())
+			defer engine.Purge()
+			cache := MakeCache[int](engine, WithGenerations(NewMemoryGenerationStore(), nil))
+			value := 1
+			for _, key := range []string{"tenant1:a", "tenant1:b", "tenant2:a"} {
+				require.Nil(t, cache.Set(key, &value))
+			}
+
+			keys, err := cache.KeysWithPrefix("tenant1:")
+			require.Nil(t, err)
+			assert.ElementsMatch(t, []string{"tenant1#0:a", "tenant1#0:b"}, keys)
+			_, err = cache.KeysWithPrefix("tenant")
+			assert.ErrorIs(t, err, ErrGenerationPrefix)
+			keys, err = cache.KeysWithPrefix("")
+			require.Nil(t, err)
+			assert.Len(t, keys, 3)
+
+			deleted, err := cache.DeleteWithPrefix("tenant1:a")
+			require.Nil(t, err)
+			assert.Equal(t, []string{"tenant1#0:a"}, deleted)
+			_, err = cache.Get("tenant1:a")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.Nil(t, cache.PurgePrefix("tenant1:"))
+			_, err = cache.Get("tenant1:b")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = cache.Get("tenant2:a")
+			assert.Nil(t, err)
+			assert.ErrorIs(t, cache.PurgePrefix("tenant2"), ErrGenerationPrefix)
+		})
+	}
+}
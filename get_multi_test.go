@@ -0,0 +1,45 @@
+package cachier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGetMulti(t *testing.T, cache *Cache[string]) {
+	values := map[string]*string{}
+	for _, value := range []string{"a", "b", strings.Repeat("c", 500)} {
+		value := value
+		values[value[:1]] = &value
+	}
+	require.Nil(t, cache.SetMulti(values))
+
+	got, err := cache.GetMulti([]string{"a", "c", "missing", "a"})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]*string{"a": values["a"], "c": values["c"]}, got)
+
+	got, err = cache.GetMulti(nil)
+	require.Nil(t, err)
+	assert.Empty(t, got)
+}
+
+func TestGetMulti(t *testing.T) {
+	testGetMulti(t, InitLRUCache[string]())
+}
+
+func TestRedisGetMulti(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "multi:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil).WithChunking(100)
+	cache := MakeCache[string](rc)
+	defer cache.Purge()
+
+	testGetMulti(t, cache)
+}
@@ -0,0 +1,90 @@
+// Package gocacheengine provides a cachier.CacheEngine using patrickmn/go-cache, an in-memory map with
+// per-entry expiration and no size bound, for applications which only need TTL-based caching.
+package gocacheengine
+
+import (
+	"context"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// Engine is a cachier.CacheEngine storing the values as they are in a go-cache
+type Engine struct {
+	cache *gocache.Cache
+}
+
+// New creates an Engine using the go-cache; Set stores the values with its default expiration,
+// e.g. New(gocache.New(time.Hour, 10*time.Minute))
+func New(cache *gocache.Cache) *Engine {
+	return &Engine{cache: cache}
+}
+
+// Get gets a value by given key
+func (e *Engine) Get(key string) (interface{}, error) {
+	value, found := e.cache.Get(key)
+	if !found {
+		return nil, &cachier.KeyError{Key: key, Op: cachier.OpGet, Err: cachier.ErrNotFound}
+	}
+	return value, nil
+}
+
+// Peek gets a value by given key (identical as Get in this implementation)
+func (e *Engine) Peek(key string) (interface{}, error) {
+	return e.Get(key)
+}
+
+// Set stores given key-value pair into cache with the default expiration of the go-cache
+func (e *Engine) Set(key string, value interface{}) error {
+	e.cache.SetDefault(key, value)
+	return nil
+}
+
+// SetWithTTL stores given key-value pair which expires after ttl; ttl <= 0 means it does not expire
+func (e *Engine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = gocache.NoExpiration
+	}
+	e.cache.Set(key, value, ttl)
+	return nil
+}
+
+// TTL returns the remaining time to live of the key; 0 means the key does not expire
+func (e *Engine) TTL(key string) (time.Duration, error) {
+	_, expiration, found := e.cache.GetWithExpiration(key)
+	if !found {
+		return 0, cachier.ErrNotFound
+	}
+	if expiration.IsZero() {
+		return 0, nil
+	}
+	return time.Until(expiration), nil
+}
+
+// Delete removes a key from cache
+func (e *Engine) Delete(key string) error {
+	e.cache.Delete(key)
+	return nil
+}
+
+// Keys returns all the not expired keys in cache
+func (e *Engine) Keys() ([]string, error) {
+	items := e.cache.Items()
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Purge removes all records from the cache
+func (e *Engine) Purge() error {
+	e.cache.Flush()
+	return nil
+}
+
+// Ping always succeeds as Engine is in memory
+func (e *Engine) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+package gocacheengine
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine(t *testing.T) {
+	engine := New(gocache.New(time.Minute, 0))
+
+	require.Nil(t, engine.Set("a", 1))
+	require.Nil(t, engine.SetWithTTL("b", 2, 10*time.Millisecond))
+	require.Nil(t, engine.SetWithTTL("c", 3, 0))
+	value, err := engine.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 1, value)
+
+	ttl, err := engine.TTL("a")
+	require.Nil(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+	ttl, err = engine.TTL("c")
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = engine.Peek("b")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	keys, err := engine.Keys()
+	require.Nil(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "c"}, keys)
+
+	require.Nil(t, engine.Delete("a"))
+	_, err = engine.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	require.Nil(t, engine.Purge())
+	keys, err = engine.Keys()
+	require.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestEngineCache(t *testing.T) {
+	cache := cachier.MakeCache[string](New(gocache.New(gocache.NoExpiration, 0)))
+
+	value := "value"
+	require.Nil(t, cache.SetWithTTL("key", &value, 10*time.Millisecond))
+	got, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, "value", *got)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
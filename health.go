@@ -0,0 +1,11 @@
+package cachier
+
+import "context"
+
+// Healthy checks whether the engine is reachable; engines not implementing Pinger are considered healthy
+func (c *Cache[T]) Healthy(ctx context.Context) error {
+	if pinger, ok := c.engine.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
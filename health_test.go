@@ -0,0 +1,30 @@
+package cachier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthy(t *testing.T) {
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	assert.Nil(t, cachier.MakeCache[int](lc).Healthy(context.Background()))
+	assert.Nil(t, cachier.MakeCache[int](cachiertest.NewEngine()).Healthy(context.Background()))
+
+	unreachable := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	defer unreachable.Close()
+	rc := cachier.NewRedisCache(unreachable, "", nil, nil, 0, nil)
+	subcached := &cachier.CacheWithSubcache[int]{
+		Cache:    cachier.MakeCache[int](rc),
+		Subcache: cachier.MakeCache[int](cachier.NewMemoryCache(0, 0)),
+	}
+	err = cachier.MakeCache[int](subcached).Healthy(context.Background())
+	assert.ErrorIs(t, err, cachier.ErrEngineUnavailable)
+}
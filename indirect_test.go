@@ -0,0 +1,91 @@
+package cachier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func linkTo(value *string) string {
+	return strings.TrimPrefix(*value, "->")
+}
+
+func setLinks(t *testing.T, c *Cache[string], links map[string]string) {
+	for key, value := range links {
+		value := value
+		require.Nil(t, c.Set(key, &value))
+	}
+}
+
+func TestGetIndirect(t *testing.T) {
+	c := MakeCache[string](NewMemoryCache(0, 0))
+	setLinks(t, c, map[string]string{"a": "->b", "b": "->c", "c": "value"})
+	value, err := c.GetIndirect("a", func(value *string) string {
+		if strings.HasPrefix(*value, "->") {
+			return linkTo(value)
+		}
+		return ""
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "value", *value)
+}
+
+func TestGetIndirectCycle(t *testing.T) {
+	c := MakeCache[string](NewMemoryCache(0, 0))
+	setLinks(t, c, map[string]string{"a": "->b", "b": "->a"})
+	_, err := c.GetIndirect("a", linkTo)
+	assert.ErrorIs(t, err, ErrLinkCycle)
+}
+
+func TestGetIndirectMaxDepth(t *testing.T) {
+	c := MakeCache[string](NewMemoryCache(0, 0), WithMaxLinkDepth(3))
+	links := map[string]string{}
+	for i := 0; i < 4; i++ {
+		links[fmt.Sprint(i)] = fmt.Sprintf("->%d", i+1)
+	}
+	links["4"] = "value"
+	setLinks(t, c, links)
+
+	resolver := func(value *string) string {
+		if strings.HasPrefix(*value, "->") {
+			return linkTo(value)
+		}
+		return ""
+	}
+	value, err := c.GetIndirect("1", resolver)
+	require.Nil(t, err)
+	assert.Equal(t, "value", *value)
+
+	_, err = c.GetIndirect("0", resolver)
+	assert.ErrorIs(t, err, ErrLinkCycle)
+}
+
+func TestSetIndirect(t *testing.T) {
+	type item struct {
+		ID   string
+		Link string
+	}
+	linkResolver := func(value *item) string { return value.Link }
+	linkGenerator := func(value *item) *item { return &item{Link: "id:" + value.ID} }
+
+	mc := NewMemoryCache(0, 0)
+	c := MakeCache[item](mc)
+	require.Nil(t, c.SetIndirect("name:a", &item{ID: "1"}, linkResolver, linkGenerator))
+	value, err := c.GetIndirect("name:a", linkResolver)
+	require.Nil(t, err)
+	assert.Equal(t, "1", value.ID)
+
+	// without MultiSetter the value is written first, so a failure does not leave a dangling link
+	failing := MakeCache[item](WrapEngine(mc, func(call *Call, next func(call *Call) error) error {
+		if call.Op == OpSet && call.Key == "id:2" {
+			return ErrEngineUnavailable
+		}
+		return next(call)
+	}))
+	assert.ErrorIs(t, failing.SetIndirect("name:b", &item{ID: "2"}, linkResolver, linkGenerator), ErrEngineUnavailable)
+	_, err = c.Get("name:b")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
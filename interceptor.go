@@ -0,0 +1,138 @@
+package cachier
+
+import (
+	"errors"
+	"time"
+)
+
+// Call describes an engine operation passed through interceptors
+type Call struct {
+	// Op is one of OpGet, OpPeek, OpSet, OpDelete, OpKeys and OpPurge
+	Op string
+	// Key is the key of the operation (empty for OpKeys and OpPurge)
+	Key string
+	// Value is the value to be stored by OpSet and the value returned by OpGet and OpPeek
+	Value interface{}
+	// Keys are the keys returned by OpKeys
+	Keys []string
+}
+
+// Interceptor intercepts an engine operation. It calls next to execute the operation
+// (or the next interceptor), possibly several times (retries) or not at all (e.g. fail fast),
+// and may inspect or transform call before and after it.
+type Interceptor func(call *Call, next func(call *Call) error) error
+
+// interceptedEngine is the CacheEngine returned by WrapEngine
+type interceptedEngine struct {
+	engine       CacheEngine
+	interceptors []Interceptor
+}
+
+// WrapEngine returns engine with the interceptors applied to all its operations.
+// The first interceptor is the outermost one. Of the optional interfaces of engine, the wrapper exposes
+// CacheEngineTTL (SetWithTTL is intercepted as OpSet) and EntryAger.
+func WrapEngine(engine CacheEngine, interceptors ...Interceptor) CacheEngine {
+	return &interceptedEngine{
+		engine:       engine,
+		interceptors: interceptors,
+	}
+}
+
+// intercept runs op through the interceptors
+func intercept(interceptors []Interceptor, call *Call, op func(call *Call) error) error {
+	if len(interceptors) == 0 {
+		return op(call)
+	}
+	return interceptors[0](call, func(call *Call) error {
+		return intercept(interceptors[1:], call, op)
+	})
+}
+
+// Get gets a value by given key
+func (ie *interceptedEngine) Get(key string) (interface{}, error) {
+	call := &Call{Op: OpGet, Key: key}
+	err := intercept(ie.interceptors, call, func(call *Call) (err error) {
+		call.Value, err = ie.engine.Get(call.Key)
+		return err
+	})
+	return call.Value, err
+}
+
+// Peek gets a value by given key without updating recency
+func (ie *interceptedEngine) Peek(key string) (interface{}, error) {
+	call := &Call{Op: OpPeek, Key: key}
+	err := intercept(ie.interceptors, call, func(call *Call) (err error) {
+		call.Value, err = ie.engine.Peek(call.Key)
+		return err
+	})
+	return call.Value, err
+}
+
+// Set stores given key-value pair
+func (ie *interceptedEngine) Set(key string, value interface{}) error {
+	return intercept(ie.interceptors, &Call{Op: OpSet, Key: key, Value: value}, func(call *Call) error {
+		return ie.engine.Set(call.Key, call.Value)
+	})
+}
+
+// SetWithTTL stores given key-value pair which expires after ttl
+func (ie *interceptedEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	return intercept(ie.interceptors, &Call{Op: OpSet, Key: key, Value: value}, func(call *Call) error {
+		return setWithTTL(ie.engine, call.Key, call.Value, ttl)
+	})
+}
+
+// TTL returns the remaining time to live of the key; it is not intercepted
+func (ie *interceptedEngine) TTL(key string) (time.Duration, error) {
+	return engineTTL(ie.engine, key)
+}
+
+// Age returns how long ago the key was stored; it is not intercepted
+func (ie *interceptedEngine) Age(key string) (time.Duration, error) {
+	return engineAge(ie.engine, key)
+}
+
+// Delete removes a key
+func (ie *interceptedEngine) Delete(key string) error {
+	return intercept(ie.interceptors, &Call{Op: OpDelete, Key: key}, func(call *Call) error {
+		return ie.engine.Delete(call.Key)
+	})
+}
+
+// Keys returns all the keys
+func (ie *interceptedEngine) Keys() ([]string, error) {
+	call := &Call{Op: OpKeys}
+	err := intercept(ie.interceptors, call, func(call *Call) (err error) {
+		call.Keys, err = ie.engine.Keys()
+		return err
+	})
+	return call.Keys, err
+}
+
+// Purge removes all records
+func (ie *interceptedEngine) Purge() error {
+	return intercept(ie.interceptors, &Call{Op: OpPurge}, func(call *Call) error {
+		return ie.engine.Purge()
+	})
+}
+
+// Unwrap returns the wrapped engine
+func (ie *interceptedEngine) Unwrap() CacheEngine {
+	return ie.engine
+}
+
+// LoggingInterceptor logs every operation as a debug message and failed operations as errors.
+// Misses are logged as debug messages. Messages are prefixed with prefix (e.g. "redis").
+func LoggingInterceptor(logger Logger, prefix string) Interceptor {
+	logger = loggerOrDefault(logger)
+	return func(call *Call, next func(call *Call) error) error {
+		logDebug(logger, prefix+" "+call.Op+" ", call.Key)
+		err := next(call)
+		if errors.Is(err, ErrNotFound) {
+			logDebug(logger, prefix+": key not found: ", call.Key)
+		} else if err != nil {
+			logger.Error(prefix+": error in "+call.Op+" with key: ", call.Key, " error: ", err)
+		}
+		return err
+	}
+}
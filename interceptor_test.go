@@ -0,0 +1,62 @@
+package cachier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapEngine(t *testing.T) {
+	recorder := &recordingLogger{}
+	ops := make([]string, 0)
+	failures := 1
+	engine := WrapEngine(NewMemoryCache(0, 0),
+		LoggingInterceptor(recorder, "memory"),
+		// retries a failed operation once
+		func(call *Call, next func(call *Call) error) error {
+			ops = append(ops, call.Op)
+			err := next(call)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				err = next(call)
+			}
+			return err
+		},
+		// fails the first operation
+		func(call *Call, next func(call *Call) error) error {
+			if failures > 0 {
+				failures--
+				return errors.New("failure")
+			}
+			return next(call)
+		},
+		// stores upper-cased strings
+		func(call *Call, next func(call *Call) error) error {
+			if s, ok := call.Value.(string); ok && call.Op == OpSet {
+				call.Value = strings.ToUpper(s)
+			}
+			return next(call)
+		},
+	)
+
+	require.Nil(t, engine.Set("key", "value"))
+	value, err := engine.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, "VALUE", value)
+	_, err = engine.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	keys, err := engine.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+
+	assert.Equal(t, []string{OpSet, OpGet, OpGet, OpKeys}, ops)
+	assert.Equal(t, []string{
+		"print: memory set key",
+		"print: memory get key",
+		"print: memory get missing",
+		"print: memory: key not found: missing",
+		"print: memory keys ",
+	}, recorder.Messages())
+}
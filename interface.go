@@ -7,7 +7,7 @@
 // like GetOrCompute method (shortcut for fetching a hit or computing/writing
 // a miss).
 
-// There are also three implementations included:
+// There are also these implementations included:
 
 //  - LRUCache: a wrapper of hashicorp/golang-lru which fulfills the CacheEngine
 //    interface
@@ -18,20 +18,37 @@
 //    fast L1 subcache. E.g. primary Redis cache and fast (and small) LRU
 //    subcache. But any other implementations of CacheEngine can be used.
 
+//  - MemoryCache: dependency-free map based CacheEngine with optional TTL and
+//    janitor goroutine; handy for tests and small applications
+
 package cachier
 
 import (
+	"context"
 	"errors"
-	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
 )
 
 // Errors
 var (
-	ErrNotFound      = errors.New("key not found")
-	ErrWrongDataType = errors.New("data in wrong format")
+	ErrNotFound           = errors.New("key not found")
+	ErrWrongDataType      = errors.New("data in wrong format")
+	ErrExportNotSupported = errors.New("engine does not support export/import")
+	ErrExportFormat       = errors.New("unsupported export format")
+	ErrSerialization      = errors.New("cannot serialize data")
+	ErrEngineUnavailable  = errors.New("cache engine unavailable")
+	ErrCorrupted          = compression.ErrCorrupted
+	ErrAgeUnknown         = errors.New("entry age unknown")
+	ErrTTLNotSupported    = errors.New("engine does not support individual TTLs")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrLinkCycle          = errors.New("link cycle detected")
 )
 
 // Predicate evaluates a condition on the input string
@@ -47,26 +64,190 @@ type CacheEngine interface {
 	Purge() error
 }
 
+// KeyIterator is an optional interface of CacheEngine.
+// Engines implementing it can walk all the keys without materializing them in a slice.
+// Iteration stops when fn returns false.
+type KeyIterator interface {
+	RangeKeys(fn func(key string) bool) error
+}
+
+// KeysPredicateEngine is an optional interface of CacheEngine.
+// Engines implementing it can filter keys more efficiently than Keys followed by filtering.
+type KeysPredicateEngine interface {
+	KeysPredicate(pred Predicate) ([]string, error)
+}
+
+// KeysPrefixEngine is an optional interface of CacheEngine.
+// Engines implementing it can list keys with a given prefix efficiently (e.g. Redis SCAN MATCH).
+type KeysPrefixEngine interface {
+	KeysWithPrefix(prefix string) ([]string, error)
+}
+
+// PrefixPurger is an optional interface of CacheEngine.
+// Engines implementing it can remove all keys with a given prefix efficiently.
+type PrefixPurger interface {
+	PurgePrefix(prefix string) error
+}
+
+// KeysPager is an optional interface of CacheEngine.
+// Engines implementing it can list keys page by page without loading the whole keyspace.
+// The empty cursor starts the listing, the empty next cursor ends it.
+type KeysPager interface {
+	KeysPage(cursor string, limit int) (keys []string, nextCursor string, err error)
+}
+
+// MultiSetter is an optional interface of CacheEngine.
+// Engines implementing it store several key-value pairs atomically: all of them or none.
+type MultiSetter interface {
+	SetMulti(values map[string]interface{}) error
+}
+
+// MultiGetter is an optional interface of CacheEngine.
+// Engines implementing it get several keys at once; keys which are not found are left out of the result.
+type MultiGetter interface {
+	GetMulti(keys []string) (map[string]interface{}, error)
+}
+
+// MultiDeleter is an optional interface of CacheEngine.
+// Engines implementing it delete several keys at once, e.g. in a single round-trip.
+type MultiDeleter interface {
+	DeleteMulti(keys []string) error
+}
+
+// AtomicEngine is an optional interface of CacheEngine providing atomic operations
+// for building coordination primitives on top of the cache.
+type AtomicEngine interface {
+	// SetIfAbsent stores the value only if the key does not exist and reports whether it was stored
+	SetIfAbsent(key string, value interface{}) (bool, error)
+	// GetWithVersion returns the value together with an opaque version of the stored entry
+	GetWithVersion(key string) (value interface{}, version string, err error)
+	// CompareAndSwap stores the value only if the stored entry still has the given version
+	// and reports whether it was stored
+	CompareAndSwap(key string, version string, value interface{}) (bool, error)
+	// GetAndTouch returns the value and sets its expiration to ttl (ttl <= 0 removes the expiration)
+	GetAndTouch(key string, ttl time.Duration) (interface{}, error)
+}
+
+// CacheEngineTTL is an optional interface of CacheEngine.
+// Engines implementing it store entries with individual expiration; Cache.SetWithTTL emulates
+// the expiration for other engines.
+type CacheEngineTTL interface {
+	SetWithTTL(key string, value interface{}, ttl time.Duration) error
+	// TTL returns the remaining time to live of the key; 0 means the key does not expire
+	TTL(key string) (time.Duration, error)
+}
+
+// EvictionReporter is an optional interface of CacheEngine.
+// Engines implementing it report statistics of the entries evicted because of their size limit.
+type EvictionReporter interface {
+	EvictionStats() EvictionStats
+}
+
+// RawEngine is an optional interface of CacheEngine.
+// Engines implementing it give access to the stored payloads (marshaled and compressed values),
+// e.g. for tools migrating data between serialization formats or compression providers.
+type RawEngine interface {
+	GetRaw(key string) ([]byte, error)
+	SetRaw(key string, payload []byte) error
+}
+
+// EntryInspector is an optional interface of CacheEngine.
+// Engines implementing it describe stored entries (see Cache.GetWithInfo).
+type EntryInspector interface {
+	Inspect(key string) (EntryInfo, error)
+}
+
+// Pinger is an optional interface of CacheEngine.
+// Engines implementing it can check whether their backend is reachable, e.g. for readiness probes.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// EntryAger is an optional interface of CacheEngine.
+// Engines implementing it can report how long ago a key was stored, which is needed by WithSoftTTL.
+type EntryAger interface {
+	Age(key string) (time.Duration, error)
+}
+
+// Pinner is an optional interface of CacheEngine.
+// Engines implementing it can keep pinned keys from being evicted, see Cache.Pin.
+type Pinner interface {
+	Pin(key string) error
+	Unpin(key string) error
+	Pinned(key string) bool
+}
+
+// MemoryReporter is an optional interface of CacheEngine.
+// Engines implementing it report how much memory the stored keys use, e.g. to find oversized entries.
+type MemoryReporter interface {
+	MemoryUsage(key string) (int64, error)
+	TopKeysBySize(n int) ([]KeySize, error)
+}
+
+// ExpirationNotifier is an optional interface of CacheEngine.
+// Engines implementing it report the keys they remove by themselves, i.e. expired or evicted ones.
+type ExpirationNotifier interface {
+	// NotifyExpired calls fn for every expired or evicted key until stop is called
+	NotifyExpired(fn func(key string)) (stop func() error, err error)
+}
+
+// ForEachOptions configures Cache.ForEach
+type ForEachOptions struct {
+	// OnError is called for entries which cannot be read or decoded; such entries are skipped
+	OnError func(key string, err error)
+}
+
 // Cache is an implementation of a cache (key-value store).
 // It needs to be provided with cache engine.
 type Cache[T any] struct {
 	engine       CacheEngine
-	computeLocks sync.Map
+	computeLocks map[string]*keyLock
+	locksMutex   sync.Mutex
+	options      options
+	expiry       *ttlEmulation
+	writes       *backgroundWrites
+	watchers     *watchHub
+	bus          atomic.Pointer[joinedBus]
+	held         *heldWrites[T]
+}
+
+// keyLock is the mutex of a key; it is dropped when no one holds or waits for it
+type keyLock struct {
+	sync.Mutex
+	refs int
 }
 
 type lock struct {
 	key   string
-	mutex *sync.Mutex
+	mutex *keyLock
 }
 
 // MakeCache creates cache with provided engine
-func MakeCache[T any](engine CacheEngine) *Cache[T] {
-	return &Cache[T]{engine: engine}
+func MakeCache[T any](engine CacheEngine, opts ...Option) *Cache[T] {
+	options := makeOptions(opts)
+	options.applyLeases(engine)
+	return &Cache[T]{
+		engine:   engine,
+		options:  options,
+		expiry:   newTTLEmulation(options.clock, options.ttlSweepInterval),
+		writes:   newBackgroundWrites(),
+		watchers: newWatchHub(),
+		held:     newHeldWrites[T](),
+	}
 }
 
 func (c *Cache[T]) lockKey(key string) lock {
-	value, _ := c.computeLocks.LoadOrStore(key, &sync.Mutex{})
-	mutex := value.(*sync.Mutex)
+	c.locksMutex.Lock()
+	if c.computeLocks == nil {
+		c.computeLocks = make(map[string]*keyLock)
+	}
+	mutex, found := c.computeLocks[key]
+	if !found {
+		mutex = &keyLock{}
+		c.computeLocks[key] = mutex
+	}
+	mutex.refs++
+	c.locksMutex.Unlock()
 	mutex.Lock()
 
 	return lock{
@@ -75,99 +256,372 @@ func (c *Cache[T]) lockKey(key string) lock {
 	}
 }
 
+// engineKey returns the key stored in the engine, i.e. the key with the generation of its scope
+// embedded (WithGenerations) mapped by the key hasher (WithKeyHasher)
+func (c *Cache[T]) engineKey(key string) (string, error) {
+	if c.options.generations != nil {
+		var err error
+		if key, err = c.options.generations.embed(key); err != nil {
+			return "", err
+		}
+	}
+	if c.options.keyHasher != nil {
+		key = c.options.keyHasher(key)
+	}
+	return key, nil
+}
+
 func (c *Cache[T]) unlock(l lock) {
-	c.computeLocks.Delete(l.key)
 	l.mutex.Unlock()
+	c.locksMutex.Lock()
+	defer c.locksMutex.Unlock()
+	if l.mutex.refs--; l.mutex.refs == 0 {
+		delete(c.computeLocks, l.key)
+	}
 }
 
 // GetOrCompute tries to get value from cache.
 // If not found, it computes the value using provided evaluator function and stores it into cache.
 // In case of other errors the value is evaluated but not stored in the cache.
+// With WithSoftTTL values older than the soft TTL (and with WithEarlyExpiration values expiring soon)
+// are recomputed; if the evaluator fails, the stale value is returned instead of the error.
 func (c *Cache[T]) GetOrCompute(key string, evaluator func() (*T, error)) (*T, error) {
+	return c.GetOrComputeWithTTL(key, func() (*T, time.Duration, error) {
+		value, err := evaluator()
+		return value, 0, err
+	})
+}
+
+// GetOrComputeWithTTL works as GetOrCompute, but the evaluator also returns the TTL of the computed value
+// (e.g. derived from the caching headers of an upstream response), which is stored with SetWithTTL;
+// ttl <= 0 stores it as Set does.
+func (c *Cache[T]) GetOrComputeWithTTL(key string, evaluator func() (*T, time.Duration, error)) (*T, error) {
+	evaluator = c.validated(key, c.measured(key, evaluator))
 	value, err := c.Get(key)
 	if err == nil {
-		return value, nil
+		if !c.softExpired(key) && !c.expiresEarly(key) {
+			return value, nil
+		}
+		if lockOptions := c.options.distributedLock; lockOptions != nil && lockOptions.serveStale {
+			return c.refreshWithLease(key, value, evaluator), nil
+		}
+		computed, err := c.compute(key, evaluator)
+		if err != nil {
+			return value, nil
+		}
+		return computed, nil
 	}
 
-	calculatedValue, evaluatorErr := evaluator()
+	if errors.Is(err, ErrNotFound) && c.options.tombstones && c.tombstoned(key) {
+		return nil, tombstoneError(key)
+	}
+	if errors.Is(err, ErrNotFound) && c.options.distributedLock != nil {
+		return c.computeWithDistributedLock(key, evaluator)
+	}
 
-	if evaluatorErr == nil {
-		// Key not found on cache
-		go func() {
-			// Set key to cache in gorutine
-			c.Set(key, calculatedValue)
-		}()
-		return calculatedValue, nil
-	} else {
+	return c.compute(key, evaluator)
+}
+
+// compute evaluates the value and stores it into cache in background
+func (c *Cache[T]) compute(key string, evaluator func() (*T, time.Duration, error)) (*T, error) {
+	calculatedValue, ttl, err := evaluator()
+	if err != nil {
 		// evalutation error
-		calculatedValue = nil
-		err = evaluatorErr
+		return nil, err
 	}
-	return calculatedValue, err
+
+	// Key not found on cache
+	size := c.pendingSize(calculatedValue)
+	if c.overBudget(size) {
+		// too many values are waiting to be written, the caller waits instead
+		c.SetWithTTL(key, calculatedValue, ttl)
+		return calculatedValue, nil
+	}
+	c.writes.started(size)
+	go func() {
+		defer c.writes.done(size)
+		// Set key to cache in gorutine
+		c.SetWithTTL(key, calculatedValue, ttl)
+	}()
+	return calculatedValue, nil
+}
+
+// softExpired reports whether the value of the key is older than the soft TTL
+func (c *Cache[T]) softExpired(key string) bool {
+	if c.options.softTTL <= 0 {
+		return false
+	}
+	ager, ok := engineAs[EntryAger](c.engine)
+	if !ok {
+		return false
+	}
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return false
+	}
+	age, err := ager.Age(engineKey)
+	return err == nil && age >= c.options.softTTL
 }
 
 // Set stores a key-value pair into cache
 func (c *Cache[T]) Set(key string, value *T) error {
+	if err := c.validate(key, value); err != nil {
+		return err
+	}
+	if held, err := c.hold(heldWrite[T]{key: key, value: value}); held || err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+	return c.set(key, value)
+}
+
+// set stores the validated key-value pair into the engine
+func (c *Cache[T]) set(key string, value *T) error {
+	if c.options.slidingTTL > 0 {
+		return c.setWithTTL(key, value, c.options.slidingTTL)
+	}
+	if err := c.waitWrite(value); err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
 	lock := c.lockKey(key)
 	defer c.unlock(lock)
-	return c.engine.Set(key, value)
+	return c.setUnlocked(key, value)
+}
+
+// setUnlocked stores the validated key-value pair into the engine; the caller holds the key lock
+func (c *Cache[T]) setUnlocked(key string, value *T) error {
+	if c.options.slidingTTL > 0 {
+		return c.setWithTTLUnlocked(key, value, c.options.slidingTTL)
+	}
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+	c.expiry.forget(engineKey)
+	return wrapKeyError(OpSet, key, c.audited(OpSet, key, value, func() error {
+		return c.timed(OpSet, func() error {
+			return c.engine.Set(engineKey, value)
+		})
+	}))
 }
 
 // Get gets a cached value by key
 func (c *Cache[T]) Get(key string) (*T, error) {
+	if write, found := c.held.lookup(key); found {
+		return write.value, wrapKeyError(OpGet, key, write.notFound())
+	}
 	lock := c.lockKey(key)
 	defer c.unlock(lock)
-	value, err := c.engine.Get(key)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return nil, wrapKeyError(OpGet, key, err)
+	}
+	if c.expiry.expire(c.engine, engineKey) {
+		return nil, wrapKeyError(OpGet, key, ErrNotFound)
+	}
+	var value interface{}
+	err = c.timed(OpGet, func() (err error) {
+		value, err = c.getAndTouch(engineKey)
+		return err
+	})
 	if err == nil {
-		if reflect.ValueOf(value).Kind() == reflect.Ptr {
-			typedValue, ok := value.(*T)
-			if !ok {
-				return nil, ErrWrongDataType
-			}
-			return typedValue, nil
-		} else {
-			typedValue, ok := value.(T)
-			if !ok {
-				return nil, ErrWrongDataType
-			}
-			return &typedValue, nil
-		}
+		typedValue, err := c.toTyped(value)
+		return typedValue, wrapKeyError(OpGet, key, err)
 	}
 
-	return nil, err
+	return nil, wrapKeyError(OpGet, key, err)
 }
 
-// GetIndirect gets a key value following any intermediary links
-func (c *Cache[T]) GetIndirect(key string, linkResolver func(*T) string) (*T, error) {
-	value, err := c.Get(key)
-	if err != nil {
-		return nil, err
-	}
+// toTyped converts a value returned by the engine to *T
+func (c *Cache[T]) toTyped(value interface{}) (*T, error) {
+	return toTyped[T](value)
+}
 
-	if linkResolver != nil {
-		if link := linkResolver(value); len(link) > 0 && link != key {
-			return c.GetIndirect(link, linkResolver)
+// toTyped converts a value returned by an engine to *T; engines return either the stored pointers
+// or the decoded values
+func toTyped[T any](value interface{}) (*T, error) {
+	switch typedValue := value.(type) {
+	case *T:
+		return typedValue, nil
+	case T:
+		return &typedValue, nil
+	case []byte:
+		// payloads of RawValue values returned by engines with WithRawValues
+		var rawValue T
+		if raw, ok := any(&rawValue).(RawValue); ok {
+			raw.SetRawBytes(typedValue)
+			return &rawValue, nil
 		}
 	}
+	return nil, ErrWrongDataType
+}
 
-	return value, nil
+// GetIndirect gets a key value following any intermediary links.
+// ErrLinkCycle is returned if the links form a cycle or the chain is longer than the maximum
+// link depth (see WithMaxLinkDepth).
+func (c *Cache[T]) GetIndirect(key string, linkResolver func(*T) string) (*T, error) {
+	visited := make(map[string]bool)
+	for {
+		value, err := c.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if linkResolver == nil {
+			return value, nil
+		}
+
+		link := linkResolver(value)
+		if len(link) == 0 || link == key {
+			return value, nil
+		}
+		visited[key] = true
+		if visited[link] || len(visited) > c.options.maxLinkDepth() {
+			return nil, wrapKeyError(OpGet, link, ErrLinkCycle)
+		}
+		key = link
+	}
 }
 
-// SetIndirect sets cache key including intermediary links
+// SetIndirect sets cache key including intermediary links.
+// The link and the value are stored atomically if the engine implements MultiSetter,
+// otherwise the value is stored first, so a failure never leaves a dangling link.
 func (c *Cache[T]) SetIndirect(key string, value *T, linkResolver func(*T) string, linkGenerator func(*T) *T) error {
 	if linkGenerator != nil && linkResolver != nil {
 		if linkValue := linkGenerator(value); linkValue != nil {
 			link := linkResolver(linkValue)
+			return c.SetMulti(map[string]*T{link: value, key: linkValue}, link, key)
+		}
+	}
+
+	return c.Set(key, value)
+}
+
+// SetMulti stores several key-value pairs. If the engine implements MultiSetter they are stored
+// atomically, otherwise (or with WithSlidingExpiration, as MultiSetter stores no TTLs) one by one
+// in the given order (keys not listed in order are stored afterwards).
+func (c *Cache[T]) SetMulti(values map[string]*T, order ...string) error {
+	keys := make([]string, 0, len(values))
+	listed := make(map[string]bool, len(order))
+	for _, key := range order {
+		if _, found := values[key]; found && !listed[key] {
+			listed[key] = true
+			keys = append(keys, key)
+		}
+	}
+	rest := make([]string, 0, len(values)-len(keys))
+	for key := range values {
+		if !listed[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
 
-			if err := c.Set(key, linkValue); err != nil {
+	multiSetter, ok := c.engine.(MultiSetter)
+	if !ok || c.options.slidingTTL > 0 {
+		for _, key := range keys {
+			if err := c.Set(key, values[key]); err != nil {
 				return err
 			}
+		}
+		return nil
+	}
 
-			return c.Set(link, value)
+	held := make([]heldWrite[T], 0, len(keys))
+	for _, key := range keys {
+		if err := c.validate(key, values[key]); err != nil {
+			return err
+		}
+		held = append(held, heldWrite[T]{key: key, value: values[key]})
+	}
+	if held, err := c.hold(held...); held || err != nil {
+		return err
+	}
+	// the locks are taken in sorted order to avoid deadlocks
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	engineValues := make(map[string]interface{}, len(values))
+	for _, key := range sorted {
+		lock := c.lockKey(key)
+		defer c.unlock(lock)
+		engineKey, err := c.engineKey(key)
+		if err != nil {
+			return wrapKeyError(OpSet, key, err)
 		}
+		c.expiry.forget(engineKey)
+		engineValues[engineKey] = values[key]
 	}
+	start := c.auditStart()
+	err := c.timed(OpSet, func() error {
+		return multiSetter.SetMulti(engineValues)
+	})
+	for _, key := range keys {
+		c.audit(OpSet, key, values[key], start, err)
+	}
+	return err
+}
 
-	return c.Set(key, value)
+// GetMulti gets the values of several keys; keys which are not found are left out of the result.
+// If the engine implements MultiGetter (e.g. RedisCache) the values are fetched at once,
+// otherwise (or with WithSlidingExpiration) they are got one by one.
+func (c *Cache[T]) GetMulti(keys []string) (map[string]*T, error) {
+	multiGetter, ok := c.engine.(MultiGetter)
+	if !ok || c.options.slidingTTL > 0 {
+		values := make(map[string]*T, len(keys))
+		for _, key := range keys {
+			value, err := c.Get(key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+		return values, nil
+	}
+
+	// the locks are taken in sorted order to avoid deadlocks
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	engineKeys := make([]string, 0, len(sorted))
+	keysByEngineKey := make(map[string]string, len(sorted))
+	for i, key := range sorted {
+		if i > 0 && key == sorted[i-1] {
+			continue
+		}
+		lock := c.lockKey(key)
+		defer c.unlock(lock)
+		engineKey, err := c.engineKey(key)
+		if err != nil {
+			return nil, wrapKeyError(OpGet, key, err)
+		}
+		if c.expiry.expire(c.engine, engineKey) {
+			continue
+		}
+		engineKeys = append(engineKeys, engineKey)
+		keysByEngineKey[engineKey] = key
+	}
+
+	engineValues, err := multiGetter.GetMulti(engineKeys)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]*T, len(engineValues))
+	for engineKey, value := range engineValues {
+		key := keysByEngineKey[engineKey]
+		typedValue, err := c.toTyped(value)
+		if err != nil {
+			return nil, wrapKeyError(OpGet, key, err)
+		}
+		values[key] = typedValue
+	}
+	for _, key := range keys {
+		if write, found := c.held.lookup(key); found && write.deleted {
+			delete(values, key)
+		} else if found {
+			values[key] = write.value
+		}
+	}
+	return values, nil
 }
 
 // GetOrComputeEx tries to get value from cache.
@@ -188,7 +642,7 @@ func (c *Cache[T]) GetOrComputeEx(key string, evaluator func() (*T, error), vali
 
 	if evaluatorErr == nil {
 		// value evaluted correctly
-		if err == ErrNotFound {
+		if errors.Is(err, ErrNotFound) {
 			if writeApprover == nil || writeApprover(value) {
 				// Key not found in cache
 				c.SetIndirect(key, value, linkResolver, linkGenerator)
@@ -207,32 +661,131 @@ func (c *Cache[T]) GetOrComputeEx(key string, evaluator func() (*T, error), vali
 
 // DeletePredicate deletes all keys matching the supplied predicate, returns number of deleted keys
 func (c *Cache[T]) DeletePredicate(pred Predicate) ([]string, error) {
-	removedKeys := make([]string, 0)
-
-	keys, err := c.Keys()
+	if err := c.writeAllDirectly(); err != nil {
+		return nil, err
+	}
+	keys, err := c.KeysPredicate(pred)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, key := range keys {
-		if pred(key) {
-			if err := c.engine.Delete(key); err != nil {
+	return c.publishDeleted(c.deleteKeys(keys))
+}
+
+// deleteBatchSize is the number of keys deleted at once by engines implementing MultiDeleter
+const deleteBatchSize = 1000
+
+// deleteKeys deletes given engine keys in batches if the engine implements MultiDeleter, otherwise one by one.
+// It stops at the first error and returns the keys deleted until then. The deletes are not published
+// on the invalidation bus, the callers publish them by publishDeleted once they released the key locks.
+func (c *Cache[T]) deleteKeys(keys []string) ([]string, error) {
+	if c.Frozen() {
+		return nil, ErrFrozen
+	}
+	removedKeys, err := c.deleteEngineKeys(keys)
+	c.forgetCosts(removedKeys)
+	return removedKeys, err
+}
+
+// deleteEngineKeys deletes the engine keys like deleteKeys
+func (c *Cache[T]) deleteEngineKeys(keys []string) ([]string, error) {
+	removedKeys := make([]string, 0, len(keys))
+
+	multiDeleter, ok := c.engine.(MultiDeleter)
+	if !ok {
+		for _, key := range keys {
+			c.expiry.forget(key)
+			if err := c.audited(OpDelete, key, nil, func() error { return c.engine.Delete(key) }); err != nil {
 				return removedKeys, err
 			}
 			removedKeys = append(removedKeys, key)
 		}
+		return removedKeys, nil
+	}
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+		for _, key := range batch {
+			c.expiry.forget(key)
+		}
+		start := c.auditStart()
+		err := multiDeleter.DeleteMulti(batch)
+		for _, key := range batch {
+			c.audit(OpDelete, key, nil, start, err)
+		}
+		if err != nil {
+			return removedKeys, err
+		}
+		removedKeys = append(removedKeys, batch...)
 	}
 
 	return removedKeys, nil
 }
 
+// DeleteKeys removes the keys from the cache; missing keys are ignored. Engines implementing MultiDeleter
+// remove them in batches instead of one by one. It stops at the first error.
+func (c *Cache[T]) DeleteKeys(keys []string) error {
+	// the locks are taken in sorted order to avoid deadlocks
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, key := range sorted {
+		if i == 0 || key != sorted[i-1] {
+			unique = append(unique, key)
+		}
+	}
+
+	for start := 0; start < len(unique); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		removedKeys, err := c.deleteBatch(unique[start:end])
+		for _, key := range removedKeys {
+			c.publishInvalidation(OpDelete, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteBatch removes the sorted keys holding their locks and returns the removed keys
+func (c *Cache[T]) deleteBatch(keys []string) ([]string, error) {
+	if err := c.writeDirectly(keys...); err != nil {
+		return nil, wrapKeyError(OpDelete, keys[0], err)
+	}
+	engineKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lock := c.lockKey(key)
+		defer c.unlock(lock)
+		engineKey, err := c.engineKey(key)
+		if err != nil {
+			return nil, wrapKeyError(OpDelete, key, err)
+		}
+		engineKeys = append(engineKeys, engineKey)
+	}
+	// deleteKeys removes a prefix of the given keys
+	removedEngineKeys, err := c.deleteKeys(engineKeys)
+	return keys[:len(removedEngineKeys)], err
+}
+
 // DeleteWithPrefix removes all keys that start with given prefix, returns number of deleted keys
 func (c *Cache[T]) DeleteWithPrefix(prefix string) ([]string, error) {
-	pred := func(s string) bool {
-		return strings.HasPrefix(s, prefix)
+	if err := c.writeAllDirectly(); err != nil {
+		return nil, err
+	}
+	keys, err := c.KeysWithPrefix(prefix)
+	if err != nil {
+		return nil, err
 	}
 
-	return c.DeletePredicate(pred)
+	return c.publishDeleted(c.deleteKeys(keys))
 }
 
 // DeleteRegExp deletes all keys matching the supplied regexp, returns number of deleted keys
@@ -245,22 +798,81 @@ func (c *Cache[T]) DeleteRegExp(pattern string) ([]string, error) {
 	return c.DeletePredicate(re.MatchString)
 }
 
-// CountPredicate counts cache keys satisfying the given predicate
+// Count returns the number of keys in the cache. Engines implementing Counter count them cheaply
+// (e.g. LRUCache, MemoryCache, RedisCache), otherwise the keys are listed by Keys. With WithEarlyExpiration
+// the keys are always listed to skip the keys holding compute costs.
+func (c *Cache[T]) Count() (int, error) {
+	return c.CountPredicate(nil)
+}
+
+// CountPredicate counts cache keys satisfying the given predicate; nil predicate counts all the keys,
+// without listing them if the engine implements Counter
 func (c *Cache[T]) CountPredicate(pred Predicate) (int, error) {
-	keys, err := c.Keys()
+	if pred == nil && c.options.xfetch == nil {
+		return countKeys(c.engine)
+	}
+	if pred == nil {
+		pred = func(string) bool { return true }
+	}
+	keys, err := c.KeysPredicate(pred)
 	if err != nil {
 		return 0, err
 	}
 
-	count := 0
+	return len(keys), nil
+}
 
+// countKeys counts the keys of the engine, without listing them if it implements Counter
+func countKeys(engine CacheEngine) (int, error) {
+	if counter, ok := engine.(Counter); ok {
+		return counter.Count()
+	}
+	keys, err := engine.Keys()
+	if err != nil {
+		return 0, err
+	}
+	return len(keys), nil
+}
+
+// KeysPredicate returns cache keys satisfying the given predicate.
+// If the engine implements KeysPredicateEngine it is used instead of filtering Keys.
+func (c *Cache[T]) KeysPredicate(pred Predicate) ([]string, error) {
+	if engine, ok := c.engine.(KeysPredicateEngine); ok {
+		return engine.KeysPredicate(func(key string) bool {
+			return !isCostKey(key) && pred(key)
+		})
+	}
+
+	keys, err := c.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	matchingKeys := make([]string, 0)
 	for _, key := range keys {
 		if pred(key) {
-			count++
+			matchingKeys = append(matchingKeys, key)
 		}
 	}
 
-	return count, nil
+	return matchingKeys, nil
+}
+
+// KeysWithPrefix returns cache keys starting with the given prefix.
+// If the engine implements KeysPrefixEngine it is used instead of filtering Keys.
+func (c *Cache[T]) KeysWithPrefix(prefix string) ([]string, error) {
+	prefix, err := c.enginePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if engine, ok := c.engine.(KeysPrefixEngine); ok {
+		keys, err := engine.KeysWithPrefix(prefix)
+		return withoutCostKeys(keys), err
+	}
+
+	return c.KeysPredicate(func(s string) bool {
+		return strings.HasPrefix(s, prefix)
+	})
 }
 
 // CountRegExp counts all keys matching the supplied regexp
@@ -275,35 +887,181 @@ func (c *Cache[T]) CountRegExp(pattern string) (int, error) {
 
 // Peek gets a value by given key and does not change it's "lruness"
 func (c *Cache[T]) Peek(key string) (*T, error) {
+	if write, found := c.held.lookup(key); found {
+		return write.value, wrapKeyError(OpPeek, key, write.notFound())
+	}
 	lock := c.lockKey(key)
 	defer c.unlock(lock)
-	value, err := c.engine.Peek(key)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return nil, wrapKeyError(OpPeek, key, err)
+	}
+	if c.expiry.expire(c.engine, engineKey) {
+		return nil, wrapKeyError(OpPeek, key, ErrNotFound)
+	}
+	value, err := c.engine.Peek(engineKey)
 	if err == nil {
-		typedValue, ok := value.(T)
-		if ok {
-			return &typedValue, nil
-		} else {
-			err = ErrNotFound
-		}
+		typedValue, err := c.toTyped(value)
+		return typedValue, wrapKeyError(OpPeek, key, err)
 	}
 
-	return nil, err
+	return nil, wrapKeyError(OpPeek, key, err)
 }
 
 // Delete removes a key from cache
 func (c *Cache[T]) Delete(key string) error {
+	if held, _ := c.hold(heldWrite[T]{key: key, deleted: true}); held {
+		return nil
+	}
+	return c.delete(key)
+}
+
+// delete removes the key from the engine and publishes the invalidation once the key lock is released
+func (c *Cache[T]) delete(key string) error {
+	if err := c.deleteLocked(key); err != nil {
+		return err
+	}
+	c.publishInvalidation(OpDelete, key)
+	return nil
+}
+
+// deleteLocked removes the key from the engine holding its lock
+func (c *Cache[T]) deleteLocked(key string) error {
+	if err := c.waitWrite(nil); err != nil {
+		return wrapKeyError(OpDelete, key, err)
+	}
 	lock := c.lockKey(key)
 	defer c.unlock(lock)
-	return c.engine.Delete(key)
+	return c.deleteUnlocked(key)
+}
+
+// deleteUnlocked removes the key from the engine; the caller holds the key lock
+func (c *Cache[T]) deleteUnlocked(key string) error {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return wrapKeyError(OpDelete, key, err)
+	}
+	c.expiry.forget(engineKey)
+	err = c.audited(OpDelete, key, nil, func() error {
+		return c.timed(OpDelete, func() error {
+			return c.engine.Delete(engineKey)
+		})
+	})
+	if err == nil || errors.Is(err, ErrNotFound) {
+		c.forgetCosts([]string{engineKey})
+	}
+	return wrapKeyError(OpDelete, key, err)
 }
 
 // Purge removes all records from the cache
 func (c *Cache[T]) Purge() error {
-	c.engine.Purge()
+	if err := c.writeAllDirectly(); err != nil {
+		return wrapKeyError(OpPurge, "", err)
+	}
+	c.expiry.purge()
+	if err := c.audited(OpPurge, "", nil, c.engine.Purge); err == nil {
+		c.publishInvalidation(OpPurge, "")
+	}
 	return nil
 }
 
+// PurgePrefix removes all records with keys starting with the given prefix.
+// If the engine implements PrefixPurger it is used, otherwise the keys are deleted one by one.
+func (c *Cache[T]) PurgePrefix(prefix string) error {
+	if err := c.writeAllDirectly(); err != nil {
+		return wrapKeyError(OpPurge, prefix, err)
+	}
+	if purger, ok := c.engine.(PrefixPurger); ok {
+		enginePrefix, err := c.enginePrefix(prefix)
+		if err != nil {
+			return wrapKeyError(OpPurge, prefix, err)
+		}
+		if err := c.audited(OpPurge, prefix, nil, func() error { return purger.PurgePrefix(enginePrefix) }); err != nil {
+			return err
+		}
+		c.publishInvalidation(OpPurge, prefix)
+		return nil
+	}
+
+	_, err := c.DeleteWithPrefix(prefix)
+	return err
+}
+
 // Keys returns all the keys in cache
 func (c *Cache[T]) Keys() ([]string, error) {
-	return c.engine.Keys()
+	keys, err := c.engine.Keys()
+	return withoutCostKeys(keys), err
+}
+
+// KeysPage returns a page of keys starting at cursor ("" for the first page) and the cursor of the next page
+// ("" after the last page). If the engine implements KeysPager it is used (the page size may then differ
+// from limit), otherwise the keys are listed by Keys and paged in lexicographical order.
+func (c *Cache[T]) KeysPage(cursor string, limit int) ([]string, string, error) {
+	if pager, ok := c.engine.(KeysPager); ok {
+		keys, next, err := pager.KeysPage(cursor, limit)
+		return withoutCostKeys(keys), next, err
+	}
+
+	keys, err := c.Keys()
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(keys)
+	start := sort.SearchStrings(keys, cursor)
+	if start < len(keys) && keys[start] == cursor {
+		start++
+	}
+	keys = keys[start:]
+	if limit <= 0 || len(keys) <= limit {
+		return keys, "", nil
+	}
+	return keys[:limit], keys[limit-1], nil
+}
+
+// Range calls fn for every key in cache until fn returns false.
+// If the engine implements KeyIterator the keys are streamed, otherwise Keys is used.
+func (c *Cache[T]) Range(fn func(key string) bool) error {
+	if iterator, ok := c.engine.(KeyIterator); ok {
+		return iterator.RangeKeys(func(key string) bool {
+			return isCostKey(key) || fn(key)
+		})
+	}
+
+	keys, err := c.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !fn(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ForEach calls fn for every decoded entry in cache until fn returns false.
+// Entries are read without side-effects (Peek); entries deleted meanwhile are skipped silently,
+// entries which cannot be read or decoded are skipped and reported to opts.OnError.
+func (c *Cache[T]) ForEach(fn func(key string, value *T) bool, opts ForEachOptions) error {
+	return c.Range(func(key string) bool {
+		if c.expiry.expire(c.engine, key) {
+			return true
+		}
+		lock := c.lockKey(key)
+		value, err := c.engine.Peek(key)
+		c.unlock(lock)
+
+		var typedValue *T
+		if err == nil {
+			typedValue, err = c.toTyped(value)
+		}
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) && opts.OnError != nil {
+				opts.OnError(key, err)
+			}
+			return true
+		}
+
+		return fn(key, typedValue)
+	})
 }
@@ -0,0 +1,234 @@
+package cachier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrAlreadyJoined is returned by JoinInvalidationBus if the cache already joined a bus
+var ErrAlreadyJoined = errors.New("cache already joined an invalidation bus")
+
+// Invalidation is a delete or purge published on an InvalidationBus
+type Invalidation struct {
+	// Source identifies the cache which published the invalidation, so it does not apply its own ones
+	Source string `json:"source"`
+	// Op is OpDelete or OpPurge
+	Op string `json:"op"`
+	// Key is the deleted key or the purged prefix ("" for Purge)
+	Key string `json:"key"`
+	// EngineKey reports that Key is a stored key as listed by Keys (deletes of DeletePredicate and
+	// DeleteWithPrefix), so it is deleted as it is instead of being hashed or given a generation again
+	EngineKey bool `json:"engine_key,omitempty"`
+}
+
+// InvalidationBus distributes invalidations between processes, so caches with engines local to the processes
+// (e.g. the subcache of CacheWithSubcache) converge after deletes and purges
+type InvalidationBus interface {
+	// Publish sends the invalidation to all the subscribers
+	Publish(invalidation Invalidation) error
+	// Subscribe calls fn for every published invalidation (including the own ones) until stop is called
+	Subscribe(fn func(invalidation Invalidation)) (stop func() error, err error)
+}
+
+// JoinInvalidationBus publishes the deletes and purges of the cache on the bus and applies the invalidations
+// published by other caches to the engine, until leave is called. Keys deleted by DeletePredicate and
+// DeleteWithPrefix are published as listed by Keys (with EngineKey set). Invalidations are published after the local write
+// succeeded; errors of Publish are ignored.
+func (c *Cache[T]) JoinInvalidationBus(bus InvalidationBus) (leave func() error, err error) {
+	sourceBytes := make([]byte, 16)
+	if _, err := rand.Read(sourceBytes); err != nil {
+		return nil, err
+	}
+	joined := &joinedBus{bus: bus, source: hex.EncodeToString(sourceBytes)}
+	if !c.bus.CompareAndSwap(nil, joined) {
+		return nil, ErrAlreadyJoined
+	}
+
+	stop, err := bus.Subscribe(func(invalidation Invalidation) {
+		if invalidation.Source != joined.source {
+			c.invalidate(invalidation)
+		}
+	})
+	if err != nil {
+		c.bus.Store(nil)
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() error {
+		var stopErr error
+		once.Do(func() {
+			c.bus.Store(nil)
+			stopErr = stop()
+		})
+		return stopErr
+	}, nil
+}
+
+// joinedBus is the bus joined by a cache
+type joinedBus struct {
+	bus    InvalidationBus
+	source string
+}
+
+// publishInvalidation publishes the successful delete or purge if the cache joined a bus.
+// It must not be called holding key locks: MemoryInvalidationBus delivers synchronously to caches taking
+// their own key locks (two caches deleting the same key would deadlock) and other buses make network calls.
+func (c *Cache[T]) publishInvalidation(op string, key string) {
+	c.publish(Invalidation{Op: op, Key: key})
+}
+
+// publish publishes the invalidation from the cache if it joined a bus
+func (c *Cache[T]) publish(invalidation Invalidation) {
+	if joined := c.bus.Load(); joined != nil {
+		invalidation.Source = joined.source
+		joined.bus.Publish(invalidation)
+	}
+}
+
+// publishDeleted publishes the deletes of the engine keys returned by deleteKeys and passes its results through
+func (c *Cache[T]) publishDeleted(engineKeys []string, err error) ([]string, error) {
+	for _, engineKey := range engineKeys {
+		c.publish(Invalidation{Op: OpDelete, Key: engineKey, EngineKey: true})
+	}
+	return engineKeys, err
+}
+
+// invalidate applies the invalidation of another cache to the engine without publishing it again
+func (c *Cache[T]) invalidate(invalidation Invalidation) {
+	switch {
+	case invalidation.Op == OpDelete && invalidation.EngineKey:
+		c.expiry.forget(invalidation.Key)
+		c.engine.Delete(invalidation.Key)
+	case invalidation.Op == OpDelete:
+		lock := c.lockKey(invalidation.Key)
+		defer c.unlock(lock)
+		engineKey, err := c.engineKey(invalidation.Key)
+		if err != nil {
+			return
+		}
+		c.expiry.forget(engineKey)
+		c.engine.Delete(engineKey)
+	case invalidation.Op == OpPurge && invalidation.Key == "":
+		c.expiry.purge()
+		c.engine.Purge()
+	case invalidation.Op == OpPurge:
+		if purger, ok := c.engine.(PrefixPurger); ok {
+			prefix, err := c.enginePrefix(invalidation.Key)
+			if err != nil {
+				return
+			}
+			purger.PurgePrefix(prefix)
+			break
+		}
+		keys, err := c.KeysWithPrefix(invalidation.Key)
+		if err != nil {
+			return
+		}
+		for _, key := range keys {
+			c.expiry.forget(key)
+			c.engine.Delete(key)
+		}
+	default:
+		return
+	}
+	c.watchers.publish(invalidation.Op, invalidation.Key)
+}
+
+// MemoryInvalidationBus is an InvalidationBus within one process, e.g. for tests
+type MemoryInvalidationBus struct {
+	mutex       sync.Mutex
+	subscribers map[*func(invalidation Invalidation)]struct{}
+}
+
+// NewMemoryInvalidationBus creates a MemoryInvalidationBus
+func NewMemoryInvalidationBus() *MemoryInvalidationBus {
+	return &MemoryInvalidationBus{subscribers: make(map[*func(invalidation Invalidation)]struct{})}
+}
+
+// Publish calls the subscribers synchronously
+func (b *MemoryInvalidationBus) Publish(invalidation Invalidation) error {
+	b.mutex.Lock()
+	subscribers := make([]func(invalidation Invalidation), 0, len(b.subscribers))
+	for fn := range b.subscribers {
+		subscribers = append(subscribers, *fn)
+	}
+	b.mutex.Unlock()
+
+	for _, fn := range subscribers {
+		fn(invalidation)
+	}
+	return nil
+}
+
+// Subscribe calls fn for every published invalidation until stop is called
+func (b *MemoryInvalidationBus) Subscribe(fn func(invalidation Invalidation)) (stop func() error, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	subscriber := &fn
+	b.subscribers[subscriber] = struct{}{}
+	return func() error {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscribers, subscriber)
+		return nil
+	}, nil
+}
+
+// RedisInvalidationBus is an InvalidationBus using redis pub/sub. Messages published while a subscriber
+// is disconnected are lost.
+type RedisInvalidationBus struct {
+	redisClient *redis.Client
+	channel     string
+}
+
+// NewRedisInvalidationBus creates a RedisInvalidationBus publishing the invalidations on the channel
+func NewRedisInvalidationBus(redisClient *redis.Client, channel string) *RedisInvalidationBus {
+	return &RedisInvalidationBus{
+		redisClient: redisClient,
+		channel:     channel,
+	}
+}
+
+// Publish publishes the invalidation as JSON
+func (b *RedisInvalidationBus) Publish(invalidation Invalidation) error {
+	message, err := json.Marshal(invalidation)
+	if err != nil {
+		return err
+	}
+	return engineError(b.redisClient.Publish(ctx, b.channel, message).Err())
+}
+
+// Subscribe subscribes to the channel and calls fn for every invalidation until stop is called;
+// malformed messages are skipped
+func (b *RedisInvalidationBus) Subscribe(fn func(invalidation Invalidation)) (stop func() error, err error) {
+	pubsub := b.redisClient.Subscribe(ctx, b.channel)
+	// wait for the confirmation of the subscription
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, engineError(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for message := range pubsub.Channel() {
+			var invalidation Invalidation
+			if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+				continue
+			}
+			fn(invalidation)
+		}
+	}()
+
+	return func() error {
+		err := pubsub.Close()
+		<-done
+		return err
+	}, nil
+}
@@ -0,0 +1,183 @@
+package cachier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinInvalidationBus(t *testing.T) {
+	bus := NewMemoryInvalidationBus()
+	first, second := InitLRUCache[int](), InitLRUCache[int]()
+	leaveFirst, err := first.JoinInvalidationBus(bus)
+	require.Nil(t, err)
+	leaveSecond, err := second.JoinInvalidationBus(bus)
+	require.Nil(t, err)
+	_, err = second.JoinInvalidationBus(bus)
+	assert.ErrorIs(t, err, ErrAlreadyJoined)
+
+	value := 1
+	for _, cache := range []*Cache[int]{first, second} {
+		for _, key := range []string{"a", "b", "tenant1:a", "tenant2:a"} {
+			require.Nil(t, cache.Set(key, &value))
+		}
+	}
+
+	events, stop, err := second.Watch("")
+	require.Nil(t, err)
+	defer stop()
+
+	require.Nil(t, first.Delete("a"))
+	require.Nil(t, first.PurgePrefix("tenant1:"))
+	keys, err := second.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"b", "tenant2:a"}, keys)
+	assert.Equal(t, ChangeEvent{Op: OpDelete, Key: "a"}, receiveEvent(t, events))
+	// LRUCache does not implement PrefixPurger, so the purged keys are published one by one
+	assert.Equal(t, ChangeEvent{Op: OpDelete, Key: "tenant1:a"}, receiveEvent(t, events))
+
+	require.Nil(t, second.Purge())
+	keys, err = first.Keys()
+	require.Nil(t, err)
+	assert.Empty(t, keys)
+
+	// writes after leaving are not published
+	require.Nil(t, leaveFirst())
+	require.Nil(t, first.Set("c", &value))
+	require.Nil(t, second.Set("c", &value))
+	require.Nil(t, first.Delete("c"))
+	_, err = second.Get("c")
+	assert.Nil(t, err)
+	require.Nil(t, leaveSecond())
+}
+
+func TestInvalidationBusWithGenerations(t *testing.T) {
+	bus := NewMemoryInvalidationBus()
+	store := NewMemoryGenerationStore()
+	newCache := func() *Cache[int] {
+		lc, err := NewLRUCache(300, nil, nil, nil)
+		require.Nil(t, err)
+		cache := MakeCache[int](lc, WithGenerations(store, nil))
+		_, err = cache.JoinInvalidationBus(bus)
+		require.Nil(t, err)
+		return cache
+	}
+	first, second := newCache(), newCache()
+
+	value := 1
+	keys := []string{"tenant:1", "tenant:2", "tenant:3", "tenant:4", "other:1"}
+	for _, cache := range []*Cache[int]{first, second} {
+		for _, key := range keys {
+			require.Nil(t, cache.Set(key, &value))
+		}
+	}
+
+	require.Nil(t, first.Delete("tenant:1"))
+	require.Nil(t, first.DeleteKeys([]string{"tenant:2"}))
+	_, err := first.DeletePredicate(func(key string) bool { return key == "tenant#0:3" })
+	require.Nil(t, err)
+	_, err = first.DeleteWithPrefix("tenant:4")
+	require.Nil(t, err)
+	for _, key := range keys[:4] {
+		_, err := second.Peek(key)
+		assert.ErrorIs(t, err, ErrNotFound, key)
+	}
+	_, err = second.Peek("other:1")
+	assert.Nil(t, err)
+}
+
+func TestInvalidationBusConcurrentDeletes(t *testing.T) {
+	bus := NewMemoryInvalidationBus()
+	slow := func(call *Call, next func(call *Call) error) error {
+		time.Sleep(time.Millisecond)
+		return next(call)
+	}
+	first := MakeCache[int](WrapEngine(NewMemoryCache(0, 0), slow))
+	second := MakeCache[int](WrapEngine(NewMemoryCache(0, 0), slow))
+	for _, cache := range []*Cache[int]{first, second} {
+		leave, err := cache.JoinInvalidationBus(bus)
+		require.Nil(t, err)
+		defer leave()
+	}
+
+	// the invalidations are published after the key locks are released, so caches deleting the same key
+	// at the same time do not wait for each other
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			finished := make(chan struct{}, 2)
+			for _, cache := range []*Cache[int]{first, second} {
+				go func(cache *Cache[int]) {
+					cache.Delete("a")
+					finished <- struct{}{}
+				}(cache)
+			}
+			<-finished
+			<-finished
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("concurrent deletes deadlocked")
+	}
+}
+
+func TestCacheWithSubcacheJoinInvalidationBus(t *testing.T) {
+	bus := NewMemoryInvalidationBus()
+	primary := InitLRUCache[int]()
+	first := &CacheWithSubcache[int]{Cache: primary, Subcache: InitLRUCache[int]()}
+	second := &CacheWithSubcache[int]{Cache: primary, Subcache: InitLRUCache[int]()}
+	for _, cs := range []*CacheWithSubcache[int]{first, second} {
+		leave, err := cs.JoinInvalidationBus(bus)
+		require.Nil(t, err)
+		defer leave()
+	}
+
+	require.Nil(t, first.Set("a", 1))
+	_, err := second.Get("a")
+	require.Nil(t, err)
+	_, err = second.Subcache.DrainWithin(ctx, nil)
+	require.Nil(t, err)
+	_, err = second.Subcache.Get("a")
+	require.Nil(t, err)
+
+	require.Nil(t, first.Delete("a"))
+	_, err = second.Subcache.Get("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRedisInvalidationBus(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	bus := NewRedisInvalidationBus(redisClient, "cachier-test-invalidations")
+	first, second := InitLRUCache[int](), InitLRUCache[int]()
+	for _, cache := range []*Cache[int]{first, second} {
+		leave, err := cache.JoinInvalidationBus(bus)
+		require.Nil(t, err)
+		defer leave()
+	}
+
+	value := 1
+	require.Nil(t, second.Set("a", &value))
+	events, stop, err := second.Watch("")
+	require.Nil(t, err)
+	defer stop()
+
+	require.Nil(t, first.Delete("a"))
+	assert.Equal(t, ChangeEvent{Op: OpDelete, Key: "a"}, receiveEvent(t, events))
+	_, err = second.Get("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// the own invalidations are not applied again
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,45 @@
+package cachier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DefaultKeyHashThreshold is the key length above which keys are hashed by WithKeyHasher(nil);
+// it matches the memcached key size limit
+const DefaultKeyHashThreshold = 250
+
+// KeyHasher maps a key to the key stored in the engine
+type KeyHasher func(key string) string
+
+// SHA256KeyHasher returns a KeyHasher which replaces keys longer than threshold bytes
+// with their leading part followed by "#" and the hex encoded SHA-256 of the whole key,
+// so the result is at most threshold bytes long and prefix based operations keep working for short prefixes.
+// If threshold is too small to keep any part of the key, just the hash is used.
+func SHA256KeyHasher(threshold int) KeyHasher {
+	return func(key string) string {
+		if len(key) <= threshold {
+			return key
+		}
+		sum := sha256.Sum256([]byte(key))
+		hash := hex.EncodeToString(sum[:])
+		keep := threshold - len(hash) - 1
+		if keep <= 0 {
+			return hash
+		}
+		return key[:keep] + "#" + hash
+	}
+}
+
+// WithKeyHasher makes the cache store all the keys mapped by hasher, so arbitrary long keys
+// can be used without hitting key size limits of the engine.
+// If hasher is nil SHA256KeyHasher(DefaultKeyHashThreshold) is used.
+// Keys listed by Keys, KeysPredicate, Range etc. are the stored (hashed) keys.
+func WithKeyHasher(hasher KeyHasher) Option {
+	if hasher == nil {
+		hasher = SHA256KeyHasher(DefaultKeyHashThreshold)
+	}
+	return func(o *options) {
+		o.keyHasher = hasher
+	}
+}
@@ -0,0 +1,42 @@
+package cachier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSHA256KeyHasher(t *testing.T) {
+	hasher := SHA256KeyHasher(100)
+	assert.Equal(t, "short", hasher("short"))
+
+	long := "tenant:1:" + strings.Repeat("x", 200)
+	hashed := hasher(long)
+	assert.Len(t, hashed, 100)
+	assert.True(t, strings.HasPrefix(hashed, "tenant:1:"))
+	assert.NotEqual(t, hashed, hasher(long+"y"))
+
+	assert.Len(t, SHA256KeyHasher(10)(long), 64)
+}
+
+func TestWithKeyHasher(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+	cache := MakeCache[string](mc, WithKeyHasher(nil))
+
+	long := strings.Repeat("k", 1000)
+	value := "value"
+	require.Nil(t, cache.Set(long, &value))
+	output, err := cache.Get(long)
+	require.Nil(t, err)
+	assert.Equal(t, value, *output)
+
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	require.Len(t, keys, 1)
+	assert.Len(t, keys[0], DefaultKeyHashThreshold)
+
+	require.Nil(t, cache.Delete(long))
+	assert.Equal(t, 0, mc.Len())
+}
@@ -0,0 +1,154 @@
+package cachier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysPredicate(t *testing.T) {
+	c := InitLRUCache[int]()
+	for i, key := range []string{"a:1", "a:2", "b:1"} {
+		value := i
+		require.Nil(t, c.Set(key, &value))
+	}
+
+	count, err := c.CountPredicate(func(key string) bool { return strings.HasSuffix(key, ":1") })
+	require.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	keys, err := c.KeysWithPrefix("a:")
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a:1", "a:2"}, keys)
+
+	removed, err := c.DeleteWithPrefix("a:")
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a:1", "a:2"}, removed)
+
+	keys, err = c.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"b:1"}, keys)
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, `tenant\*:\[1\]\?`, escapeGlob("tenant*:[1]?"))
+	assert.Equal(t, "plain:prefix", escapeGlob("plain:prefix"))
+}
+
+func TestKeysPage(t *testing.T) {
+	c := InitLRUCache[int]()
+	for i, key := range []string{"e", "b", "d", "a", "c"} {
+		value := i
+		require.Nil(t, c.Set(key, &value))
+	}
+
+	pages := make([][]string, 0)
+	cursor := ""
+	for {
+		keys, next, err := c.KeysPage(cursor, 2)
+		require.Nil(t, err)
+		pages = append(pages, keys)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages)
+}
+
+func TestRedisKeysPage(t *testing.T) {
+	c, err := InitRedisCache[int]()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	_, _, err = c.KeysPage("not a cursor", 10)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestRedisDeletePredicate(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "delete:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil).WithChunking(100)
+	c := MakeCache[string](rc)
+	defer c.Purge()
+
+	large := strings.Repeat("a", 1000)
+	small := "small"
+	values := map[string]*string{"keep": &small}
+	for i := 0; i < deleteBatchSize+10; i++ {
+		values[fmt.Sprintf("drop:%d", i)] = &small
+	}
+	values["drop:large"] = &large
+	require.Nil(t, c.SetMulti(values))
+
+	removed, err := c.DeleteWithPrefix("drop:")
+	require.Nil(t, err)
+	assert.Len(t, removed, deleteBatchSize+11)
+
+	keys, err := redisClient.Keys(ctx, "delete:*").Result()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"delete:keep"}, keys)
+}
+
+func TestDeleteKeys(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "delete-keys:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil)
+	for name, c := range map[string]*Cache[int]{"lru": InitLRUCache[int](), "redis": MakeCache[int](rc)} {
+		t.Run(name, func(t *testing.T) {
+			defer c.Purge()
+			for i := 0; i < 5; i++ {
+				value := i
+				require.Nil(t, c.Set(fmt.Sprintf("key:%d", i), &value))
+			}
+
+			require.Nil(t, c.DeleteKeys([]string{"key:3", "key:1", "key:3", "missing"}))
+			require.Nil(t, c.DeleteKeys(nil))
+
+			keys, err := c.Keys()
+			require.Nil(t, err)
+			assert.ElementsMatch(t, []string{"key:0", "key:2", "key:4"}, keys)
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "count:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil).WithChunking(100)
+	partitions := []CacheEngine{NewMemoryCache(0, 0), NewMemoryCache(0, 0)}
+	for name, c := range map[string]*Cache[string]{
+		"lru":         InitLRUCache[string](),
+		"redis":       MakeCache[string](rc),
+		"partitioned": MakeCache[string](NewPartitionedEngine(partitions, nil)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer c.Purge()
+			for i := 0; i < 5; i++ {
+				value := strings.Repeat("a", i*100)
+				require.Nil(t, c.Set(fmt.Sprintf("key:%d", i), &value))
+			}
+
+			// chunks of the large values are not counted
+			count, err := c.Count()
+			require.Nil(t, err)
+			assert.Equal(t, 5, count)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package cachier
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// OpEvaluate identifies the evaluator runs of GetOrCompute in Stats
+const OpEvaluate = "evaluate"
+
+// latencyBounds are the upper bounds of the latency buckets
+var latencyBounds = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// latencyOps are the operations whose latency is recorded
+var latencyOps = []string{OpGet, OpSet, OpDelete, OpEvaluate}
+
+// Stats is a snapshot of the statistics of a cache
+type Stats struct {
+	// Latencies are the latency histograms by operation (OpGet, OpSet, OpDelete of the engine and OpEvaluate);
+	// they are recorded only with WithLatencyHistograms
+	Latencies map[string]LatencyHistogram `json:"latencies,omitempty"`
+	// PendingWrites is the number of values computed by GetOrCompute still being written and of the held writes
+	// (see PauseWrites)
+	PendingWrites int `json:"pending_writes"`
+	// PendingBytes is the size of these values; they are measured only with WithPendingWritesBudget
+	PendingBytes int64 `json:"pending_bytes"`
+}
+
+// LatencyHistogram counts operations by their duration
+type LatencyHistogram struct {
+	// Count is the number of operations
+	Count uint64 `json:"count"`
+	// Sum is the total duration of the operations
+	Sum time.Duration `json:"sum"`
+	// Bounds are the upper bounds of the buckets
+	Bounds []time.Duration `json:"bounds"`
+	// Counts counts the operations by duration: Counts[i] counts the operations not longer than Bounds[i]
+	// not counted by the previous buckets, the last one counts the rest
+	Counts []uint64 `json:"counts"`
+}
+
+// WithLatencyHistograms records the latency of engine Get, Set and Delete operations and of evaluator runs,
+// so a slow engine can be told apart from slow evaluators; see Cache.Stats
+func WithLatencyHistograms() Option {
+	return func(o *options) {
+		o.latencies = newLatencyRecorder()
+	}
+}
+
+// latencyRecorder holds the histograms of the operations
+type latencyRecorder struct {
+	histograms map[string]*latencyHistogram
+}
+
+type latencyHistogram struct {
+	count  atomic.Uint64
+	sum    atomic.Int64
+	counts []atomic.Uint64
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	r := &latencyRecorder{histograms: make(map[string]*latencyHistogram, len(latencyOps))}
+	for _, op := range latencyOps {
+		r.histograms[op] = &latencyHistogram{counts: make([]atomic.Uint64, len(latencyBounds)+1)}
+	}
+	return r
+}
+
+// observe records the duration of the operation; nil recorder records nothing
+func (r *latencyRecorder) observe(op string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	h := r.histograms[op]
+	bucket := len(latencyBounds)
+	for i, bound := range latencyBounds {
+		if duration <= bound {
+			bucket = i
+			break
+		}
+	}
+	h.counts[bucket].Add(1)
+	h.sum.Add(int64(duration))
+	h.count.Add(1)
+}
+
+func (r *latencyRecorder) snapshot() map[string]LatencyHistogram {
+	if r == nil {
+		return nil
+	}
+	snapshot := make(map[string]LatencyHistogram, len(r.histograms))
+	for op, h := range r.histograms {
+		counts := make([]uint64, len(h.counts))
+		for i := range h.counts {
+			counts[i] = h.counts[i].Load()
+		}
+		snapshot[op] = LatencyHistogram{
+			Count:  h.count.Load(),
+			Sum:    time.Duration(h.sum.Load()),
+			Bounds: append([]time.Duration(nil), latencyBounds...),
+			Counts: counts,
+		}
+	}
+	return snapshot
+}
+
+// timed runs op and records its latency if WithLatencyHistograms is enabled
+func (c *Cache[T]) timed(op string, fn func() error) error {
+	if c.options.latencies == nil {
+		return fn()
+	}
+	clock := clockOrDefault(c.options.clock)
+	start := clock.Now()
+	err := fn()
+	c.options.latencies.observe(op, clock.Since(start))
+	return err
+}
+
+// measured wraps the evaluator to record its latency (WithLatencyHistograms)
+// and store its compute cost in background (WithEarlyExpiration) if enabled
+func (c *Cache[T]) measured(key string, evaluator func() (*T, time.Duration, error)) func() (*T, time.Duration, error) {
+	x := c.options.xfetch
+	if x == nil && c.options.latencies == nil {
+		return evaluator
+	}
+	return func() (*T, time.Duration, error) {
+		clock := clockOrDefault(c.options.clock)
+		start := clock.Now()
+		value, ttl, err := evaluator()
+		cost := clock.Since(start)
+		c.options.latencies.observe(OpEvaluate, cost)
+		if err == nil && x != nil {
+			c.writes.started(0)
+			go func() {
+				defer c.writes.done(0)
+				c.recordCost(key, cost, ttl)
+			}()
+		}
+		return value, ttl, err
+	}
+}
+
+// Stats returns a snapshot of the statistics of the cache
+func (c *Cache[T]) Stats() Stats {
+	return Stats{
+		Latencies:     c.options.latencies.snapshot(),
+		PendingWrites: int(c.writes.pending.Load()) + c.held.count(),
+		PendingBytes:  c.writes.bytes.Load() + c.held.bytes.Load(),
+	}
+}
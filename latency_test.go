@@ -0,0 +1,54 @@
+package cachier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHistograms(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0), cachier.WithClock(clock), cachier.WithLatencyHistograms())
+
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	_, err := cache.Get("a")
+	require.Nil(t, err)
+	_, err = cache.GetOrCompute("b", func() (*int, error) {
+		clock.Advance(2 * time.Second)
+		return &value, nil
+	})
+	require.Nil(t, err)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+	require.Nil(t, cache.Delete("a"))
+
+	latencies := cache.Stats().Latencies
+	assert.Equal(t, uint64(2), latencies[cachier.OpGet].Count)
+	assert.Equal(t, uint64(2), latencies[cachier.OpSet].Count)
+	assert.Equal(t, uint64(1), latencies[cachier.OpDelete].Count)
+
+	evaluate := latencies[cachier.OpEvaluate]
+	assert.Equal(t, uint64(1), evaluate.Count)
+	assert.Equal(t, 2*time.Second, evaluate.Sum)
+	for i, bound := range evaluate.Bounds {
+		if bound == 5*time.Second {
+			assert.Equal(t, uint64(1), evaluate.Counts[i])
+		} else {
+			assert.Equal(t, uint64(0), evaluate.Counts[i])
+		}
+	}
+	assert.Equal(t, uint64(0), evaluate.Counts[len(evaluate.Bounds)])
+}
+
+func TestLatencyHistogramsDisabled(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0))
+	_, err := cache.Get("a")
+	require.ErrorIs(t, err, cachier.ErrNotFound)
+	assert.Nil(t, cache.Stats().Latencies)
+}
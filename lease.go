@@ -0,0 +1,182 @@
+package cachier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// leaseKeySuffix is appended to the key to get the key holding its lease
+const leaseKeySuffix = "\x00lease"
+
+// LeaseEngine is an optional interface of CacheEngine.
+// Engines implementing it store the leases of EngineLocker as they are, independently of the codec of the values
+// (e.g. RedisCache using SET NX PX); other engines get them through AtomicEngine as string values.
+type LeaseEngine interface {
+	// AcquireLease stores the token under the key for ttl only if the key does not exist and reports whether
+	// it was stored
+	AcquireLease(key string, token string, ttl time.Duration) (bool, error)
+	// ReleaseLease deletes the key only if it still holds the token
+	ReleaseLease(key string, token string) error
+}
+
+// leaseOptions holds the configuration of WithLeases
+type leaseOptions struct {
+	ttl  time.Duration
+	wait time.Duration
+}
+
+// WithLeases protects GetOrCompute against stampedes across processes using lease tokens stored in the engine
+// itself, which must implement AtomicEngine (otherwise the option has no effect). On a miss the first caller
+// gets the lease (held at most leaseTTL) and computes the value, other callers wait for it at most wait and
+// compute it themselves if it does not appear. With WithSoftTTL the caller holding the lease recomputes a stale
+// value while the others get the stale value immediately. Lease keys (the key followed by "\x00lease") are
+// listed by Keys while they are held; engines not implementing LeaseEngine keep the released leases as expired
+// ones until the key is leased again.
+func WithLeases(leaseTTL time.Duration, wait time.Duration) Option {
+	return func(o *options) {
+		o.leases = &leaseOptions{ttl: leaseTTL, wait: wait}
+	}
+}
+
+// applyLeases replaces the distributed lock by the leases stored in the engine
+func (o *options) applyLeases(engine CacheEngine) {
+	if o.leases == nil {
+		return
+	}
+	atomicEngine, ok := engine.(AtomicEngine)
+	if !ok {
+		return
+	}
+	o.distributedLock = &distributedLockOptions{
+		locker:       NewEngineLocker(atomicEngine).WithClock(o.clock),
+		ttl:          o.leases.ttl,
+		wait:         o.leases.wait,
+		pollInterval: defaultLockPollInterval,
+		serveStale:   true,
+	}
+}
+
+// refreshWithLease recomputes the stale value of the key if it gets the lease, otherwise returns the stale value
+func (c *Cache[T]) refreshWithLease(key string, stale *T, evaluator func() (*T, time.Duration, error)) *T {
+	lockOptions := c.options.distributedLock
+	lockKey, err := c.engineKey(key)
+	if err != nil {
+		return stale
+	}
+	unlock, acquired, err := lockOptions.locker.Lock(lockKey, lockOptions.ttl)
+	if err != nil || !acquired {
+		return stale
+	}
+	defer unlock()
+
+	value, ttl, err := evaluator()
+	if err != nil {
+		return stale
+	}
+	c.SetWithTTL(key, value, ttl)
+	return value
+}
+
+// EngineLocker is a DistributedLocker storing leases in an AtomicEngine, so processes sharing the engine
+// (e.g. RedisCache) coordinate without another service. Engines implementing LeaseEngine store the random token
+// of a lease with its expiration. Other engines store the token with its deadline as a string value, so they
+// must store the values as they are (e.g. MemoryCache); expired leases are taken over atomically.
+type EngineLocker struct {
+	engine AtomicEngine
+	clock  Clock
+}
+
+// NewEngineLocker creates an EngineLocker
+func NewEngineLocker(engine AtomicEngine) *EngineLocker {
+	return &EngineLocker{
+		engine: engine,
+		clock:  SystemClock{},
+	}
+}
+
+// WithClock sets the clock used for the deadlines of the leases; nil means SystemClock.
+// The processes sharing the leases must have synchronized clocks.
+func (l *EngineLocker) WithClock(clock Clock) *EngineLocker {
+	l.clock = clockOrDefault(clock)
+	return l
+}
+
+// Lock tries to acquire the lease of the key; the lease is released only by its owner or when it expires
+func (l *EngineLocker) Lock(key string, ttl time.Duration) (func() error, bool, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, false, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	leaseKey := key + leaseKeySuffix
+	if leaseEngine, ok := l.engine.(LeaseEngine); ok {
+		acquired, err := leaseEngine.AcquireLease(leaseKey, token, ttl)
+		if err != nil || !acquired {
+			return nil, false, err
+		}
+		return func() error {
+			return leaseEngine.ReleaseLease(leaseKey, token)
+		}, true, nil
+	}
+
+	now := l.clock.Now()
+	lease := token + ":" + strconv.FormatInt(now.Add(ttl).UnixNano(), 10)
+
+	stored, err := l.engine.SetIfAbsent(leaseKey, lease)
+	if err != nil {
+		return nil, false, err
+	}
+	if !stored {
+		current, version, err := l.engine.GetWithVersion(leaseKey)
+		if errors.Is(err, ErrNotFound) {
+			// released meanwhile, the next caller will get it
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+		if _, deadline, ok := parseLease(current); ok && now.Before(deadline) {
+			return nil, false, nil
+		}
+		// the lease expired (or cannot be read), take it over
+		swapped, err := l.engine.CompareAndSwap(leaseKey, version, lease)
+		if err != nil || !swapped {
+			return nil, false, err
+		}
+	}
+
+	return func() error {
+		current, version, err := l.engine.GetWithVersion(leaseKey)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if owner, _, ok := parseLease(current); !ok || owner != token {
+			return nil
+		}
+		// the lease is expired rather than deleted, so a lease taken over meanwhile is not released
+		_, err = l.engine.CompareAndSwap(leaseKey, version, token+":0")
+		return err
+	}, true, nil
+}
+
+// parseLease returns the token and the deadline of the lease
+func parseLease(value interface{}) (token string, deadline time.Time, ok bool) {
+	lease, ok := value.(string)
+	if !ok {
+		return "", time.Time{}, false
+	}
+	token, deadlineString, found := strings.Cut(lease, ":")
+	if !found {
+		return "", time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(deadlineString, 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return token, time.Unix(0, nanos), true
+}
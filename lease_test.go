@@ -0,0 +1,134 @@
+package cachier_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngineLocker(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	locker := cachier.NewEngineLocker(cachier.NewMemoryCache(0, 0)).WithClock(clock)
+
+	unlock, acquired, err := locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	require.True(t, acquired)
+	_, acquired, err = locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+
+	// the expired lease is taken over and the previous owner cannot release it
+	clock.Advance(time.Second)
+	unlockTaken, acquired, err := locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	require.True(t, acquired)
+	require.Nil(t, unlock())
+	_, acquired, err = locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+
+	require.Nil(t, unlockTaken())
+	_, acquired, err = locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	assert.True(t, acquired)
+}
+
+func TestLeasesMiss(t *testing.T) {
+	engine := cachier.NewMemoryCache(0, 0)
+	var evaluations atomic.Int32
+	evaluator := func() (*int, error) {
+		evaluations.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		value := 1
+		return &value, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every caller has its own Cache as if it ran in another process
+			cache := cachier.MakeCache[int](engine, cachier.WithLeases(time.Second, time.Second))
+			value, err := cache.GetOrCompute("key", evaluator)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, *value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), evaluations.Load())
+}
+
+func TestLeasesStale(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachier.NewMemoryCacheWithClock(time.Hour, 0, clock)
+	cache := cachier.MakeCache[int](engine, cachier.WithSoftTTL(time.Minute), cachier.WithLeases(time.Second, time.Second))
+
+	stale := 1
+	require.Nil(t, cache.Set("key", &stale))
+	clock.Advance(time.Minute)
+	fresh := func() (*int, error) {
+		value := 2
+		return &value, nil
+	}
+
+	// another process holds the lease, the stale value is served
+	unlock, acquired, err := cachier.NewEngineLocker(engine).WithClock(clock).Lock("key", time.Second)
+	require.Nil(t, err)
+	require.True(t, acquired)
+	value, err := cache.GetOrCompute("key", fresh)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *value)
+
+	require.Nil(t, unlock())
+	value, err = cache.GetOrCompute("key", fresh)
+	require.Nil(t, err)
+	assert.Equal(t, 2, *value)
+	value, err = cache.Peek("key")
+	require.Nil(t, err)
+	assert.Equal(t, 2, *value)
+}
+
+// hookedAtomicEngine calls afterGet once after the next GetWithVersion
+type hookedAtomicEngine struct {
+	*cachier.MemoryCache
+	afterGet func()
+}
+
+func (e *hookedAtomicEngine) GetWithVersion(key string) (interface{}, string, error) {
+	value, version, err := e.MemoryCache.GetWithVersion(key)
+	if hook := e.afterGet; hook != nil {
+		e.afterGet = nil
+		hook()
+	}
+	return value, version, err
+}
+
+func TestEngineLockerUnlockAfterTakeover(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := &hookedAtomicEngine{MemoryCache: cachier.NewMemoryCache(0, 0)}
+	locker := cachier.NewEngineLocker(engine).WithClock(clock)
+
+	unlock, acquired, err := locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	require.True(t, acquired)
+
+	// the expired lease is taken over between the check of its owner and its release
+	clock.Advance(time.Second)
+	engine.afterGet = func() {
+		_, acquired, err := locker.Lock("key", time.Second)
+		require.Nil(t, err)
+		require.True(t, acquired)
+	}
+	require.Nil(t, unlock())
+
+	_, acquired, err = locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+}
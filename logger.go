@@ -0,0 +1,112 @@
+package cachier
+
+import "sync/atomic"
+
+// Logger is interface for logging
+type Logger interface {
+	Error(...interface{})
+	Warn(...interface{})
+	Print(...interface{})
+}
+
+// DebugLogger is a Logger which is able to log debug messages.
+// Loggers which do not implement it receive debug messages via Print.
+type DebugLogger interface {
+	Logger
+	Debug(...interface{})
+}
+
+// LogLevel defines the minimum severity of messages which are logged
+type LogLevel int
+
+// Log levels
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+	LogLevelOff
+)
+
+// DummyLogger is implementation of Logger that does not log anything
+type DummyLogger struct{}
+
+// Error does nothing
+func (d DummyLogger) Error(...interface{}) {}
+
+// Warn does nothing
+func (d DummyLogger) Warn(...interface{}) {}
+
+// Print does nothing
+func (d DummyLogger) Print(...interface{}) {}
+
+// Debug does nothing
+func (d DummyLogger) Debug(...interface{}) {}
+
+// LevelLogger wraps any Logger and drops messages below the configured level
+type LevelLogger struct {
+	logger Logger
+	level  atomic.Int32
+}
+
+// NewLevelLogger creates a LevelLogger logging messages with severity >= level
+func NewLevelLogger(logger Logger, level LogLevel) *LevelLogger {
+	l := &LevelLogger{logger: loggerOrDefault(logger)}
+	l.SetLevel(level)
+	return l
+}
+
+// SetLevel changes the minimum severity of logged messages; it is safe to call while logging
+func (l *LevelLogger) SetLevel(level LogLevel) {
+	l.level.Store(int32(level))
+}
+
+// Level returns the minimum severity of logged messages
+func (l *LevelLogger) Level() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// Error logs an error message
+func (l *LevelLogger) Error(args ...interface{}) {
+	if l.Level() <= LogLevelError {
+		l.logger.Error(args...)
+	}
+}
+
+// Warn logs a warning message
+func (l *LevelLogger) Warn(args ...interface{}) {
+	if l.Level() <= LogLevelWarn {
+		l.logger.Warn(args...)
+	}
+}
+
+// Print logs an informational message
+func (l *LevelLogger) Print(args ...interface{}) {
+	if l.Level() <= LogLevelInfo {
+		l.logger.Print(args...)
+	}
+}
+
+// Debug logs a debug message; it falls back to Print if the wrapped logger has no Debug method
+func (l *LevelLogger) Debug(args ...interface{}) {
+	if l.Level() <= LogLevelDebug {
+		logDebug(l.logger, args...)
+	}
+}
+
+// loggerOrDefault returns DummyLogger if logger is nil
+func loggerOrDefault(logger Logger) Logger {
+	if logger == nil {
+		return DummyLogger{}
+	}
+	return logger
+}
+
+// logDebug logs a debug message using Debug if the logger supports it, Print otherwise
+func logDebug(logger Logger, args ...interface{}) {
+	if debugLogger, ok := logger.(DebugLogger); ok {
+		debugLogger.Debug(args...)
+		return
+	}
+	logger.Print(args...)
+}
@@ -0,0 +1,120 @@
+package cachier
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// maxSampledMessages bounds the number of distinct messages tracked by SamplingLogger;
+// the least recently logged ones are forgotten first
+const maxSampledMessages = 1024
+
+// SamplingLogger wraps any Logger and rate limits repeated messages.
+// Messages are grouped by level and their first argument (e.g. "redis: error in get with key: "),
+// so the same error reported for different keys is sampled together.
+// The first `first` messages of a group are logged, then only every `thereafter`-th one
+// together with the number of messages suppressed since the last logged one.
+type SamplingLogger struct {
+	logger     Logger
+	first      int
+	thereafter int
+	counters   map[string]*list.Element
+	// recency orders the counters from the most recently logged group
+	recency *list.List
+	mutex   sync.Mutex
+}
+
+// sampledGroup counts the messages of a group
+type sampledGroup struct {
+	key string
+	n   int
+}
+
+// NewSamplingLogger creates a SamplingLogger which logs the first `first` occurrences of a message
+// and then every `thereafter`-th occurrence. If thereafter <= 0 the remaining messages are dropped.
+func NewSamplingLogger(logger Logger, first int, thereafter int) *SamplingLogger {
+	return &SamplingLogger{
+		logger:     loggerOrDefault(logger),
+		first:      first,
+		thereafter: thereafter,
+		counters:   make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+}
+
+// Error logs a sampled error message
+func (s *SamplingLogger) Error(args ...interface{}) {
+	if args, ok := s.sample("error", args); ok {
+		s.logger.Error(args...)
+	}
+}
+
+// Warn logs a sampled warning message
+func (s *SamplingLogger) Warn(args ...interface{}) {
+	if args, ok := s.sample("warn", args); ok {
+		s.logger.Warn(args...)
+	}
+}
+
+// Print logs a sampled informational message
+func (s *SamplingLogger) Print(args ...interface{}) {
+	if args, ok := s.sample("print", args); ok {
+		s.logger.Print(args...)
+	}
+}
+
+// Debug logs a sampled debug message
+func (s *SamplingLogger) Debug(args ...interface{}) {
+	if args, ok := s.sample("debug", args); ok {
+		logDebug(s.logger, args...)
+	}
+}
+
+// Reset forgets all counters, so every message is logged again
+func (s *SamplingLogger) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.counters = make(map[string]*list.Element)
+	s.recency.Init()
+}
+
+// sample decides whether the message should be logged and
+// appends the suppressed-count summary when some messages were dropped
+func (s *SamplingLogger) sample(level string, args []interface{}) ([]interface{}, bool) {
+	key := level
+	if len(args) > 0 {
+		key += ":" + fmt.Sprint(args[0])
+	}
+
+	s.mutex.Lock()
+	element, found := s.counters[key]
+	if found {
+		s.recency.MoveToFront(element)
+	} else {
+		if len(s.counters) >= maxSampledMessages {
+			oldest := s.recency.Back()
+			s.recency.Remove(oldest)
+			delete(s.counters, oldest.Value.(*sampledGroup).key)
+		}
+		element = s.recency.PushFront(&sampledGroup{key: key})
+		s.counters[key] = element
+	}
+	group := element.Value.(*sampledGroup)
+	group.n++
+	n := group.n
+	s.mutex.Unlock()
+
+	if n <= s.first {
+		return args, true
+	}
+	if s.thereafter <= 0 || (n-s.first)%s.thereafter != 0 {
+		return nil, false
+	}
+
+	suppressed := s.thereafter - 1
+	if suppressed == 0 {
+		return args, true
+	}
+	return append(args, fmt.Sprintf(" (suppressed %d similar messages)", suppressed)), true
+}
@@ -0,0 +1,130 @@
+package cachier
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (r *recordingLogger) record(level string, args ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages = append(r.messages, level+": "+fmt.Sprint(args...))
+}
+
+func (r *recordingLogger) Error(args ...interface{}) { r.record("error", args...) }
+func (r *recordingLogger) Warn(args ...interface{})  { r.record("warn", args...) }
+func (r *recordingLogger) Print(args ...interface{}) { r.record("print", args...) }
+
+func (r *recordingLogger) Messages() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+func TestLevelLogger(t *testing.T) {
+	recorder := &recordingLogger{}
+	logger := NewLevelLogger(recorder, LogLevelInfo)
+
+	logger.Debug("debug")
+	logger.Print("print")
+	logger.Warn("warn")
+	logger.Error("error")
+	assert.Equal(t, []string{"print: print", "warn: warn", "error: error"}, recorder.Messages())
+
+	logger.SetLevel(LogLevelDebug)
+	logger.Debug("debug")
+	// recorder has no Debug method so the message falls back to Print
+	assert.Equal(t, "print: debug", recorder.Messages()[3])
+
+	logger.SetLevel(LogLevelOff)
+	logger.Error("error")
+	assert.Len(t, recorder.Messages(), 4)
+}
+
+func TestLevelLoggerConcurrentSetLevel(t *testing.T) {
+	logger := NewLevelLogger(&recordingLogger{}, LogLevelOff)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.SetLevel(LogLevel(i % int(LogLevelOff+1)))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			logger.Error("error")
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, LogLevel(99%int(LogLevelOff+1)), logger.Level())
+}
+
+func TestSamplingLogger(t *testing.T) {
+	recorder := &recordingLogger{}
+	logger := NewSamplingLogger(recorder, 2, 3)
+
+	for i := 0; i < 8; i++ {
+		logger.Error("redis: error getting data with key: ", i)
+	}
+	logger.Warn("other")
+
+	assert.Equal(t, []string{
+		"error: redis: error getting data with key: 0",
+		"error: redis: error getting data with key: 1",
+		"error: redis: error getting data with key: 4 (suppressed 2 similar messages)",
+		"error: redis: error getting data with key: 7 (suppressed 2 similar messages)",
+		"warn: other",
+	}, recorder.Messages())
+
+	logger.Reset()
+	logger.Error("redis: error getting data with key: ", 8)
+	assert.Len(t, recorder.Messages(), 6)
+}
+
+func TestSamplingLoggerManyMessages(t *testing.T) {
+	recorder := &recordingLogger{}
+	logger := NewSamplingLogger(recorder, 1, 0)
+
+	// the frequent message stays tracked while more than maxSampledMessages others come and go
+	for i := 0; i < 2*maxSampledMessages; i++ {
+		logger.Error("redis: connection refused")
+		logger.Error(fmt.Sprint("rare message ", i))
+	}
+
+	messages := recorder.Messages()
+	assert.Len(t, messages, 1+2*maxSampledMessages)
+	assert.Equal(t, "error: redis: connection refused", messages[0])
+	assert.Equal(t, "error: rare message 0", messages[1])
+}
+
+func TestSamplingLoggingInterceptor(t *testing.T) {
+	recorder := &recordingLogger{}
+	engine := WrapEngine(NewMemoryCache(0, 0), LoggingInterceptor(NewSamplingLogger(recorder, 1, 0), "memory"))
+
+	// the keys are not part of the sampled group
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, engine.Set(fmt.Sprint("key", i), i))
+	}
+	assert.Equal(t, []string{"print: memory set key0"}, recorder.Messages())
+}
+
+func TestNilLogger(t *testing.T) {
+	lc, err := NewLRUCacheWithLogger(10, nil, nil, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, DummyLogger{}, lc.logger)
+
+	recorder := &recordingLogger{}
+	assert.Equal(t, recorder, lc.WithLogger(recorder).logger)
+	assert.Equal(t, DummyLogger{}, lc.WithLogger(nil).logger)
+}
@@ -1,20 +1,30 @@
 package cachier
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/datasapiens/cachier/compression"
-	lru "github.com/hashicorp/golang-lru"
 )
 
 // LRUCache is a wrapper of hashicorp's golang-lru cache which
-// implements cachier.Cache interface
+// implements cachier.Cache interface; other eviction policies can be set by WithEvictionPolicy
 type LRUCache struct {
-	lru               *lru.Cache
+	store             entryStore
+	size              int
 	marshal           func(value interface{}) ([]byte, error)
 	unmarshal         func(b []byte, value *interface{}) error
 	compressionEngine *compression.Engine
 	logger            Logger
+	maxValueSize      maxValueSize
+	evictions         *evictionTracker
+	rawValues         bool
+
+	// pinned holds the pinned entries outside of the lru, so they are never evicted
+	pinned      map[string]interface{}
+	pinnedMutex sync.Mutex
 }
 
 // NewLRUCache is a constructor that creates LRU cache of given size
@@ -26,37 +36,129 @@ func NewLRUCache(
 	unmarshal func(b []byte, value *interface{}) error,
 	compressionEngine *compression.Engine,
 ) (*LRUCache, error) {
-	lruHashicorp, err := lru.New(size)
+	return NewLRUCacheWithLogger(size, marshal, unmarshal, nil, compressionEngine)
+}
+
+// NewLRUCacheWithLogger is a constructor that creates LRU cache of given size with a logger
+// If logger is nil DummyLogger is used
+func NewLRUCacheWithLogger(
+	size int,
+	marshal func(value interface{}) ([]byte, error),
+	unmarshal func(b []byte, value *interface{}) error,
+	logger Logger,
+	compressionEngine *compression.Engine,
+) (*LRUCache, error) {
+	evictions := newEvictionTracker()
+	store, err := newLRUStore(size, evictions.onEvicted)
 	if err != nil {
 		return nil, err
 	}
 	return &LRUCache{
-		lru:               lruHashicorp,
+		store:             store,
+		size:              size,
 		marshal:           marshal,
 		unmarshal:         unmarshal,
 		compressionEngine: compressionEngine,
-		logger:            DummyLogger{},
+		logger:            loggerOrDefault(logger),
+		evictions:         evictions,
+		pinned:            make(map[string]interface{}),
 	}, nil
 }
 
-func NewLRUCacheWithLogger(
+// lfuAgingFactor multiplied by the size of NewLFUCache is the number of touches after which the frequencies are halved
+const lfuAgingFactor = 10
+
+// NewLFUCache is a constructor that creates an in-memory cache of given size evicting the least frequently used
+// entries, for workloads where recency is a poor predictor (e.g. periodic scans would flush the hot keys out
+// of an LRU cache). The frequencies age: they are halved after every 10*size reads and writes.
+// The other parameters are the same as of NewLRUCache.
+func NewLFUCache(
 	size int,
 	marshal func(value interface{}) ([]byte, error),
 	unmarshal func(b []byte, value *interface{}) error,
-	logger Logger,
 	compressionEngine *compression.Engine,
 ) (*LRUCache, error) {
-	lruHashicorp, err := lru.New(size)
+	lc, err := NewLRUCache(size, marshal, unmarshal, compressionEngine)
 	if err != nil {
 		return nil, err
 	}
-	return &LRUCache{
-		lru:               lruHashicorp,
-		marshal:           marshal,
-		unmarshal:         unmarshal,
-		compressionEngine: compressionEngine,
-		logger:            logger,
-	}, nil
+	return lc.WithEvictionPolicy(NewAgingLFUPolicy(lfuAgingFactor * size)), nil
+}
+
+// WithLogger sets the logger used by the cache; nil means DummyLogger
+func (lc *LRUCache) WithLogger(logger Logger) *LRUCache {
+	lc.logger = loggerOrDefault(logger)
+	return lc
+}
+
+// WithMaxValueSize limits the size of marshaled values (before compression).
+// Larger values are refused with ErrValueTooLarge, or not stored without an error if skip is true
+// (the previous value of the key is deleted then). The limit <= 0 means no limit. Values are marshaled only if the compression engine is set,
+// so the limit does not apply to caches without compression.
+func (lc *LRUCache) WithMaxValueSize(limit int, skip bool) *LRUCache {
+	lc.maxValueSize = maxValueSize{limit: limit, skip: skip}
+	return lc
+}
+
+// WithRawValues stores the payload of []byte, *[]byte and RawValue values compressed as they are instead of
+// marshaling them; Get returns the payloads as []byte, which must not be modified. Other values are refused
+// with ErrWrongDataType. Without the compression engine the values are stored as they are anyway.
+func (lc *LRUCache) WithRawValues() *LRUCache {
+	lc.rawValues = true
+	return lc
+}
+
+// WithClock sets the clock used to measure the age of evicted entries; nil means SystemClock
+func (lc *LRUCache) WithClock(clock Clock) *LRUCache {
+	lc.evictions.clock = clockOrDefault(clock)
+	return lc
+}
+
+// WithEvictionPolicy makes the cache evict the entries chosen by the policy instead of the least recently used
+// ones, e.g. NewLFUPolicy(), NewFIFOPolicy() or a custom one. The stored entries are kept.
+func (lc *LRUCache) WithEvictionPolicy(policy EvictionPolicy) *LRUCache {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	store := newPolicyStore(lc.size, policy, lc.evictions.onEvicted)
+	for _, key := range lc.store.Keys() {
+		if value, found := lc.store.Peek(key); found {
+			store.Add(key, value)
+		}
+	}
+	lc.store = store
+	return lc
+}
+
+// EvictionStats returns the statistics of the entries evicted because the cache was full
+func (lc *LRUCache) EvictionStats() EvictionStats {
+	return lc.evictions.stats()
+}
+
+// add stores the input into the lru cache, or replaces the pinned entry
+func (lc *LRUCache) add(key string, input interface{}) {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	if _, found := lc.pinned[key]; found {
+		lc.pinned[key] = input
+		return
+	}
+	lc.evictions.added(key)
+	if !lc.store.Add(key, input) {
+		lc.evictions.removed(key)
+	}
+}
+
+// lookup returns the stored input of the key; peek does not change its "lruness"
+func (lc *LRUCache) lookup(key string, peek bool) (interface{}, bool) {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	if input, found := lc.pinned[key]; found {
+		return input, true
+	}
+	if peek {
+		return lc.store.Peek(key)
+	}
+	return lc.store.Get(key)
 }
 
 // Get gets a value by given key
@@ -66,8 +168,9 @@ func (lc *LRUCache) Get(key string) (v interface{}, err error) {
 			err = fmt.Errorf("%v", r)
 			v = nil
 		}
+		err = wrapKeyError(OpGet, key, err)
 	}()
-	value, found := lc.lru.Get(key)
+	value, found := lc.lookup(key, false)
 	if !found {
 		return nil, ErrNotFound
 	}
@@ -87,7 +190,7 @@ func (lc *LRUCache) decompress(key string, value interface{}) (interface{}, erro
 	byteValue, ok := value.([]byte)
 	if !ok {
 		lc.Delete(key)
-		return nil, fmt.Errorf("data in cache are corrupted")
+		return nil, ErrCorrupted
 	}
 
 	input, err := lc.compressionEngine.Decompress(byteValue)
@@ -96,8 +199,10 @@ func (lc *LRUCache) decompress(key string, value interface{}) (interface{}, erro
 		return nil, err
 	}
 
-	var result interface{}
-	lc.unmarshal(input, &result)
+	result, err := unmarshalValue(lc.rawValues, lc.unmarshal, input)
+	if err != nil {
+		return nil, serializationError(err)
+	}
 	return result, nil
 }
 
@@ -108,8 +213,9 @@ func (lc *LRUCache) Peek(key string) (v interface{}, err error) {
 			err = fmt.Errorf("%v", r)
 			v = nil
 		}
+		err = wrapKeyError(OpPeek, key, err)
 	}()
-	value, found := lc.lru.Peek(key)
+	value, found := lc.lookup(key, true)
 	if !found {
 		return nil, ErrNotFound
 	}
@@ -126,50 +232,269 @@ func (lc *LRUCache) Peek(key string) (v interface{}, err error) {
 
 // Set stores given key-value pair into cache
 func (lc *LRUCache) Set(key string, value interface{}) (err error) {
+	defer func() {
+		err = wrapKeyError(OpSet, key, err)
+	}()
+
+	input, store, err := lc.encode(key, value)
+	if store {
+		lc.add(key, input)
+	} else if err == nil {
+		// the value was skipped by WithMaxValueSize, the previous one must not be read anymore
+		lc.Delete(key)
+	}
+	return err
+}
+
+// encode returns the value to be stored (compressed if the compression engine is set);
+// store is false if the value must not be stored
+func (lc *LRUCache) encode(key string, value interface{}) (input interface{}, store bool, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("%v", r)
+			input, store, err = nil, false, fmt.Errorf("%v", r)
 		}
 	}()
 	if lc.compressionEngine == nil {
-		lc.lru.Add(key, value)
-		return nil
+		return value, true, nil
 	}
 
-	marshalledValue, err := lc.marshal(value)
-	if err != nil {
+	marshalledValue, err := marshalValue(lc.rawValues, lc.marshal, value)
+	if errors.Is(err, ErrWrongDataType) {
+		return nil, false, err
+	} else if err != nil {
 		lc.logger.Error("lru: error marshaling data: ", err)
-		return err
+		return nil, false, serializationError(err)
+	}
+	if store, err := lc.maxValueSize.check(lc.logger, "lru", key, len(marshalledValue)); !store {
+		return nil, false, err
 	}
 
-	input, err := lc.compressionEngine.Compress(marshalledValue)
+	compressed, err := lc.compressionEngine.Compress(marshalledValue)
 	if err != nil {
 		lc.logger.Error("lru: error compressing data: ", err)
-		return err
+		return nil, false, err
+	}
+	return compressed, true, nil
+}
+
+// SetMulti stores all the key-value pairs; if any value cannot be encoded nothing is stored
+func (lc *LRUCache) SetMulti(values map[string]interface{}) error {
+	inputs := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		input, store, err := lc.encode(key, value)
+		if err != nil {
+			return wrapKeyError(OpSet, key, err)
+		}
+		if store {
+			inputs[key] = input
+		}
+	}
+	for key := range values {
+		if input, found := inputs[key]; found {
+			lc.add(key, input)
+		} else {
+			lc.Delete(key)
+		}
 	}
-	lc.lru.Add(key, input)
 	return nil
 }
 
-// Delete removes a key from cache
+// ExportEntry returns the stored payload of the key.
+// Values of caches without compression are marshaled, so marshal must be provided.
+func (lc *LRUCache) ExportEntry(key string) (*ExportedEntry, error) {
+	value, found := lc.lookup(key, true)
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	if lc.compressionEngine != nil {
+		payload, ok := value.([]byte)
+		if !ok {
+			return nil, ErrCorrupted
+		}
+		return &ExportedEntry{Key: key, Payload: payload}, nil
+	}
+
+	if lc.marshal == nil {
+		return nil, ErrExportNotSupported
+	}
+	payload, err := lc.marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportedEntry{Key: key, Payload: payload}, nil
+}
+
+// ImportEntry stores the payload; TTL is ignored as LRUCache does not support expiration.
+// Caches without compression refuse the entries with ErrExportNotSupported, as they store the values
+// themselves and the payload cannot be decoded to the type of the values stored by Cache.
+func (lc *LRUCache) ImportEntry(entry *ExportedEntry) error {
+	if lc.compressionEngine == nil {
+		return ErrExportNotSupported
+	}
+	lc.add(entry.Key, entry.Payload)
+	return nil
+}
+
+// Delete removes a key from cache (also a pinned one)
 func (lc *LRUCache) Delete(key string) error {
-	lc.lru.Remove(key)
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	delete(lc.pinned, key)
+	lc.evictions.removed(key)
+	lc.store.Remove(key)
 	return nil
 }
 
 // Keys returns all the keys in cache
 func (lc *LRUCache) Keys() ([]string, error) {
-	lruKeys := lc.lru.Keys()
-	keys := make([]string, 0, len(lruKeys))
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	keys := lc.store.Keys()
+	for key := range lc.pinned {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Count returns the number of stored keys
+func (lc *LRUCache) Count() (int, error) {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	return lc.store.Len() + len(lc.pinned), nil
+}
 
-	for i := 0; i < len(lruKeys); i++ {
-		keys = append(keys, lruKeys[i].(string))
+// KeysPredicate returns the keys satisfying the given predicate
+func (lc *LRUCache) KeysPredicate(pred Predicate) ([]string, error) {
+	allKeys, err := lc.Keys()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for _, key := range allKeys {
+		if pred(key) {
+			keys = append(keys, key)
+		}
 	}
 	return keys, nil
 }
 
-// Purge removes all records from the cache
+// RangeKeys calls fn for every key in cache until fn returns false
+func (lc *LRUCache) RangeKeys(fn func(key string) bool) error {
+	keys, err := lc.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !fn(key) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Purge removes all records from the cache (also the pinned ones)
 func (lc *LRUCache) Purge() error {
-	lc.lru.Purge()
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	lc.pinned = make(map[string]interface{})
+	lc.evictions.purged()
+	lc.store.Purge()
+	return nil
+}
+
+// Pin moves the entry of the key out of the lru, so it is never evicted; pinned entries do not count
+// towards the size of the cache
+func (lc *LRUCache) Pin(key string) error {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	if _, found := lc.pinned[key]; found {
+		return nil
+	}
+	input, found := lc.store.Peek(key)
+	if !found {
+		return ErrNotFound
+	}
+	storedAt := lc.evictions.storedAtOf(key)
+	lc.evictions.removed(key)
+	lc.store.Remove(key)
+	lc.evictions.restored(key, storedAt)
+	lc.pinned[key] = input
+	return nil
+}
+
+// Unpin moves the pinned entry of the key back to the lru as the most recently used one
+func (lc *LRUCache) Unpin(key string) error {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	input, found := lc.pinned[key]
+	if !found {
+		return nil
+	}
+	delete(lc.pinned, key)
+	lc.store.Add(key, input)
+	return nil
+}
+
+// Pinned reports whether the key is pinned
+func (lc *LRUCache) Pinned(key string) bool {
+	lc.pinnedMutex.Lock()
+	defer lc.pinnedMutex.Unlock()
+	_, found := lc.pinned[key]
+	return found
+}
+
+// Ping always succeeds as LRUCache is in memory
+func (lc *LRUCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Inspect returns the metadata of the entry; sizes and compression are known only with compression enabled
+func (lc *LRUCache) Inspect(key string) (EntryInfo, error) {
+	value, found := lc.lookup(key, true)
+	if !found {
+		return EntryInfo{}, ErrNotFound
+	}
+
+	info := EntryInfo{StoredAt: lc.evictions.storedAtOf(key), Source: SourceEngine}
+	if lc.compressionEngine != nil {
+		payload, ok := value.([]byte)
+		if !ok {
+			return EntryInfo{}, ErrCorrupted
+		}
+		providerID, size, err := lc.compressionEngine.InspectFooter(payload, len(payload))
+		if err != nil {
+			return EntryInfo{}, err
+		}
+		info.Size = len(payload)
+		info.UncompressedSize = size
+		info.Compression = compression.GetProviderName(providerID)
+	}
+	return info, nil
+}
+
+// GetRaw gets the stored payload; it is supported only with compression enabled, as values
+// of caches without compression are stored as they are
+func (lc *LRUCache) GetRaw(key string) ([]byte, error) {
+	if lc.compressionEngine == nil {
+		return nil, ErrRawNotSupported
+	}
+	value, found := lc.lookup(key, true)
+	if !found {
+		return nil, ErrNotFound
+	}
+	payload, ok := value.([]byte)
+	if !ok {
+		return nil, ErrCorrupted
+	}
+	return payload, nil
+}
+
+// SetRaw stores the payload as it is; it is supported only with compression enabled
+func (lc *LRUCache) SetRaw(key string, payload []byte) error {
+	if lc.compressionEngine == nil {
+		return ErrRawNotSupported
+	}
+	lc.add(key, payload)
 	return nil
 }
@@ -0,0 +1,318 @@
+package cachier
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	value     interface{}
+	storedAt  time.Time
+	expiresAt time.Time
+	version   uint64
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+// MemoryCache is a simple map based CacheEngine with optional TTL.
+// It has no size bound and no dependencies, which makes it handy for tests and small applications.
+type MemoryCache struct {
+	items   map[string]memoryItem
+	ttl     time.Duration
+	clock   Clock
+	version uint64
+	mutex   sync.RWMutex
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+// NewMemoryCache is a constructor that creates a MemoryCache.
+// If ttl > 0 entries expire after ttl. If cleanupInterval > 0 a janitor goroutine
+// removes expired entries periodically; call Close to stop it.
+func NewMemoryCache(ttl time.Duration, cleanupInterval time.Duration) *MemoryCache {
+	return NewMemoryCacheWithClock(ttl, cleanupInterval, nil)
+}
+
+// NewMemoryCacheWithClock is a constructor that creates a MemoryCache using the given Clock
+// If clock is nil SystemClock is used
+func NewMemoryCacheWithClock(ttl time.Duration, cleanupInterval time.Duration, clock Clock) *MemoryCache {
+	mc := &MemoryCache{
+		items: make(map[string]memoryItem),
+		ttl:   ttl,
+		clock: clockOrDefault(clock),
+		stop:  make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go mc.janitor(mc.clock.NewTicker(cleanupInterval))
+	}
+
+	return mc
+}
+
+func (mc *MemoryCache) janitor(ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			mc.DeleteExpired()
+		case <-mc.stop:
+			return
+		}
+	}
+}
+
+// Close stops the janitor goroutine
+func (mc *MemoryCache) Close() {
+	mc.stopped.Do(func() {
+		close(mc.stop)
+	})
+}
+
+// DeleteExpired removes all expired entries
+func (mc *MemoryCache) DeleteExpired() {
+	now := mc.clock.Now()
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	for key, item := range mc.items {
+		if item.expired(now) {
+			delete(mc.items, key)
+		}
+	}
+}
+
+// Len returns the number of stored entries including the expired ones not removed yet
+func (mc *MemoryCache) Len() int {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	return len(mc.items)
+}
+
+// Get gets a value by given key
+func (mc *MemoryCache) Get(key string) (interface{}, error) {
+	mc.mutex.RLock()
+	item, found := mc.items[key]
+	mc.mutex.RUnlock()
+	if !found {
+		return nil, &KeyError{Key: key, Op: OpGet, Err: ErrNotFound}
+	}
+	if now := mc.clock.Now(); item.expired(now) {
+		mc.deleteExpired(key, item.version, now)
+		return nil, &KeyError{Key: key, Op: OpGet, Err: ErrNotFound}
+	}
+	return item.value, nil
+}
+
+// deleteExpired removes the expired item with the given version; a value stored meanwhile by another writer
+// has a new version and is kept
+func (mc *MemoryCache) deleteExpired(key string, version uint64, now time.Time) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if current, found := mc.items[key]; found && current.version == version && current.expired(now) {
+		delete(mc.items, key)
+	}
+}
+
+// SetMulti stores all the key-value pairs at once
+func (mc *MemoryCache) SetMulti(values map[string]interface{}) error {
+	now := mc.clock.Now()
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	for key, value := range values {
+		item := memoryItem{value: value, storedAt: now}
+		if mc.ttl > 0 {
+			item.expiresAt = now.Add(mc.ttl)
+		}
+		mc.store(key, item)
+	}
+	return nil
+}
+
+// TTL returns the remaining time to live of the key; 0 means the key does not expire
+func (mc *MemoryCache) TTL(key string) (time.Duration, error) {
+	now := mc.clock.Now()
+	mc.mutex.RLock()
+	item, found := mc.items[key]
+	mc.mutex.RUnlock()
+	if !found || item.expired(now) {
+		return 0, ErrNotFound
+	}
+	if item.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return item.expiresAt.Sub(now), nil
+}
+
+// Age returns how long ago the key was stored
+func (mc *MemoryCache) Age(key string) (time.Duration, error) {
+	now := mc.clock.Now()
+	mc.mutex.RLock()
+	item, found := mc.items[key]
+	mc.mutex.RUnlock()
+	if !found || item.expired(now) {
+		return 0, ErrNotFound
+	}
+	return now.Sub(item.storedAt), nil
+}
+
+// Peek gets a value by given key (identical as Get in this implementation)
+func (mc *MemoryCache) Peek(key string) (interface{}, error) {
+	return mc.Get(key)
+}
+
+// Set stores given key-value pair into cache
+func (mc *MemoryCache) Set(key string, value interface{}) error {
+	return mc.SetWithTTL(key, value, mc.ttl)
+}
+
+// SetWithTTL stores given key-value pair which expires after ttl instead of the TTL of the cache
+func (mc *MemoryCache) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	item := memoryItem{value: value, storedAt: mc.clock.Now()}
+	if ttl > 0 {
+		item.expiresAt = item.storedAt.Add(ttl)
+	}
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mc.store(key, item)
+	return nil
+}
+
+// store stores the item with a new version; the mutex must be held
+func (mc *MemoryCache) store(key string, item memoryItem) {
+	mc.version++
+	item.version = mc.version
+	mc.items[key] = item
+}
+
+// newItem creates an item stored now with the TTL of the cache
+func (mc *MemoryCache) newItem(value interface{}) memoryItem {
+	item := memoryItem{value: value, storedAt: mc.clock.Now()}
+	if mc.ttl > 0 {
+		item.expiresAt = item.storedAt.Add(mc.ttl)
+	}
+	return item
+}
+
+// SetIfAbsent stores the value only if the key does not exist
+func (mc *MemoryCache) SetIfAbsent(key string, value interface{}) (bool, error) {
+	item := mc.newItem(value)
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if current, found := mc.items[key]; found && !current.expired(item.storedAt) {
+		return false, nil
+	}
+	mc.store(key, item)
+	return true, nil
+}
+
+// GetWithVersion returns the value and the version of the entry, which changes on every write
+func (mc *MemoryCache) GetWithVersion(key string) (interface{}, string, error) {
+	now := mc.clock.Now()
+	mc.mutex.RLock()
+	item, found := mc.items[key]
+	mc.mutex.RUnlock()
+	if !found || item.expired(now) {
+		return nil, "", &KeyError{Key: key, Op: OpGet, Err: ErrNotFound}
+	}
+	return item.value, strconv.FormatUint(item.version, 10), nil
+}
+
+// CompareAndSwap stores the value only if the entry still has the given version
+func (mc *MemoryCache) CompareAndSwap(key string, version string, value interface{}) (bool, error) {
+	item := mc.newItem(value)
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	current, found := mc.items[key]
+	if !found || current.expired(item.storedAt) || strconv.FormatUint(current.version, 10) != version {
+		return false, nil
+	}
+	mc.store(key, item)
+	return true, nil
+}
+
+// GetAndTouch returns the value and sets its expiration to ttl (ttl <= 0 removes the expiration)
+func (mc *MemoryCache) GetAndTouch(key string, ttl time.Duration) (interface{}, error) {
+	now := mc.clock.Now()
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	item, found := mc.items[key]
+	if !found || item.expired(now) {
+		return nil, &KeyError{Key: key, Op: OpGet, Err: ErrNotFound}
+	}
+	item.expiresAt = time.Time{}
+	if ttl > 0 {
+		item.expiresAt = now.Add(ttl)
+	}
+	mc.items[key] = item
+	return item.value, nil
+}
+
+// Delete removes a key from cache
+func (mc *MemoryCache) Delete(key string) error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	delete(mc.items, key)
+	return nil
+}
+
+// Keys returns all the not expired keys in cache
+func (mc *MemoryCache) Keys() ([]string, error) {
+	now := mc.clock.Now()
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	keys := make([]string, 0, len(mc.items))
+	for key, item := range mc.items {
+		if !item.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Count returns the number of keys which have not expired
+func (mc *MemoryCache) Count() (int, error) {
+	now := mc.clock.Now()
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	count := 0
+	for _, item := range mc.items {
+		if !item.expired(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Purge removes all records from the cache
+func (mc *MemoryCache) Purge() error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mc.items = make(map[string]memoryItem)
+	return nil
+}
+
+// Ping always succeeds as MemoryCache is in memory
+func (mc *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Inspect returns the metadata of the entry; values are stored as they are, so sizes are not known
+func (mc *MemoryCache) Inspect(key string) (EntryInfo, error) {
+	now := mc.clock.Now()
+	mc.mutex.RLock()
+	item, found := mc.items[key]
+	mc.mutex.RUnlock()
+	if !found || item.expired(now) {
+		return EntryInfo{}, ErrNotFound
+	}
+
+	info := EntryInfo{StoredAt: item.storedAt, Source: SourceEngine}
+	if !item.expiresAt.IsZero() {
+		info.TTL = item.expiresAt.Sub(now)
+	}
+	return info, nil
+}
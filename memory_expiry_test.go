@@ -0,0 +1,27 @@
+package cachier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCacheDeleteExpired(t *testing.T) {
+	mc := NewMemoryCache(0, 0)
+	require.Nil(t, mc.SetWithTTL("key", "old", time.Millisecond))
+	expired := mc.items["key"]
+	now := expired.expiresAt
+
+	// a value stored after the expired one was read is not removed
+	require.Nil(t, mc.Set("key", "new"))
+	mc.deleteExpired("key", expired.version, now)
+	value, err := mc.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, "new", value)
+
+	require.Nil(t, mc.SetWithTTL("key", "old", time.Millisecond))
+	mc.deleteExpired("key", mc.items["key"].version, now.Add(time.Hour))
+	assert.Equal(t, 0, mc.Len())
+}
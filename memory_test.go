@@ -0,0 +1,57 @@
+package cachier_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCacheTTL(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	mc := cachier.NewMemoryCacheWithClock(time.Minute, 0, clock)
+	cache := cachier.MakeCache[string](mc)
+
+	value := "value"
+	require.Nil(t, cache.Set("key", &value))
+	output, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *output)
+
+	clock.Advance(time.Minute)
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	assert.Equal(t, 0, mc.Len())
+}
+
+func TestMemoryCacheJanitor(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	mc := cachier.NewMemoryCacheWithClock(time.Minute, time.Second, clock)
+	defer mc.Close()
+
+	require.Nil(t, mc.Set("a", 1))
+	require.Nil(t, mc.Set("b", 2))
+	keys, err := mc.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+
+	clock.Advance(time.Minute)
+	keys, err = mc.Keys()
+	require.Nil(t, err)
+	assert.Empty(t, keys)
+	assert.Eventually(t, func() bool { return mc.Len() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestMemoryCacheWithoutTTL(t *testing.T) {
+	mc := cachier.NewMemoryCache(0, 0)
+	require.Nil(t, mc.Set("a", 1))
+	value, err := mc.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, 1, value)
+	require.Nil(t, mc.Purge())
+	_, err = mc.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
@@ -0,0 +1,72 @@
+package cachier
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrMemoryUsageNotSupported is returned by MemoryUsage and TopKeysBySize of engines which do not report memory usage
+var ErrMemoryUsageNotSupported = errors.New("engine does not report memory usage")
+
+// KeySize is the memory used by a key of the engine
+type KeySize struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// MemoryUsage returns the number of bytes the engine uses to store the key
+func (c *Cache[T]) MemoryUsage(key string) (int64, error) {
+	reporter, ok := c.engine.(MemoryReporter)
+	if !ok {
+		return 0, wrapKeyError(OpGet, key, ErrMemoryUsageNotSupported)
+	}
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return 0, wrapKeyError(OpGet, key, err)
+	}
+	size, err := reporter.MemoryUsage(engineKey)
+	return size, wrapKeyError(OpGet, key, err)
+}
+
+// TopKeysBySize returns at most n keys using the most memory, largest first.
+// The keys are the keys of the engine, as listed by Keys.
+func (c *Cache[T]) TopKeysBySize(n int) ([]KeySize, error) {
+	reporter, ok := c.engine.(MemoryReporter)
+	if !ok {
+		return nil, ErrMemoryUsageNotSupported
+	}
+	return reporter.TopKeysBySize(n)
+}
+
+// topKeySizes collects the n largest key sizes
+type topKeySizes struct {
+	n     int
+	sizes []KeySize
+}
+
+// add records the size of the key
+func (t *topKeySizes) add(size KeySize) {
+	t.sizes = append(t.sizes, size)
+	// the collected sizes are trimmed only occasionally to keep adding cheap
+	if len(t.sizes) >= 2*t.n+scanCount {
+		t.trim()
+	}
+}
+
+func (t *topKeySizes) trim() {
+	sort.Slice(t.sizes, func(i, j int) bool {
+		if t.sizes[i].Size != t.sizes[j].Size {
+			return t.sizes[i].Size > t.sizes[j].Size
+		}
+		return t.sizes[i].Key < t.sizes[j].Key
+	})
+	if len(t.sizes) > t.n {
+		t.sizes = t.sizes[:t.n]
+	}
+}
+
+// top returns the n largest sizes, largest first
+func (t *topKeySizes) top() []KeySize {
+	t.trim()
+	return t.sizes
+}
@@ -0,0 +1,399 @@
+//go:build unix
+
+package cachier
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sync"
+
+	"github.com/datasapiens/cachier/compression"
+	"golang.org/x/sys/unix"
+)
+
+// MmapCache is a CacheEngine storing the entries in a memory-mapped file, so caches larger than RAM can be kept
+// on disk and survive restarts; the kernel keeps the hot pages in memory. The file is divided into slots of equal
+// size, each holding one entry (key and encoded value), so it suits read-mostly caches of similar sized values.
+// Writes reach the file when the kernel writes the pages back, Flush forces it (msync).
+// The file must not be used by several processes at once.
+type MmapCache struct {
+	file              *os.File
+	data              []byte
+	slotSize          int
+	slotCount         int
+	marshal           func(value interface{}) ([]byte, error)
+	unmarshal         func(b []byte, value *interface{}) error
+	compressionEngine *compression.Engine
+	logger            Logger
+
+	mutex sync.RWMutex
+	// index maps the keys to their slots
+	index map[string]int
+	// free holds the free slots
+	free []int
+	// seq is the sequence number of the last write
+	seq uint32
+}
+
+// NewMmapCache opens (or creates) the file at path with slotCount slots of slotSize bytes and loads the index
+// of the stored entries. An existing file must have the same layout. Values are marshaled and optionally
+// compressed (nil compression engine means no compression); an entry must fit into a slot
+// (slotSize - 16 bytes for the key and the value), larger ones are refused with ErrValueTooLarge.
+func NewMmapCache(
+	path string,
+	slotSize int,
+	slotCount int,
+	marshal func(value interface{}) ([]byte, error),
+	unmarshal func(b []byte, value *interface{}) error,
+	compressionEngine *compression.Engine,
+) (*MmapCache, error) {
+	if slotSize <= mmapSlotHeaderSize || slotSize > mmapMaxSlotSize || slotCount <= 0 {
+		return nil, fmt.Errorf("%w: %d slots of %d bytes", ErrMmapLayout, slotCount, slotSize)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	mc := &MmapCache{
+		file:              file,
+		slotSize:          slotSize,
+		slotCount:         slotCount,
+		marshal:           marshal,
+		unmarshal:         unmarshal,
+		compressionEngine: compressionEngine,
+		logger:            loggerOrDefault(nil),
+		index:             make(map[string]int),
+	}
+	if err := mc.open(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return mc, nil
+}
+
+var (
+	// ErrMmapLayout is returned by NewMmapCache for invalid layouts or files with another layout
+	ErrMmapLayout = errors.New("invalid memory-mapped file layout")
+	// ErrMmapFull is returned by MmapCache.Set when all the slots are used
+	ErrMmapFull = errors.New("memory-mapped file is full")
+)
+
+const (
+	mmapMagic      = "CACHIERM"
+	mmapVersion    = 1
+	mmapHeaderSize = 64
+	// the slot header holds the state (1 byte), padding (1 byte), the key length (2 bytes), the value length
+	// (4 bytes), the CRC-32 of the key and the value (4 bytes) and the sequence number of the write (4 bytes)
+	mmapSlotHeaderSize = 16
+	mmapMaxSlotSize    = 1 << 30
+	mmapSlotFree       = 0
+	mmapSlotUsed       = 1
+)
+
+// WithLogger sets the logger used by the cache; nil means DummyLogger
+func (mc *MmapCache) WithLogger(logger Logger) *MmapCache {
+	mc.logger = loggerOrDefault(logger)
+	return mc
+}
+
+// open maps the file, writing the header of a new file, and indexes the stored entries;
+// slots which fail their checksum (e.g. torn by a crash) are freed
+func (mc *MmapCache) open() error {
+	info, err := mc.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := int64(mmapHeaderSize) + int64(mc.slotSize)*int64(mc.slotCount)
+	created := info.Size() == 0
+	if created {
+		if err := mc.file.Truncate(size); err != nil {
+			return err
+		}
+	} else if info.Size() != size {
+		return fmt.Errorf("%w: file has %d bytes, expected %d", ErrMmapLayout, info.Size(), size)
+	}
+
+	mc.data, err = unix.Mmap(int(mc.file.Fd()), 0, int(size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	header := mc.data[:mmapHeaderSize]
+	if created {
+		copy(header, mmapMagic)
+		binary.LittleEndian.PutUint32(header[8:], mmapVersion)
+		binary.LittleEndian.PutUint32(header[12:], uint32(mc.slotSize))
+		binary.LittleEndian.PutUint32(header[16:], uint32(mc.slotCount))
+	} else if string(header[:8]) != mmapMagic ||
+		binary.LittleEndian.Uint32(header[8:]) != mmapVersion ||
+		binary.LittleEndian.Uint32(header[12:]) != uint32(mc.slotSize) ||
+		binary.LittleEndian.Uint32(header[16:]) != uint32(mc.slotCount) {
+		unix.Munmap(mc.data)
+		return fmt.Errorf("%w: header does not match", ErrMmapLayout)
+	}
+
+	for slot := mc.slotCount - 1; slot >= 0; slot-- {
+		key, _, ok := mc.read(slot)
+		if !ok {
+			mc.release(slot)
+			continue
+		}
+		seq := mc.seqOf(slot)
+		if seq > mc.seq {
+			mc.seq = seq
+		}
+		if previous, found := mc.index[key]; found {
+			// a crash while moving the entry left two copies, the older one is dropped
+			if mc.seqOf(previous) > seq {
+				mc.release(slot)
+				continue
+			}
+			mc.release(previous)
+		}
+		mc.index[key] = slot
+	}
+	return nil
+}
+
+// release marks the slot free
+func (mc *MmapCache) release(slot int) {
+	mc.slot(slot)[0] = mmapSlotFree
+	mc.free = append(mc.free, slot)
+}
+
+// seqOf returns the sequence number of the write of the slot
+func (mc *MmapCache) seqOf(slot int) uint32 {
+	return binary.LittleEndian.Uint32(mc.slot(slot)[12:])
+}
+
+// slot returns the bytes of the slot
+func (mc *MmapCache) slot(slot int) []byte {
+	start := mmapHeaderSize + slot*mc.slotSize
+	return mc.data[start : start+mc.slotSize]
+}
+
+// read returns the key and the payload of the used slot; ok is false for free and corrupted slots
+func (mc *MmapCache) read(slot int) (key string, payload []byte, ok bool) {
+	b := mc.slot(slot)
+	if b[0] != mmapSlotUsed {
+		return "", nil, false
+	}
+	keyLen := int(binary.LittleEndian.Uint16(b[2:]))
+	valueLen := int(binary.LittleEndian.Uint32(b[4:]))
+	if mmapSlotHeaderSize+keyLen+valueLen > len(b) {
+		return "", nil, false
+	}
+	body := b[mmapSlotHeaderSize : mmapSlotHeaderSize+keyLen+valueLen]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(b[8:]) {
+		return "", nil, false
+	}
+	return string(body[:keyLen]), body[keyLen:], true
+}
+
+// write fills the slot with the entry; the state is written last, so a torn write leaves the slot free
+// or fails the checksum
+func (mc *MmapCache) write(slot int, key string, payload []byte) {
+	b := mc.slot(slot)
+	b[0] = mmapSlotFree
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(key)))
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(payload)))
+	body := b[mmapSlotHeaderSize : mmapSlotHeaderSize+len(key)+len(payload)]
+	copy(body, key)
+	copy(body[len(key):], payload)
+	binary.LittleEndian.PutUint32(b[8:], crc32.ChecksumIEEE(body))
+	mc.seq++
+	binary.LittleEndian.PutUint32(b[12:], mc.seq)
+	b[0] = mmapSlotUsed
+}
+
+// Get gets a value by given key
+func (mc *MmapCache) Get(key string) (interface{}, error) {
+	return mc.get(OpGet, key)
+}
+
+// Peek gets a value by given key; it is the same as Get
+func (mc *MmapCache) Peek(key string) (interface{}, error) {
+	return mc.get(OpPeek, key)
+}
+
+func (mc *MmapCache) get(op string, key string) (interface{}, error) {
+	mc.mutex.RLock()
+	slot, found := mc.index[key]
+	if !found {
+		mc.mutex.RUnlock()
+		return nil, wrapKeyError(op, key, ErrNotFound)
+	}
+	_, payload, ok := mc.read(slot)
+	seq := mc.seqOf(slot)
+	// the payload is copied, as the slot can be overwritten once the lock is released
+	payload = append([]byte(nil), payload...)
+	mc.mutex.RUnlock()
+	if !ok {
+		mc.deleteSlot(key, slot, seq)
+		return nil, wrapKeyError(op, key, ErrCorrupted)
+	}
+	value, err := mc.decode(key, payload, func() { mc.deleteSlot(key, slot, seq) })
+	return value, wrapKeyError(op, key, err)
+}
+
+// deleteSlot removes the key if it is still stored in the slot by the write with the sequence number seq,
+// so an entry written after the slot was read is not removed
+func (mc *MmapCache) deleteSlot(key string, slot int, seq uint32) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if current, found := mc.index[key]; found && current == slot && mc.seqOf(slot) == seq {
+		mc.release(slot)
+		delete(mc.index, key)
+	}
+}
+
+// decode decompresses and unmarshals the payload; remove is called if the payload cannot be decompressed
+func (mc *MmapCache) decode(key string, payload []byte, remove func()) (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+			v = nil
+		}
+	}()
+	input := payload
+	if mc.compressionEngine != nil {
+		if input, err = mc.compressionEngine.Decompress(payload); err != nil {
+			mc.logger.Error("mmap: error decompressing data with key: ", key, " error: ", err)
+			remove()
+			return nil, err
+		}
+	}
+	var result interface{}
+	if err := mc.unmarshal(input, &result); err != nil {
+		mc.logger.Error("mmap: error unmarshaling data with key: ", key, " error: ", err)
+		return nil, serializationError(err)
+	}
+	return result, nil
+}
+
+// Set stores given key-value pair into the file. A new entry is written into a free slot before the old one
+// is freed, so a crash does not lose the old value.
+func (mc *MmapCache) Set(key string, value interface{}) (err error) {
+	defer func() {
+		err = wrapKeyError(OpSet, key, err)
+	}()
+	payload, err := mc.encode(key, value)
+	if err != nil {
+		return err
+	}
+
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if len(mc.free) == 0 {
+		previous, found := mc.index[key]
+		if !found {
+			return ErrMmapFull
+		}
+		// no free slot to write into first, the entry is overwritten in place
+		mc.write(previous, key, payload)
+		return nil
+	}
+	slot := mc.free[len(mc.free)-1]
+	mc.free = mc.free[:len(mc.free)-1]
+	mc.write(slot, key, payload)
+	if previous, found := mc.index[key]; found {
+		mc.release(previous)
+	}
+	mc.index[key] = slot
+	return nil
+}
+
+// encode marshals and compresses the value and checks it fits into a slot
+func (mc *MmapCache) encode(key string, value interface{}) (payload []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			payload, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	marshalled, err := mc.marshal(value)
+	if err != nil {
+		mc.logger.Error("mmap: error marshaling data: ", err)
+		return nil, serializationError(err)
+	}
+	payload = marshalled
+	if mc.compressionEngine != nil {
+		if payload, err = mc.compressionEngine.Compress(marshalled); err != nil {
+			mc.logger.Error("mmap: error compressing data: ", err)
+			return nil, err
+		}
+	}
+	if len(key) > 0xffff || mmapSlotHeaderSize+len(key)+len(payload) > mc.slotSize {
+		mc.logger.Warn("mmap: value of key ", key, " too large: ", len(payload), " bytes")
+		return nil, ErrValueTooLarge
+	}
+	return payload, nil
+}
+
+// Delete removes a key from the file
+func (mc *MmapCache) Delete(key string) error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if slot, found := mc.index[key]; found {
+		mc.release(slot)
+		delete(mc.index, key)
+	}
+	return nil
+}
+
+// Keys returns all the keys in the file
+func (mc *MmapCache) Keys() ([]string, error) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	keys := make([]string, 0, len(mc.index))
+	for key := range mc.index {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Count returns the number of stored keys
+func (mc *MmapCache) Count() (int, error) {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	return len(mc.index), nil
+}
+
+// Purge removes all the entries from the file
+func (mc *MmapCache) Purge() error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	for _, slot := range mc.index {
+		mc.release(slot)
+	}
+	mc.index = make(map[string]int)
+	return nil
+}
+
+// Flush writes the modified pages to the file and waits until they are written (msync)
+func (mc *MmapCache) Flush() error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return unix.Msync(mc.data, unix.MS_SYNC)
+}
+
+// Close flushes and unmaps the file and closes it; the cache must not be used afterwards
+func (mc *MmapCache) Close() error {
+	if err := mc.Flush(); err != nil {
+		return err
+	}
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if err := unix.Munmap(mc.data); err != nil {
+		return err
+	}
+	mc.data = nil
+	return mc.file.Close()
+}
+
+// Ping always succeeds as the file is mapped into memory
+func (mc *MmapCache) Ping(ctx context.Context) error {
+	return nil
+}
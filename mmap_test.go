@@ -0,0 +1,109 @@
+//go:build unix
+
+package cachier
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMmapCache(t *testing.T, path string, slotCount int, compressionEngine *compression.Engine) *MmapCache {
+	mc, err := NewMmapCache(path, 128, slotCount, json.Marshal, JSONCodec[string]{}.Unmarshal, compressionEngine)
+	require.Nil(t, err)
+	return mc
+}
+
+func TestMmapCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	mc := newTestMmapCache(t, path, 3, nil)
+	cache := MakeCache[string](mc)
+
+	a, b := "a", "b"
+	require.Nil(t, cache.Set("a", &a))
+	require.Nil(t, cache.Set("b", &b))
+	require.Nil(t, cache.Set("b", &a))
+	got, err := cache.Get("b")
+	require.Nil(t, err)
+	assert.Equal(t, "a", *got)
+	require.Nil(t, cache.Set("c", &a))
+	assert.ErrorIs(t, cache.Set("d", &a), ErrMmapFull)
+	require.Nil(t, cache.Delete("c"))
+	long := strings.Repeat("x", 200)
+	assert.ErrorIs(t, cache.Set("long", &long), ErrValueTooLarge)
+	count, err := cache.Count()
+	require.Nil(t, err)
+	assert.Equal(t, 2, count)
+	require.Nil(t, mc.Close())
+
+	// the entries survive reopening
+	mc = newTestMmapCache(t, path, 3, nil)
+	cache = MakeCache[string](mc)
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+	got, err = cache.Get("b")
+	require.Nil(t, err)
+	assert.Equal(t, "a", *got)
+	require.Nil(t, cache.Purge())
+	_, err = cache.Get("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.Nil(t, mc.Close())
+
+	_, err = NewMmapCache(path, 64, 3, json.Marshal, JSONCodec[string]{}.Unmarshal, nil)
+	assert.ErrorIs(t, err, ErrMmapLayout)
+}
+
+func TestMmapCacheCorruptedSlot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+	engine, err := compression.NewEngine(compression.ProviderIDS2, compression.CompressionParams{compression.CompressionParamMinInputLen: 0})
+	require.Nil(t, err)
+	mc := newTestMmapCache(t, path, 2, engine)
+	for _, key := range []string{"a", "b"} {
+		require.Nil(t, mc.Set(key, key))
+	}
+	require.Nil(t, mc.Flush())
+	slot := mc.index["a"]
+	require.Nil(t, mc.Close())
+
+	// a torn write of "a" fails the checksum, the slot is freed on open
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	data[mmapHeaderSize+slot*128+mmapSlotHeaderSize] ^= 0xff
+	require.Nil(t, os.WriteFile(path, data, 0o600))
+
+	mc = newTestMmapCache(t, path, 2, engine)
+	defer mc.Close()
+	_, err = mc.Get("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	value, err := mc.Get("b")
+	require.Nil(t, err)
+	assert.Equal(t, "b", *value.(*string))
+	require.Nil(t, mc.Set("c", "c"))
+}
+
+func TestMmapCacheDeleteSlot(t *testing.T) {
+	mc := newTestMmapCache(t, filepath.Join(t.TempDir(), "cache"), 3, nil)
+	defer mc.Close()
+	require.Nil(t, mc.Set("key", "old"))
+	slot := mc.index["key"]
+	seq := mc.seqOf(slot)
+
+	// an entry written after the corrupted slot was read is not removed
+	require.Nil(t, mc.Set("key", "new"))
+	mc.deleteSlot("key", slot, seq)
+	value, err := mc.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, "new", *value.(*string))
+
+	slot = mc.index["key"]
+	mc.deleteSlot("key", slot, mc.seqOf(slot))
+	_, err = mc.Get("key")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
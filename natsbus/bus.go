@@ -0,0 +1,55 @@
+// Package natsbus provides a cachier.InvalidationBus using NATS, so the invalidations can be distributed
+// by an existing NATS deployment instead of redis pub/sub.
+package natsbus
+
+import (
+	"encoding/json"
+
+	"github.com/datasapiens/cachier"
+	"github.com/nats-io/nats.go"
+)
+
+// Bus is a cachier.InvalidationBus publishing the invalidations as JSON messages on a NATS subject.
+// As with core NATS in general, messages published while a subscriber is disconnected are lost.
+type Bus struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// New creates a Bus publishing the invalidations on the subject
+func New(conn *nats.Conn, subject string) *Bus {
+	return &Bus{
+		conn:    conn,
+		subject: subject,
+	}
+}
+
+// Publish publishes the invalidation
+func (b *Bus) Publish(invalidation cachier.Invalidation) error {
+	message, err := json.Marshal(invalidation)
+	if err != nil {
+		return err
+	}
+	return b.conn.Publish(b.subject, message)
+}
+
+// Subscribe subscribes to the subject and calls fn for every invalidation until stop is called;
+// malformed messages are skipped
+func (b *Bus) Subscribe(fn func(invalidation cachier.Invalidation)) (stop func() error, err error) {
+	subscription, err := b.conn.Subscribe(b.subject, func(message *nats.Msg) {
+		var invalidation cachier.Invalidation
+		if err := json.Unmarshal(message.Data, &invalidation); err != nil {
+			return
+		}
+		fn(invalidation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// make sure the server knows the subscription before the caller publishes
+	if err := b.conn.Flush(); err != nil {
+		subscription.Unsubscribe()
+		return nil, err
+	}
+	return subscription.Unsubscribe, nil
+}
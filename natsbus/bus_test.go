@@ -0,0 +1,34 @@
+package natsbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	conn, err := nats.Connect(nats.DefaultURL, nats.Timeout(time.Second))
+	if err != nil {
+		t.Skipf("skipping because of nats error: %s", err.Error())
+	}
+	defer conn.Close()
+
+	bus := New(conn, "cachier.test.invalidations")
+	received := make(chan cachier.Invalidation, 1)
+	stop, err := bus.Subscribe(func(invalidation cachier.Invalidation) { received <- invalidation })
+	require.Nil(t, err)
+	defer stop()
+
+	invalidation := cachier.Invalidation{Source: "a", Op: cachier.OpDelete, Key: "key"}
+	require.Nil(t, bus.Publish(invalidation))
+	select {
+	case got := <-received:
+		assert.Equal(t, invalidation, got)
+	case <-time.After(time.Second):
+		t.Fatal("invalidation not received")
+	}
+}
@@ -0,0 +1,61 @@
+package cachier
+
+import "time"
+
+// Option configures a Cache created by MakeCache
+type Option func(*options)
+
+// options holds the configuration of a Cache
+type options struct {
+	distributedLock  *distributedLockOptions
+	softTTL          time.Duration
+	keyHasher        KeyHasher
+	generations      *generationOptions
+	clock            Clock
+	ttlSweepInterval time.Duration
+	linkDepth        int
+	slidingTTL       time.Duration
+	leases           *leaseOptions
+	xfetch           *xfetchOptions
+	latencies        *latencyRecorder
+	audit            AuditSink
+	validator        func(key string, value interface{}) error
+	tombstones       bool
+	// pendingBudget is the byte budget of the held and background writes (WithPendingWritesBudget)
+	pendingBudget int64
+}
+
+// defaultMaxLinkDepth is the default maximum number of links followed by GetIndirect
+const defaultMaxLinkDepth = 16
+
+func makeOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSoftTTL makes GetOrCompute recompute values stored longer than softTTL ago,
+// while serving the stale value if the evaluator fails. It should be shorter than the TTL of the engine.
+// The engine must implement EntryAger (e.g. RedisCache with TTL, MemoryCache); values of other
+// engines are never considered stale.
+func WithSoftTTL(softTTL time.Duration) Option {
+	return func(o *options) {
+		o.softTTL = softTTL
+	}
+}
+
+// WithMaxLinkDepth sets the maximum number of links followed by GetIndirect (default 16)
+func WithMaxLinkDepth(depth int) Option {
+	return func(o *options) {
+		o.linkDepth = depth
+	}
+}
+
+func (o options) maxLinkDepth() int {
+	if o.linkDepth <= 0 {
+		return defaultMaxLinkDepth
+	}
+	return o.linkDepth
+}
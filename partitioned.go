@@ -0,0 +1,344 @@
+package cachier
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// partitionPoints is the number of points of every engine on the hash ring; more points spread the keys more evenly
+const partitionPoints = 160
+
+var (
+	// ErrNoPartitions is returned by PartitionedEngine without any engine
+	ErrNoPartitions = errors.New("partitioned engine has no engines")
+	// ErrUnknownPartition is returned by RemoveEngine for an engine which is not a partition
+	ErrUnknownPartition = errors.New("engine is not a partition")
+)
+
+// PartitionedEngine is a CacheEngine sharding keys across several engines (e.g. Redis instances) using
+// consistent hashing, so a single engine is not the capacity ceiling. Adding or removing an engine
+// moves only the keys of its share of the ring; until Rebalance moves them, such keys are not found
+// (GetOrCompute recomputes them).
+// The ring depends on the order the engines were given to NewPartitionedEngine and AddEngine,
+// so all the processes sharing the engines must configure them in the same order.
+type PartitionedEngine struct {
+	hash func(key string) uint32
+
+	mutex sync.RWMutex
+	// partitions are the engines with their ids; the id of an engine is its position in the order they were added
+	partitions []partition
+	nextID     int
+	// ring is sorted by hash
+	ring []ringPoint
+}
+
+type partition struct {
+	id     int
+	engine CacheEngine
+}
+
+type ringPoint struct {
+	hash      uint32
+	partition int
+}
+
+// NewPartitionedEngine creates a PartitionedEngine over the engines; if hash is nil crc32 (IEEE) is used
+func NewPartitionedEngine(engines []CacheEngine, hash func(key string) uint32) *PartitionedEngine {
+	if hash == nil {
+		hash = func(key string) uint32 {
+			return crc32.ChecksumIEEE([]byte(key))
+		}
+	}
+	pe := &PartitionedEngine{hash: hash}
+	for _, engine := range engines {
+		pe.partitions = append(pe.partitions, partition{id: pe.nextID, engine: engine})
+		pe.nextID++
+	}
+	pe.buildRing()
+	return pe
+}
+
+// buildRing computes the ring of the partitions; the caller must hold the write lock
+func (pe *PartitionedEngine) buildRing() {
+	pe.ring = make([]ringPoint, 0, len(pe.partitions)*partitionPoints)
+	for i, p := range pe.partitions {
+		for point := 0; point < partitionPoints; point++ {
+			pe.ring = append(pe.ring, ringPoint{
+				hash:      pe.hash(strconv.Itoa(p.id) + "#" + strconv.Itoa(point)),
+				partition: i,
+			})
+		}
+	}
+	sort.Slice(pe.ring, func(i, j int) bool {
+		return pe.ring[i].hash < pe.ring[j].hash
+	})
+}
+
+// AddEngine adds the engine as a new partition; call Rebalance to move the keys it now owns
+func (pe *PartitionedEngine) AddEngine(engine CacheEngine) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	pe.partitions = append(pe.partitions, partition{id: pe.nextID, engine: engine})
+	pe.nextID++
+	pe.buildRing()
+}
+
+// RemoveEngine removes the engine from the partitions; its keys are not moved
+func (pe *PartitionedEngine) RemoveEngine(engine CacheEngine) error {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	for i, p := range pe.partitions {
+		if p.engine == engine {
+			pe.partitions = append(pe.partitions[:i:i], pe.partitions[i+1:]...)
+			pe.buildRing()
+			return nil
+		}
+	}
+	return ErrUnknownPartition
+}
+
+// Engines returns the partitions in the order they were added
+func (pe *PartitionedEngine) Engines() []CacheEngine {
+	pe.mutex.RLock()
+	defer pe.mutex.RUnlock()
+	engines := make([]CacheEngine, len(pe.partitions))
+	for i, p := range pe.partitions {
+		engines[i] = p.engine
+	}
+	return engines
+}
+
+// Unwrap returns the partitions like Engines
+func (pe *PartitionedEngine) Unwrap() []CacheEngine {
+	return pe.Engines()
+}
+
+// engineFor returns the engine owning the key
+func (pe *PartitionedEngine) engineFor(key string) (CacheEngine, error) {
+	pe.mutex.RLock()
+	defer pe.mutex.RUnlock()
+	if len(pe.ring) == 0 {
+		return nil, ErrNoPartitions
+	}
+	return pe.partitions[pe.ownerOf(key)].engine, nil
+}
+
+// ownerOf returns the index of the partition owning the key; the caller must hold the lock
+func (pe *PartitionedEngine) ownerOf(key string) int {
+	hash := pe.hash(key)
+	i := sort.Search(len(pe.ring), func(i int) bool {
+		return pe.ring[i].hash >= hash
+	})
+	if i == len(pe.ring) {
+		i = 0
+	}
+	return pe.ring[i].partition
+}
+
+// Get gets a value from the engine owning the key
+func (pe *PartitionedEngine) Get(key string) (interface{}, error) {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Get(key)
+}
+
+// Peek gets a value from the engine owning the key without side effects
+func (pe *PartitionedEngine) Peek(key string) (interface{}, error) {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Peek(key)
+}
+
+// Set stores a value into the engine owning the key
+func (pe *PartitionedEngine) Set(key string, value interface{}) error {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return err
+	}
+	return engine.Set(key, value)
+}
+
+// SetWithTTL stores a value which expires after ttl into the engine owning the key
+func (pe *PartitionedEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return err
+	}
+	return setWithTTL(engine, key, value, ttl)
+}
+
+// TTL returns the remaining time to live of the key in the engine owning it
+func (pe *PartitionedEngine) TTL(key string) (time.Duration, error) {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return engineTTL(engine, key)
+}
+
+// Age returns how long ago the key was stored in the engine owning it
+func (pe *PartitionedEngine) Age(key string) (time.Duration, error) {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return 0, err
+	}
+	return engineAge(engine, key)
+}
+
+// Delete removes a value from the engine owning the key
+func (pe *PartitionedEngine) Delete(key string) error {
+	engine, err := pe.engineFor(key)
+	if err != nil {
+		return err
+	}
+	return engine.Delete(key)
+}
+
+// Keys lists the keys of all the engines; keys not moved yet by Rebalance are left out as they cannot be read
+func (pe *PartitionedEngine) Keys() ([]string, error) {
+	pe.mutex.RLock()
+	defer pe.mutex.RUnlock()
+	keys := make([]string, 0)
+	for i, p := range pe.partitions {
+		engineKeys, err := p.engine.Keys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range engineKeys {
+			if pe.ownerOf(key) == i {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Count returns the number of keys in all the engines (without listing them if they implement Counter);
+// unlike Keys, it includes the keys not moved yet by Rebalance
+func (pe *PartitionedEngine) Count() (int, error) {
+	pe.mutex.RLock()
+	defer pe.mutex.RUnlock()
+	total := 0
+	for _, p := range pe.partitions {
+		count, err := countKeys(p.engine)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Purge removes all values from all the engines; the first error is returned after all the engines are purged
+func (pe *PartitionedEngine) Purge() error {
+	var firstErr error
+	for _, engine := range pe.Engines() {
+		if err := engine.Purge(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Ping checks all the engines implementing Pinger
+func (pe *PartitionedEngine) Ping(ctx context.Context) error {
+	for _, engine := range pe.Engines() {
+		if pinger, ok := engine.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rebalance moves the keys stored in an engine which does not own them (after AddEngine or RemoveEngine)
+// to their owner and returns the number of moved keys. Entries are moved with ExportEntry/ImportEntry
+// (keeping the TTL) if both engines implement EntryExporter, otherwise by Peek and Set (SetIfAbsent if the owner
+// implements AtomicEngine). Keys the owner already has (written after AddEngine or RemoveEngine) are fresher,
+// so they are not overwritten and only the stale entries are removed.
+// Keys which vanish meanwhile are skipped; the first other error stops the rebalancing.
+func (pe *PartitionedEngine) Rebalance() (int, error) {
+	type move struct {
+		key      string
+		from, to CacheEngine
+	}
+
+	pe.mutex.RLock()
+	moves := make([]move, 0)
+	for i, p := range pe.partitions {
+		keys, err := p.engine.Keys()
+		if err != nil {
+			pe.mutex.RUnlock()
+			return 0, err
+		}
+		for _, key := range keys {
+			if owner := pe.ownerOf(key); owner != i {
+				moves = append(moves, move{key: key, from: p.engine, to: pe.partitions[owner].engine})
+			}
+		}
+	}
+	pe.mutex.RUnlock()
+
+	moved := 0
+	for _, m := range moves {
+		stored, err := moveEntry(m.key, m.from, m.to)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return moved, wrapKeyError(OpSet, m.key, err)
+		}
+		if stored {
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// moveEntry moves the entry of the key from one engine to another unless the other engine has the key already;
+// the entry is removed from the first engine in both cases and stored reports whether it was moved
+func moveEntry(key string, from, to CacheEngine) (stored bool, err error) {
+	if _, err := to.Peek(key); err == nil {
+		return false, from.Delete(key)
+	} else if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
+	fromExporter, ok := from.(EntryExporter)
+	toExporter, toOK := to.(EntryExporter)
+	if ok && toOK {
+		entry, err := fromExporter.ExportEntry(key)
+		if err == nil {
+			err = toExporter.ImportEntry(entry)
+			if err == nil {
+				return true, from.Delete(key)
+			}
+		}
+		if !errors.Is(err, ErrExportNotSupported) {
+			return false, err
+		}
+	}
+
+	value, err := from.Peek(key)
+	if err != nil {
+		return false, err
+	}
+	if atomic, ok := to.(AtomicEngine); ok {
+		stored, err = atomic.SetIfAbsent(key, value)
+	} else {
+		stored, err = true, to.Set(key, value)
+	}
+	if err != nil {
+		return false, err
+	}
+	return stored, from.Delete(key)
+}
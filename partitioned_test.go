@@ -0,0 +1,90 @@
+package cachier_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionedEngine(t *testing.T) {
+	engines := []*cachiertest.Engine{cachiertest.NewEngine(), cachiertest.NewEngine(), cachiertest.NewEngine()}
+	pe := cachier.NewPartitionedEngine([]cachier.CacheEngine{engines[0], engines[1], engines[2]}, nil)
+	cache := cachier.MakeCache[int](pe)
+
+	const n = 3000
+	for i := 0; i < n; i++ {
+		value := i
+		require.Nil(t, cache.Set(fmt.Sprintf("key:%d", i), &value))
+	}
+	for _, engine := range engines {
+		assert.InDelta(t, n/3, engine.Len(), n/6)
+	}
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.Len(t, keys, n)
+
+	added := cachiertest.NewEngine()
+	pe.AddEngine(added)
+	moved, err := pe.Rebalance()
+	require.Nil(t, err)
+	assert.Equal(t, added.Len(), moved)
+	// only the share of the new engine is moved
+	assert.InDelta(t, n/4, moved, n/8)
+	for i := 0; i < n; i++ {
+		value, err := cache.Get(fmt.Sprintf("key:%d", i))
+		require.Nil(t, err)
+		assert.Equal(t, i, *value)
+	}
+
+	require.Nil(t, pe.RemoveEngine(engines[0]))
+	assert.ErrorIs(t, pe.RemoveEngine(engines[0]), cachier.ErrUnknownPartition)
+	keys, err = cache.Keys()
+	require.Nil(t, err)
+	assert.Len(t, keys, n-engines[0].Len())
+
+	require.Nil(t, cache.Purge())
+	for _, engine := range append(engines[1:], added) {
+		assert.Zero(t, engine.Len())
+	}
+}
+
+func TestPartitionedEngineEmpty(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewPartitionedEngine(nil, nil))
+	value := 1
+	assert.ErrorIs(t, cache.Set("key", &value), cachier.ErrNoPartitions)
+}
+
+func TestPartitionedEngineRebalanceKeepsFresherValues(t *testing.T) {
+	old := cachiertest.NewEngine()
+	pe := cachier.NewPartitionedEngine([]cachier.CacheEngine{old}, nil)
+	cache := cachier.MakeCache[int](pe)
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		value := i
+		require.Nil(t, cache.Set(fmt.Sprintf("key:%d", i), &value))
+	}
+
+	added := cachiertest.NewEngine()
+	pe.AddEngine(added)
+	// the keys owned by the new engine are written again before they are moved
+	for i := 0; i < n; i++ {
+		value := -i
+		require.Nil(t, cache.Set(fmt.Sprintf("key:%d", i), &value))
+	}
+	require.NotZero(t, added.Len())
+
+	moved, err := pe.Rebalance()
+	require.Nil(t, err)
+	assert.Zero(t, moved)
+	assert.Equal(t, n, old.Len()+added.Len())
+	for i := 0; i < n; i++ {
+		value, err := cache.Get(fmt.Sprintf("key:%d", i))
+		require.Nil(t, err)
+		assert.Equal(t, -i, *value)
+	}
+}
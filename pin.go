@@ -0,0 +1,62 @@
+package cachier
+
+import "errors"
+
+// OpPin identifies Pin and Unpin in errors
+const OpPin = "pin"
+
+// ErrPinningNotSupported is returned by Pin and Unpin if the engine does not implement Pinner
+var ErrPinningNotSupported = errors.New("engine does not support pinning")
+
+// Pin keeps the entry of the key in the engine until Unpin: it is not evicted when the engine is full
+// (e.g. LRUCache) and not removed by the TTL emulation of SetWithTTL, so small critical datasets
+// (feature flags, configuration) stay hot. Set keeps the key pinned, Delete and Purge remove it.
+// The key must exist and the engine must implement Pinner (LRUCache, CacheWithSubcache with such a subcache).
+func (c *Cache[T]) Pin(key string) error {
+	pinner, ok := c.engine.(Pinner)
+	if !ok {
+		return wrapKeyError(OpPin, key, ErrPinningNotSupported)
+	}
+
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return wrapKeyError(OpPin, key, err)
+	}
+	if c.expiry.expire(c.engine, engineKey) {
+		return wrapKeyError(OpPin, key, ErrNotFound)
+	}
+	return wrapKeyError(OpPin, key, pinner.Pin(engineKey))
+}
+
+// Unpin lets the entry of the key be evicted and expired again; keys which are not pinned are ignored
+func (c *Cache[T]) Unpin(key string) error {
+	pinner, ok := c.engine.(Pinner)
+	if !ok {
+		return wrapKeyError(OpPin, key, ErrPinningNotSupported)
+	}
+
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return wrapKeyError(OpPin, key, err)
+	}
+	return wrapKeyError(OpPin, key, pinner.Unpin(engineKey))
+}
+
+// Pinned reports whether the key is pinned
+func (c *Cache[T]) Pinned(key string) bool {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return false
+	}
+	return pinned(c.engine, engineKey)
+}
+
+// pinned reports whether the engine keeps the key pinned
+func pinned(engine CacheEngine, key string) bool {
+	pinner, ok := engine.(Pinner)
+	return ok && pinner.Pinned(key)
+}
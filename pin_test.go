@@ -0,0 +1,98 @@
+package cachier_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinLRU(t *testing.T) {
+	lc, err := cachier.NewLRUCache(2, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc)
+
+	value := 1
+	require.ErrorIs(t, cache.Pin("flag"), cachier.ErrNotFound)
+	require.Nil(t, cache.Set("flag", &value))
+	require.Nil(t, cache.Pin("flag"))
+	assert.True(t, cache.Pinned("flag"))
+	for i := 0; i < 10; i++ {
+		require.Nil(t, cache.Set(fmt.Sprint(i), &value))
+	}
+
+	// the pinned entry is kept and updated, it does not take the place of other entries
+	updated := 2
+	require.Nil(t, cache.Set("flag", &updated))
+	got, err := cache.Get("flag")
+	require.Nil(t, err)
+	assert.Equal(t, 2, *got)
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"8", "9", "flag"}, keys)
+	count, err := cache.CountPredicate(nil)
+	require.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	require.Nil(t, cache.Unpin("flag"))
+	assert.False(t, cache.Pinned("flag"))
+	require.Nil(t, cache.Set("10", &value))
+	require.Nil(t, cache.Set("11", &value))
+	_, err = cache.Get("flag")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	// deleted entries are not pinned anymore
+	require.Nil(t, cache.Pin("11"))
+	require.Nil(t, cache.Delete("11"))
+	assert.False(t, cache.Pinned("11"))
+}
+
+func TestPinEmulatedTTL(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc, cachier.WithClock(clock), cachier.WithTTLSweepInterval(time.Second))
+	defer cache.Close()
+
+	value := 1
+	require.Nil(t, cache.SetWithTTL("config", &value, time.Second))
+	require.Nil(t, cache.Pin("config"))
+	clock.Advance(time.Minute)
+	_, err = cache.Get("config")
+	require.Nil(t, err)
+	ttl, err := cache.TTL("config")
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	require.Nil(t, cache.Unpin("config"))
+	_, err = cache.Get("config")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
+
+func TestPinNotSupported(t *testing.T) {
+	cache := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0))
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	assert.ErrorIs(t, cache.Pin("a"), cachier.ErrPinningNotSupported)
+	assert.False(t, cache.Pinned("a"))
+}
+
+func TestPinSubcache(t *testing.T) {
+	primary := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0))
+	lc, err := cachier.NewLRUCache(1, nil, nil, nil)
+	require.Nil(t, err)
+	cs := &cachier.CacheWithSubcache[int]{Cache: primary, Subcache: cachier.MakeCache[int](lc)}
+	cache := cachier.MakeCache[int](cs)
+
+	value := 1
+	require.Nil(t, primary.Set("flag", &value))
+	require.Nil(t, cache.Pin("flag"))
+	require.Nil(t, cache.Set("other", &value))
+	assert.True(t, cs.Subcache.Pinned("flag"))
+	_, err = cs.Subcache.Get("flag")
+	assert.Nil(t, err)
+}
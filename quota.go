@@ -0,0 +1,327 @@
+package cachier
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by QuotaEngine when a write does not fit into the quota of its prefix
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// DefaultQuotaReloadInterval is how often QuotaEngine reloads the usage of a prefix from the engine at most
+const DefaultQuotaReloadInterval = 10 * time.Second
+
+// QuotaPolicy decides what happens to writes exceeding a quota
+type QuotaPolicy int
+
+const (
+	// QuotaReject refuses the write with ErrQuotaExceeded
+	QuotaReject QuotaPolicy = iota
+	// QuotaEvictOldest deletes the entries of the prefix stored longest ago to make room for the write
+	QuotaEvictOldest
+)
+
+// Quota limits the keys starting with Prefix
+type Quota struct {
+	Prefix string
+	// MaxKeys is the maximum number of keys (0 means no limit)
+	MaxKeys int
+	// MaxBytes is the maximum total size of the values (0 means no limit)
+	MaxBytes int64
+	Policy   QuotaPolicy
+}
+
+// QuotaEngine wraps a CacheEngine and enforces quotas of key prefixes at Set time, so one feature cannot
+// consume the whole engine shared by several ones. Keys are subject to the quota with the longest matching
+// prefix; other keys are not limited.
+// The usage of a prefix is loaded from the engine at its first write and then tracked by the writes going
+// through the QuotaEngine; before a write is rejected or entries are evicted, it is loaded again if it was loaded
+// longer than the reload interval ago, so keys expired or stored by other processes are accounted for. Values stored before the first write are counted with the size
+// reported by the engine if it implements MemoryReporter, otherwise as 0 bytes, and they are the oldest ones.
+type QuotaEngine struct {
+	engine         CacheEngine
+	size           func(value interface{}) int
+	quotas         []*prefixQuota
+	reloadInterval time.Duration
+	clock          Clock
+}
+
+// prefixQuota tracks the usage of a Quota
+type prefixQuota struct {
+	Quota
+	mutex  sync.Mutex
+	loaded bool
+	// loadedAt is when the usage was loaded from the engine
+	loadedAt time.Time
+	entries  map[string]quotaEntry
+	bytes    int64
+	seq      uint64
+}
+
+// quotaEntry is a tracked entry; entries with a lower seq were stored earlier
+type quotaEntry struct {
+	size int64
+	seq  uint64
+}
+
+// NewQuotaEngine creates a QuotaEngine without quotas
+func NewQuotaEngine(engine CacheEngine) *QuotaEngine {
+	return &QuotaEngine{
+		engine:         engine,
+		size:           auditSize,
+		reloadInterval: DefaultQuotaReloadInterval,
+		clock:          SystemClock{},
+	}
+}
+
+// WithQuota adds the quota of a prefix
+func (qe *QuotaEngine) WithQuota(quota Quota) *QuotaEngine {
+	qe.quotas = append(qe.quotas, &prefixQuota{Quota: quota})
+	sort.SliceStable(qe.quotas, func(i, j int) bool {
+		return len(qe.quotas[i].Prefix) > len(qe.quotas[j].Prefix)
+	})
+	return qe
+}
+
+// WithSizeFunc sets how the size of the values is measured for MaxBytes;
+// nil means the length of []byte and RawValue values and the length of the JSON encoding of the others
+func (qe *QuotaEngine) WithSizeFunc(size func(value interface{}) int) *QuotaEngine {
+	qe.size = size
+	if qe.size == nil {
+		qe.size = auditSize
+	}
+	return qe
+}
+
+// WithReloadInterval sets how often the usage of a prefix is reloaded from the engine at most when a write
+// does not fit into its quota; 0 reloads it before every such write
+func (qe *QuotaEngine) WithReloadInterval(interval time.Duration) *QuotaEngine {
+	qe.reloadInterval = interval
+	return qe
+}
+
+// WithClock sets the clock used to measure the reload interval
+func (qe *QuotaEngine) WithClock(clock Clock) *QuotaEngine {
+	qe.clock = clockOrDefault(clock)
+	return qe
+}
+
+// quotaOf returns the quota of the key, nil if it is not limited
+func (qe *QuotaEngine) quotaOf(key string) *prefixQuota {
+	for _, quota := range qe.quotas {
+		if strings.HasPrefix(key, quota.Prefix) {
+			return quota
+		}
+	}
+	return nil
+}
+
+// Get gets a value from the engine
+func (qe *QuotaEngine) Get(key string) (interface{}, error) {
+	return qe.engine.Get(key)
+}
+
+// Peek gets a value from the engine without updating recency
+func (qe *QuotaEngine) Peek(key string) (interface{}, error) {
+	return qe.engine.Peek(key)
+}
+
+// Set stores a value into the engine if it fits into the quota of its prefix,
+// evicting the oldest entries of the prefix first with QuotaEvictOldest
+func (qe *QuotaEngine) Set(key string, value interface{}) error {
+	return qe.set(key, value, func() error {
+		return qe.engine.Set(key, value)
+	})
+}
+
+// SetWithTTL stores a value which expires after ttl into the engine like Set
+func (qe *QuotaEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if _, ok := qe.engine.(CacheEngineTTL); !ok {
+		return ErrTTLNotSupported
+	}
+	return qe.set(key, value, func() error {
+		return setWithTTL(qe.engine, key, value, ttl)
+	})
+}
+
+// set stores the value using store if it fits into the quota of its prefix
+func (qe *QuotaEngine) set(key string, value interface{}, store func() error) error {
+	quota := qe.quotaOf(key)
+	if quota == nil {
+		return store()
+	}
+	size := int64(qe.size(value))
+	if size < 0 {
+		size = 0
+	}
+
+	quota.mutex.Lock()
+	defer quota.mutex.Unlock()
+	now := qe.clock.Now()
+	if err := quota.load(qe.engine, now); err != nil {
+		return err
+	}
+	if !quota.fits(key, size) {
+		if now.Sub(quota.loadedAt) >= qe.reloadInterval {
+			quota.loaded = false
+		}
+		if err := quota.makeRoom(qe.engine, now, key, size); err != nil {
+			return err
+		}
+	}
+	if err := store(); err != nil {
+		return err
+	}
+	quota.track(key, size)
+	return nil
+}
+
+// TTL returns the remaining time to live of the key in the engine
+func (qe *QuotaEngine) TTL(key string) (time.Duration, error) {
+	return engineTTL(qe.engine, key)
+}
+
+// Age returns how long ago the key was stored in the engine
+func (qe *QuotaEngine) Age(key string) (time.Duration, error) {
+	return engineAge(qe.engine, key)
+}
+
+// Delete removes a value from the engine
+func (qe *QuotaEngine) Delete(key string) error {
+	if err := qe.engine.Delete(key); err != nil {
+		return err
+	}
+	if quota := qe.quotaOf(key); quota != nil {
+		quota.mutex.Lock()
+		quota.forget(key)
+		quota.mutex.Unlock()
+	}
+	return nil
+}
+
+// Keys lists the keys of the engine
+func (qe *QuotaEngine) Keys() ([]string, error) {
+	return qe.engine.Keys()
+}
+
+// Unwrap returns the wrapped engine
+func (qe *QuotaEngine) Unwrap() CacheEngine {
+	return qe.engine
+}
+
+// Purge removes all values from the engine
+func (qe *QuotaEngine) Purge() error {
+	if err := qe.engine.Purge(); err != nil {
+		return err
+	}
+	for _, quota := range qe.quotas {
+		quota.mutex.Lock()
+		quota.loaded = false
+		quota.mutex.Unlock()
+	}
+	return nil
+}
+
+// load loads the usage of the prefix from the engine unless it is loaded already;
+// the tracked entries still present in the engine keep their sizes and order
+func (q *prefixQuota) load(engine CacheEngine, now time.Time) error {
+	if q.loaded {
+		return nil
+	}
+	var keys []string
+	var err error
+	if prefixEngine, ok := engine.(KeysPrefixEngine); ok {
+		keys, err = prefixEngine.KeysWithPrefix(q.Prefix)
+	} else {
+		keys, err = engine.Keys()
+	}
+	if err != nil {
+		return err
+	}
+
+	reporter, _ := engine.(MemoryReporter)
+	entries := make(map[string]quotaEntry, len(keys))
+	q.bytes = 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, q.Prefix) {
+			continue
+		}
+		entry, found := q.entries[key]
+		if !found && reporter != nil {
+			if size, err := reporter.MemoryUsage(key); err == nil {
+				entry.size = size
+			}
+		}
+		entries[key] = entry
+		q.bytes += entry.size
+	}
+	q.entries = entries
+	q.loaded = true
+	q.loadedAt = now
+	return nil
+}
+
+// fits reports whether a value of the given size can be stored under the key
+func (q *prefixQuota) fits(key string, size int64) bool {
+	entry, found := q.entries[key]
+	keys, bytes := len(q.entries), q.bytes+size
+	if found {
+		bytes -= entry.size
+	} else {
+		keys++
+	}
+	return (q.MaxKeys <= 0 || keys <= q.MaxKeys) && (q.MaxBytes <= 0 || bytes <= q.MaxBytes)
+}
+
+// makeRoom reloads the usage of the prefix if it is not loaded and with QuotaEvictOldest deletes the oldest
+// tracked entries until a value of the given size fits under the key
+func (q *prefixQuota) makeRoom(engine CacheEngine, now time.Time, key string, size int64) error {
+	if err := q.load(engine, now); err != nil {
+		return err
+	}
+	if q.fits(key, size) {
+		return nil
+	}
+	if q.Policy != QuotaEvictOldest || (q.MaxBytes > 0 && size > q.MaxBytes) {
+		return ErrQuotaExceeded
+	}
+
+	oldest := make([]string, 0, len(q.entries))
+	for entryKey := range q.entries {
+		if entryKey != key {
+			oldest = append(oldest, entryKey)
+		}
+	}
+	sort.Slice(oldest, func(i, j int) bool {
+		return q.entries[oldest[i]].seq < q.entries[oldest[j]].seq
+	})
+	for _, entryKey := range oldest {
+		if err := engine.Delete(entryKey); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		q.forget(entryKey)
+		if q.fits(key, size) {
+			return nil
+		}
+	}
+	return ErrQuotaExceeded
+}
+
+// track records the stored entry as the newest one
+func (q *prefixQuota) track(key string, size int64) {
+	q.forget(key)
+	q.seq++
+	q.entries[key] = quotaEntry{size: size, seq: q.seq}
+	q.bytes += size
+}
+
+// forget stops tracking the entry
+func (q *prefixQuota) forget(key string) {
+	if entry, found := q.entries[key]; found {
+		q.bytes -= entry.size
+		delete(q.entries, key)
+	}
+}
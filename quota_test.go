@@ -0,0 +1,80 @@
+package cachier_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaEngineReject(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachiertest.NewEngine()
+	require.Nil(t, engine.Set("feature:existing", []byte("x")))
+	qe := cachier.NewQuotaEngine(engine).
+		WithQuota(cachier.Quota{Prefix: "feature:", MaxKeys: 2}).
+		WithClock(clock)
+
+	require.Nil(t, qe.Set("feature:a", []byte("a")))
+	assert.ErrorIs(t, qe.Set("feature:b", []byte("b")), cachier.ErrQuotaExceeded)
+	// existing keys can be updated and other keys are not limited
+	require.Nil(t, qe.Set("feature:a", []byte("aa")))
+	require.Nil(t, qe.Set("other", []byte("o")))
+
+	// keys removed behind the engine's back are accounted for before rejecting once the usage is reloaded
+	require.Nil(t, engine.Delete("feature:existing"))
+	assert.ErrorIs(t, qe.Set("feature:b", []byte("b")), cachier.ErrQuotaExceeded)
+	clock.Advance(cachier.DefaultQuotaReloadInterval)
+	require.Nil(t, qe.Set("feature:b", []byte("b")))
+	require.Nil(t, qe.Delete("feature:a"))
+	require.Nil(t, qe.Set("feature:c", []byte("c")))
+
+	cache := cachier.MakeCache[[]byte](qe)
+	value := []byte("d")
+	assert.ErrorIs(t, cache.Set("feature:d", &value), cachier.ErrQuotaExceeded)
+}
+
+func TestQuotaEngineEvictOldest(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	qe := cachier.NewQuotaEngine(engine).
+		WithQuota(cachier.Quota{Prefix: "feature:", MaxBytes: 10, Policy: cachier.QuotaEvictOldest}).
+		WithQuota(cachier.Quota{Prefix: "feature:small:", MaxKeys: 1, Policy: cachier.QuotaEvictOldest})
+
+	require.Nil(t, qe.Set("feature:a", make([]byte, 4)))
+	require.Nil(t, qe.Set("feature:b", make([]byte, 4)))
+	require.Nil(t, qe.Set("feature:a", make([]byte, 4)))
+	require.Nil(t, qe.Set("feature:c", make([]byte, 4)))
+	keys, err := engine.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"feature:a", "feature:c"}, keys)
+	assert.ErrorIs(t, qe.Set("feature:huge", make([]byte, 11)), cachier.ErrQuotaExceeded)
+
+	// the longest prefix applies
+	require.Nil(t, qe.Set("feature:small:a", make([]byte, 100)))
+	require.Nil(t, qe.Set("feature:small:b", make([]byte, 100)))
+	keys, err = engine.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"feature:a", "feature:c", "feature:small:b"}, keys)
+}
+
+func TestQuotaEngineReloadsAtMostOncePerInterval(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachiertest.NewEngine()
+	qe := cachier.NewQuotaEngine(engine).
+		WithQuota(cachier.Quota{Prefix: "feature:", MaxKeys: 2, Policy: cachier.QuotaEvictOldest}).
+		WithClock(clock)
+
+	for i := 0; i < 100; i++ {
+		require.Nil(t, qe.Set(fmt.Sprint("feature:", i), []byte("x")))
+	}
+	assert.Equal(t, 1, engine.Calls(cachiertest.OpKeys))
+	assert.Equal(t, 2, engine.Len())
+
+	clock.Advance(cachier.DefaultQuotaReloadInterval)
+	require.Nil(t, qe.Set("feature:new", []byte("x")))
+	assert.Equal(t, 2, engine.Calls(cachiertest.OpKeys))
+}
@@ -0,0 +1,252 @@
+package cachier
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited is returned by RateLimitedEngine when a write would have to wait longer than allowed
+var ErrRateLimited = errors.New("write rate limit exceeded")
+
+// RateLimitedEngine wraps a CacheEngine and limits the rate of writes (Set, Delete, Purge),
+// so a burst of cache fills cannot saturate the bandwidth needed by latency-sensitive reads.
+// Reads are passed through unlimited. Writes wait for the limiter; Cache.GetOrCompute stores
+// computed values in background, so waiting writes do not delay the callers. Cache waits for the limiter
+// before it takes the key lock (see WriteThrottler), so the waiting writes do not block the reads either.
+type RateLimitedEngine struct {
+	engine  CacheEngine
+	ops     *rate.Limiter
+	bytes   *rate.Limiter
+	size    func(value interface{}) int
+	maxWait time.Duration
+
+	// prepaidOps and prepaidBytes are the writes paid by WaitWrite, which are not delayed again
+	mutex        sync.Mutex
+	prepaidOps   int
+	prepaidBytes int
+}
+
+// WriteThrottler is an optional interface of CacheEngine.
+// Engines implementing it delay the writes (see RateLimitedEngine); Cache waits by WaitWrite
+// before it takes the key lock, so the waiting writes do not block the other operations on the key.
+type WriteThrottler interface {
+	// WaitWrite blocks until a write of the value (nil for deletes) is allowed; the write is then not delayed again
+	WaitWrite(value interface{}) error
+}
+
+// NewRateLimitedEngine creates a RateLimitedEngine allowing opsPerSecond writes with bursts of burst writes.
+// If opsPerSecond <= 0 the number of writes is not limited.
+func NewRateLimitedEngine(engine CacheEngine, opsPerSecond float64, burst int) *RateLimitedEngine {
+	rl := &RateLimitedEngine{
+		engine: engine,
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	if opsPerSecond > 0 {
+		rl.ops = rate.NewLimiter(rate.Limit(opsPerSecond), burst)
+	}
+	return rl
+}
+
+// WithBytesLimit additionally limits the written bytes per second.
+// size returns the size of a value passed to Set; if nil, the length of []byte, string and RawValue values
+// and the length of the JSON encoding of the others is used.
+func (rl *RateLimitedEngine) WithBytesLimit(bytesPerSecond int, burst int, size func(value interface{}) int) *RateLimitedEngine {
+	if burst < 1 {
+		burst = bytesPerSecond
+	}
+	rl.bytes = rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+	rl.size = size
+	if rl.size == nil {
+		rl.size = valueLen
+	}
+	return rl
+}
+
+// WithMaxWait makes writes which would wait longer than maxWait fail with ErrRateLimited.
+// By default writes wait as long as needed.
+func (rl *RateLimitedEngine) WithMaxWait(maxWait time.Duration) *RateLimitedEngine {
+	rl.maxWait = maxWait
+	return rl
+}
+
+// valueLen returns the length of string values, the size measured by the audit log otherwise
+// (0 if the value cannot be marshaled)
+func valueLen(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case *string:
+		return len(*v)
+	}
+	if size := auditSize(value); size > 0 {
+		return size
+	}
+	return 0
+}
+
+// wait blocks until a write of n bytes (0 for writes without payload) can be made, unless it was paid by WaitWrite
+func (rl *RateLimitedEngine) wait(n int) error {
+	rl.mutex.Lock()
+	ops := 1
+	if rl.prepaidOps > 0 {
+		rl.prepaidOps--
+		ops = 0
+	}
+	paid := rl.prepaidBytes
+	if paid > n {
+		paid = n
+	}
+	rl.prepaidBytes -= paid
+	rl.mutex.Unlock()
+	return rl.reserve(ops, n-paid)
+}
+
+// reserve blocks until ops writes (0 or 1) of n bytes can be made.
+// Writes larger than the bytes burst are charged in full in chunks of the burst size.
+func (rl *RateLimitedEngine) reserve(ops int, n int) error {
+	reservations := make([]*rate.Reservation, 0, 2)
+	cancel := func() {
+		for _, r := range reservations {
+			r.Cancel()
+		}
+	}
+
+	now := time.Now()
+	if rl.ops != nil && ops > 0 {
+		reservations = append(reservations, rl.ops.ReserveN(now, ops))
+	}
+	if rl.bytes != nil {
+		for burst := rl.bytes.Burst(); n > 0; n -= burst {
+			chunk := n
+			if chunk > burst {
+				chunk = burst
+			}
+			reservations = append(reservations, rl.bytes.ReserveN(now, chunk))
+		}
+	}
+
+	var delay time.Duration
+	for _, r := range reservations {
+		if !r.OK() {
+			cancel()
+			return ErrRateLimited
+		}
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if rl.maxWait > 0 && delay > rl.maxWait {
+		cancel()
+		return ErrRateLimited
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return nil
+}
+
+// WaitWrite blocks until a write of the value (nil for deletes and purges) is allowed and pays for it,
+// so the next write is not delayed
+func (rl *RateLimitedEngine) WaitWrite(value interface{}) error {
+	n := 0
+	if rl.size != nil && value != nil {
+		n = rl.size(value)
+	}
+	if err := rl.reserve(1, n); err != nil {
+		return err
+	}
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.prepaidOps++
+	rl.prepaidBytes += n
+	return nil
+}
+
+// waitWrite waits for the engine implementing WriteThrottler before a write of the value; it is called
+// without holding the key lock
+func (c *Cache[T]) waitWrite(value interface{}) error {
+	throttler, ok := c.engine.(WriteThrottler)
+	if !ok {
+		return nil
+	}
+	return throttler.WaitWrite(value)
+}
+
+// Get gets a value from the engine
+func (rl *RateLimitedEngine) Get(key string) (interface{}, error) {
+	return rl.engine.Get(key)
+}
+
+// Peek gets a value from the engine without updating recency
+func (rl *RateLimitedEngine) Peek(key string) (interface{}, error) {
+	return rl.engine.Peek(key)
+}
+
+// Set waits for the rate limiter and stores a value into the engine
+func (rl *RateLimitedEngine) Set(key string, value interface{}) error {
+	if err := rl.waitFor(value); err != nil {
+		return err
+	}
+	return rl.engine.Set(key, value)
+}
+
+// SetWithTTL waits for the rate limiter and stores a value which expires after ttl into the engine
+func (rl *RateLimitedEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	if _, ok := rl.engine.(CacheEngineTTL); !ok {
+		return ErrTTLNotSupported
+	}
+	if err := rl.waitFor(value); err != nil {
+		return err
+	}
+	return setWithTTL(rl.engine, key, value, ttl)
+}
+
+// waitFor blocks until the value can be written
+func (rl *RateLimitedEngine) waitFor(value interface{}) error {
+	n := 0
+	if rl.size != nil {
+		n = rl.size(value)
+	}
+	return rl.wait(n)
+}
+
+// TTL returns the remaining time to live of the key in the engine
+func (rl *RateLimitedEngine) TTL(key string) (time.Duration, error) {
+	return engineTTL(rl.engine, key)
+}
+
+// Age returns how long ago the key was stored in the engine
+func (rl *RateLimitedEngine) Age(key string) (time.Duration, error) {
+	return engineAge(rl.engine, key)
+}
+
+// Delete waits for the rate limiter and removes a value from the engine
+func (rl *RateLimitedEngine) Delete(key string) error {
+	if err := rl.wait(0); err != nil {
+		return err
+	}
+	return rl.engine.Delete(key)
+}
+
+// Keys lists the keys of the engine
+func (rl *RateLimitedEngine) Keys() ([]string, error) {
+	return rl.engine.Keys()
+}
+
+// Unwrap returns the wrapped engine
+func (rl *RateLimitedEngine) Unwrap() CacheEngine {
+	return rl.engine
+}
+
+// Purge waits for the rate limiter and removes all values from the engine
+func (rl *RateLimitedEngine) Purge() error {
+	if err := rl.wait(0); err != nil {
+		return err
+	}
+	return rl.engine.Purge()
+}
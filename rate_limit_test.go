@@ -0,0 +1,91 @@
+package cachier_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitedEngine(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	rl := cachier.NewRateLimitedEngine(engine, 1, 2).WithMaxWait(10 * time.Millisecond)
+
+	require.Nil(t, rl.Set("a", "a"))
+	require.Nil(t, rl.Delete("a"))
+	assert.ErrorIs(t, rl.Set("b", "b"), cachier.ErrRateLimited)
+	assert.Equal(t, 1, engine.Calls(cachiertest.OpSet))
+
+	// reads are not limited
+	_, err := rl.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
+
+func TestRateLimitedEngineBytes(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	rl := cachier.NewRateLimitedEngine(engine, 0, 0).
+		WithBytesLimit(100, 100, nil).
+		WithMaxWait(10 * time.Millisecond)
+
+	require.Nil(t, rl.Set("a", make([]byte, 60)))
+	require.Nil(t, rl.Set("b", 1))
+	assert.ErrorIs(t, rl.Set("c", make([]byte, 60)), cachier.ErrRateLimited)
+}
+
+func TestRateLimitedEngineBytesOfStructs(t *testing.T) {
+	type record struct {
+		Payload string
+	}
+	engine := cachiertest.NewEngine()
+	rl := cachier.NewRateLimitedEngine(engine, 0, 0).
+		WithBytesLimit(100, 100, nil).
+		WithMaxWait(10 * time.Millisecond)
+
+	// values which are not bytes or strings are measured by their JSON encoding
+	require.Nil(t, rl.Set("a", &record{Payload: strings.Repeat("x", 60)}))
+	assert.ErrorIs(t, rl.Set("b", &record{Payload: strings.Repeat("x", 60)}), cachier.ErrRateLimited)
+}
+
+func TestRateLimitedEngineChargesLargeWritesInFull(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	rl := cachier.NewRateLimitedEngine(engine, 0, 0).WithBytesLimit(1000, 100, nil)
+
+	// 300 bytes with a burst of 100 bytes take 200ms at 1000 bytes per second
+	start := time.Now()
+	require.Nil(t, rl.Set("a", make([]byte, 300)))
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	rl.WithMaxWait(10 * time.Millisecond)
+	assert.ErrorIs(t, rl.Set("b", make([]byte, 300)), cachier.ErrRateLimited)
+}
+
+func TestRateLimitedEngineWaitsOutsideKeyLock(t *testing.T) {
+	engine := cachiertest.NewEngine()
+	cache := cachier.MakeCache[string](cachier.NewRateLimitedEngine(engine, 10, 1))
+
+	a := "a"
+	require.Nil(t, cache.Set("x", &a))
+	written := make(chan error)
+	go func() {
+		// waits 100ms for the limiter
+		written <- cache.Set("a", &a)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	value, err := cache.GetOrCompute("a", func() (*string, error) {
+		computed := "computed"
+		return &computed, nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "computed", *value)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	require.Nil(t, <-written)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+}
@@ -0,0 +1,48 @@
+package cachier
+
+import "errors"
+
+// ErrRawNotSupported is returned by GetRaw and SetRaw of engines which do not store values as payloads
+var ErrRawNotSupported = errors.New("engine does not support raw access")
+
+// GetRaw gets the stored payload of the key bypassing unmarshaling and decompression
+func (c *Cache[T]) GetRaw(key string) ([]byte, error) {
+	rawEngine, ok := c.engine.(RawEngine)
+	if !ok {
+		return nil, wrapKeyError(OpGet, key, ErrRawNotSupported)
+	}
+
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return nil, wrapKeyError(OpGet, key, err)
+	}
+	if c.expiry.expire(c.engine, engineKey) {
+		return nil, wrapKeyError(OpGet, key, ErrNotFound)
+	}
+	payload, err := rawEngine.GetRaw(engineKey)
+	return payload, wrapKeyError(OpGet, key, err)
+}
+
+// SetRaw stores the payload of the key as it is; it must be marshaled and compressed the way the engine does it
+func (c *Cache[T]) SetRaw(key string, payload []byte) error {
+	rawEngine, ok := c.engine.(RawEngine)
+	if !ok {
+		return wrapKeyError(OpSet, key, ErrRawNotSupported)
+	}
+	if err := c.writeDirectly(key); err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+	c.expiry.forget(engineKey)
+	return wrapKeyError(OpSet, key, c.audited(OpSet, key, payload, func() error {
+		return rawEngine.SetRaw(engineKey, payload)
+	}))
+}
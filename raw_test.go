@@ -0,0 +1,60 @@
+package cachier_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawMigration(t *testing.T) {
+	unmarshal := func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}
+	zstd, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	s2, err := compression.NewEngine(compression.ProviderIDS2, nil)
+	require.Nil(t, err)
+	source, err := cachier.NewLRUCache(10, json.Marshal, unmarshal, zstd)
+	require.Nil(t, err)
+	target, err := cachier.NewLRUCache(10, json.Marshal, unmarshal, s2)
+	require.Nil(t, err)
+	sourceCache := cachier.MakeCache[string](source)
+	targetCache := cachier.MakeCache[string](target)
+
+	value := strings.Repeat("a", 2000)
+	require.Nil(t, sourceCache.Set("key", &value))
+
+	payload, err := sourceCache.GetRaw("key")
+	require.Nil(t, err)
+	marshaled, err := zstd.Decompress(payload)
+	require.Nil(t, err)
+	payload, err = s2.Compress(marshaled)
+	require.Nil(t, err)
+	require.Nil(t, targetCache.SetRaw("key", payload))
+
+	output, info, err := targetCache.GetWithInfo("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *output)
+	assert.Equal(t, "s2", info.Compression)
+
+	_, err = targetCache.GetRaw("missing")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
+
+func TestRawNotSupported(t *testing.T) {
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc)
+	_, err = cache.GetRaw("key")
+	assert.ErrorIs(t, err, cachier.ErrRawNotSupported)
+	assert.ErrorIs(t, cache.SetRaw("key", []byte("1")), cachier.ErrRawNotSupported)
+
+	memory := cachier.MakeCache[int](cachier.NewMemoryCache(0, 0))
+	_, err = memory.GetRaw("key")
+	assert.ErrorIs(t, err, cachier.ErrRawNotSupported)
+}
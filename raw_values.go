@@ -0,0 +1,49 @@
+package cachier
+
+// RawValue is implemented by pointers to value types holding their own serialized form (e.g. `type Blob []byte`
+// with the methods on *Blob). Engines with WithRawValues store the payload of such values as it is.
+type RawValue interface {
+	// RawBytes returns the payload to be stored
+	RawBytes() []byte
+	// SetRawBytes sets the value from the stored payload
+	SetRawBytes(payload []byte)
+}
+
+// rawBytes returns the payload of []byte, *[]byte and RawValue values
+func rawBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case *[]byte:
+		if v == nil {
+			return nil, false
+		}
+		return *v, true
+	case RawValue:
+		return v.RawBytes(), true
+	}
+	return nil, false
+}
+
+// marshalValue marshals the value; with rawValues the payload of []byte and RawValue values is used as it is
+// and other values are refused with ErrWrongDataType
+func marshalValue(rawValues bool, marshal func(value interface{}) ([]byte, error), value interface{}) ([]byte, error) {
+	if !rawValues {
+		return marshal(value)
+	}
+	payload, ok := rawBytes(value)
+	if !ok {
+		return nil, ErrWrongDataType
+	}
+	return payload, nil
+}
+
+// unmarshalValue unmarshals the payload; with rawValues the payload is the value
+func unmarshalValue(rawValues bool, unmarshal func(b []byte, value *interface{}) error, payload []byte) (interface{}, error) {
+	if rawValues {
+		return payload, nil
+	}
+	var result interface{}
+	err := unmarshal(payload, &result)
+	return result, err
+}
@@ -0,0 +1,72 @@
+package cachier
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blob []byte
+
+func (b *blob) RawBytes() []byte {
+	return *b
+}
+
+func (b *blob) SetRawBytes(payload []byte) {
+	*b = payload
+}
+
+func failingMarshal(value interface{}) ([]byte, error) {
+	return nil, errors.New("marshal must not be called")
+}
+
+func failingUnmarshal(b []byte, value *interface{}) error {
+	return errors.New("unmarshal must not be called")
+}
+
+func TestRedisRawValues(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	engine, err := compression.NewEngine(compression.ProviderIDS2, nil)
+	require.Nil(t, err)
+	rc := NewRedisCache(redisClient, "raw-values:", failingMarshal, failingUnmarshal, 0, engine).WithRawValues()
+	cache := MakeCache[[]byte](rc)
+	defer cache.Purge()
+
+	value := bytes.Repeat([]byte{0, 1, 2, 255}, 1000)
+	require.Nil(t, cache.Set("key", &value))
+	stored, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *stored)
+
+	// the payload is the compressed value itself
+	payload, err := cache.GetRaw("key")
+	require.Nil(t, err)
+	decompressed, err := engine.Decompress(payload)
+	require.Nil(t, err)
+	assert.Equal(t, value, decompressed)
+
+	texts := MakeCache[string](rc)
+	text := "text"
+	assert.ErrorIs(t, texts.Set("text", &text), ErrWrongDataType)
+}
+
+func TestLRURawValues(t *testing.T) {
+	engine, err := compression.NewEngine(compression.ProviderIDS2, nil)
+	require.Nil(t, err)
+	lc, err := NewLRUCache(10, failingMarshal, failingUnmarshal, engine)
+	require.Nil(t, err)
+	cache := MakeCache[blob](lc.WithRawValues())
+
+	value := blob("hello")
+	require.Nil(t, cache.Set("key", &value))
+	stored, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, value, *stored)
+}
@@ -1,8 +1,11 @@
 package cachier
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,26 +13,7 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
-// Logger is interface for logging
-type Logger interface {
-	Error(...interface{})
-	Warn(...interface{})
-	Print(...interface{})
-}
-
-// DummyLogger is implementation of Logger that does not log anything
-type DummyLogger struct{}
-
-// Error does nothing
-func (d DummyLogger) Error(...interface{}) {}
-
-// Warn does nothing
-func (d DummyLogger) Warn(...interface{}) {}
-
-// Print does nothing
-func (d DummyLogger) Print(...interface{}) {}
-
-// RedisCache implements cachier.CacheTTL interface using redis storage
+// RedisCache implements cachier.CacheEngineTTL interface using redis storage
 type RedisCache struct {
 	redisClient       *redis.Client
 	keyPrefix         string
@@ -37,11 +21,20 @@ type RedisCache struct {
 	unmarshal         func(b []byte, value *interface{}) error
 	ttl               time.Duration
 	logger            Logger
+	interceptors      []Interceptor
 	compressionEngine *compression.Engine
+	maxValueSize      maxValueSize
+	chunkSize         int
+	refreshOnGet      bool
+	replicas          *replicaSet
+	rawValues         bool
 }
 
 var ctx = context.Background()
 
+// scanCount is the COUNT hint used by SCAN based iteration
+const scanCount = 1000
+
 // NewRedisCache is a constructor that creates a RedisCache
 func NewRedisCache(
 	redisClient *redis.Client,
@@ -51,18 +44,19 @@ func NewRedisCache(
 	ttl time.Duration,
 	compressionEngine *compression.Engine,
 ) *RedisCache {
-	return &RedisCache{
+	rc := &RedisCache{
 		redisClient:       redisClient,
 		keyPrefix:         keyPrefix,
 		marshal:           marshal,
 		unmarshal:         unmarshal,
 		ttl:               ttl,
-		logger:            DummyLogger{},
 		compressionEngine: compressionEngine,
 	}
+	return rc.WithLogger(nil)
 }
 
 // NewRedisCacheWithLogger is a constructor that creates a RedisCache
+// If logger is nil DummyLogger is used
 func NewRedisCacheWithLogger(
 	redisClient *redis.Client,
 	keyPrefix string,
@@ -72,114 +66,621 @@ func NewRedisCacheWithLogger(
 	logger Logger,
 	compressionEngine *compression.Engine,
 ) *RedisCache {
-	return &RedisCache{
+	rc := &RedisCache{
 		redisClient:       redisClient,
 		keyPrefix:         keyPrefix,
 		marshal:           marshal,
 		unmarshal:         unmarshal,
 		ttl:               ttl,
-		logger:            logger,
 		compressionEngine: compressionEngine,
 	}
+	return rc.WithLogger(logger)
+}
+
+// WithLogger sets the logger used by the cache; nil means DummyLogger
+func (rc *RedisCache) WithLogger(logger Logger) *RedisCache {
+	rc.logger = loggerOrDefault(logger)
+	rc.interceptors = []Interceptor{LoggingInterceptor(rc.logger, "redis")}
+	return rc
+}
+
+// WithMaxValueSize limits the size of marshaled values (before compression).
+// Larger values are refused with ErrValueTooLarge, or not stored without an error if skip is true
+// (the previous value of the key is deleted then). The limit <= 0 means no limit.
+func (rc *RedisCache) WithMaxValueSize(limit int, skip bool) *RedisCache {
+	rc.maxValueSize = maxValueSize{limit: limit, skip: skip}
+	return rc
+}
+
+// WithRefreshOnGet makes Get reset the expiration of the key to the TTL of the cache using GETEX
+// (Redis >= 6.2), which implements sliding expiration without a second round-trip. Peek does not refresh the TTL.
+func (rc *RedisCache) WithRefreshOnGet() *RedisCache {
+	rc.refreshOnGet = true
+	return rc
+}
+
+// WithRawValues stores []byte, *[]byte and RawValue values as they are (compressed if the compression engine
+// is set) instead of marshaling them, so blobs do not make a pointless round-trip through the serialization format;
+// Get returns the payloads as []byte. Other values are refused with ErrWrongDataType. The marshal functions
+// are not used, existing marshaled values are returned as payloads.
+func (rc *RedisCache) WithRawValues() *RedisCache {
+	rc.rawValues = true
+	return rc
 }
 
 // Get gets a cached value by key
-func (rc *RedisCache) Get(key string) (v interface{}, err error) {
+func (rc *RedisCache) Get(key string) (interface{}, error) {
+	call := &Call{Op: OpGet, Key: key}
+	err := rc.intercept(call, func(call *Call) (err error) {
+		call.Value, err = rc.get(call.Key, rc.refreshOnGet && rc.ttl > 0)
+		return err
+	})
+	return call.Value, err
+}
+
+// intercept runs op through the logging interceptor built by WithLogger
+func (rc *RedisCache) intercept(call *Call, op func(call *Call) error) error {
+	return intercept(rc.interceptors, call, op)
+}
+
+// get gets the value of the key; if refresh is true its expiration is reset to the TTL of the cache
+func (rc *RedisCache) get(key string, refresh bool) (v interface{}, err error) {
 	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("%v", r)
-			v = nil
-		}
+		err = wrapKeyError(OpGet, key, err)
 	}()
 
-	rc.logger.Print("redis get " + rc.keyPrefix + key)
-	value, err := rc.redisClient.Get(ctx, rc.keyPrefix+key).Result()
-
+	var value []byte
+	client := rc.redisClient
+	if refresh {
+		var text string
+		text, err = client.Do(ctx, "getex", rc.keyPrefix+key, "px", rc.ttl.Milliseconds()).Text()
+		value = []byte(text)
+	} else {
+		client = rc.reader()
+		value, err = client.Get(ctx, rc.keyPrefix+key).Bytes()
+	}
 	if err == redis.Nil {
-		rc.logger.Print("redis: key not found:", key)
 		return nil, ErrNotFound
 	} else if err != nil {
-		rc.logger.Error("redis: error getting data with key: ", key, " error: ", err)
-		return nil, err
+		return nil, engineError(err)
 	}
+	value = unstamped(value)
+	if rc.chunkSize > 0 {
+		if value, err = rc.assemble(client, key, value, refresh); err != nil {
+			return nil, err
+		}
+	}
+	return rc.decode(key, value)
+}
 
-	var input []byte
-	if rc.compressionEngine == nil {
-		input = []byte(value)
-	} else {
-		input, err = rc.compressionEngine.Decompress([]byte(value))
+// decode decompresses and unmarshals the stored payload of the key
+func (rc *RedisCache) decode(key string, payload []byte) (v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+			v = nil
+		}
+	}()
+
+	if _, ok := parseChunkManifest(payload); ok {
+		// a manifest which was not reassembled (e.g. chunking was disabled); removing it would orphan the chunks
+		return nil, ErrWrongDataType
+	}
+	input := payload
+	if rc.compressionEngine != nil {
+		input, err = rc.compressionEngine.Decompress(payload)
 		if err != nil {
-			// backward compatibility for not compressed entries
+			// not compressed or corrupted entries are removed
+			rc.logger.Error("redis: error decompressing data with key: ", key, " error: ", err)
 			rc.Delete(key)
-			return nil, ErrNotFound
+			return nil, err
 		}
 	}
 
-	var result interface{}
-	rc.unmarshal(input, &result)
+	result, err := unmarshalValue(rc.rawValues, rc.unmarshal, input)
+	if err != nil {
+		rc.logger.Error("redis: error unmarshaling data with key: ", key, " error: ", err)
+		return nil, serializationError(err)
+	}
 	return result, nil
 }
 
-// Peek gets a cached value by key without any sideeffects (identical as Get in this implementation)
+// Peek gets a cached value by key without any sideeffects (identical as Get without WithRefreshOnGet)
 func (rc *RedisCache) Peek(key string) (interface{}, error) {
-	return rc.Get(key)
+	call := &Call{Op: OpPeek, Key: key}
+	err := rc.intercept(call, func(call *Call) (err error) {
+		call.Value, err = rc.get(call.Key, false)
+		return err
+	})
+	return call.Value, err
 }
 
 // Set stores a key-value pair into cache
-func (rc *RedisCache) Set(key string, value interface{}) (err error) {
+func (rc *RedisCache) Set(key string, value interface{}) error {
+	return rc.SetWithTTL(key, value, rc.ttl)
+}
+
+// SetWithTTL stores a key-value pair into cache which expires after ttl instead of the TTL of the cache
+func (rc *RedisCache) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	return rc.intercept(&Call{Op: OpSet, Key: key, Value: value}, func(call *Call) error {
+		return rc.set(call.Key, call.Value, ttl)
+	})
+}
+
+// TTL returns the remaining time to live of the key; 0 means the key does not expire
+func (rc *RedisCache) TTL(key string) (time.Duration, error) {
+	pttl, err := rc.redisClient.PTTL(ctx, rc.keyPrefix+key).Result()
+	if err != nil {
+		return 0, engineError(err)
+	}
+	switch {
+	case pttl == -2:
+		return 0, ErrNotFound
+	case pttl < 0:
+		return 0, nil
+	}
+	return pttl, nil
+}
+
+func (rc *RedisCache) set(key string, value interface{}, ttl time.Duration) (err error) {
+	defer func() {
+		err = wrapKeyError(OpSet, key, err)
+	}()
+
+	input, store, err := rc.encode(key, value)
+	if err != nil {
+		return err
+	} else if !store {
+		// the value was skipped by WithMaxValueSize, the previous one must not be read anymore
+		return rc.DeleteMulti([]string{key})
+	}
+
+	if rc.chunkSize > 0 {
+		return rc.setChunked(key, input, ttl)
+	}
+
+	status := rc.redisClient.Set(ctx, rc.keyPrefix+key, rc.stamp(input, ttl, time.Now()), ttl)
+	if status.Err() != nil {
+		return engineError(status.Err())
+	}
+	return nil
+}
 
+// encode marshals and compresses the value; store is false if the value must not be stored
+func (rc *RedisCache) encode(key string, value interface{}) (input []byte, store bool, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("%v", r)
+			input, store, err = nil, false, fmt.Errorf("%v", r)
 		}
 	}()
 
-	marshalledValue, err := rc.marshal(value)
-	if err != nil {
+	marshalledValue, err := marshalValue(rc.rawValues, rc.marshal, value)
+	if errors.Is(err, ErrWrongDataType) {
+		return nil, false, err
+	} else if err != nil {
 		rc.logger.Error("redis: error marshaling data: ", err)
-		return err
+		return nil, false, serializationError(err)
+	}
+	if store, err := rc.maxValueSize.check(rc.logger, "redis", key, len(marshalledValue)); !store {
+		return nil, false, err
 	}
 
-	var input []byte
 	if rc.compressionEngine == nil {
-		input = marshalledValue
-	} else {
-		input, err = rc.compressionEngine.Compress(marshalledValue)
+		return marshalledValue, true, nil
+	}
+	input, err = rc.compressionEngine.Compress(marshalledValue)
+	if err != nil {
+		rc.logger.Error("redis: error compressing data: ", err)
+		return nil, false, err
+	}
+	return input, true, nil
+}
+
+// SetMulti stores all the key-value pairs atomically in a MULTI/EXEC transaction.
+// If any value cannot be encoded nothing is stored; values skipped by WithMaxValueSize are left out
+// and their keys deleted.
+func (rc *RedisCache) SetMulti(values map[string]interface{}) error {
+	inputs := make(map[string][]byte, len(values))
+	var skipped []string
+	for key, value := range values {
+		input, store, err := rc.encode(key, value)
 		if err != nil {
-			rc.logger.Error("redis: error compressing data: ", err)
-			return err
+			return wrapKeyError(OpSet, key, err)
+		}
+		if store {
+			inputs[key] = input
+		} else {
+			skipped = append(skipped, key)
 		}
 	}
+	if err := rc.DeleteMulti(skipped); err != nil {
+		return err
+	}
 
-	rc.logger.Print("redis set " + rc.keyPrefix + key)
-	status := rc.redisClient.Set(ctx, rc.keyPrefix+key, input, rc.ttl)
-	if status.Err() != nil {
-		rc.logger.Error("redis: error setting data in cache: ", err)
-		return status.Err()
+	_, err := rc.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, input := range inputs {
+			rc.pipeSet(pipe, key, input, rc.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return engineError(err)
 	}
 	return nil
 }
 
+// GetMulti gets the values of the keys using a single MGET; keys which are not found or cannot be decoded
+// are left out of the result. With WithRefreshOnGet the TTLs are refreshed in the same pipeline.
+// Chunked values need another round-trip each to read their chunks.
+func (rc *RedisCache) GetMulti(keys []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = rc.keyPrefix + key
+	}
+
+	refresh := rc.refreshOnGet && rc.ttl > 0
+	client := rc.redisClient
+	if !refresh {
+		client = rc.reader()
+	}
+	pipe := client.Pipeline()
+	mget := pipe.MGet(ctx, redisKeys...)
+	if refresh {
+		for _, redisKey := range redisKeys {
+			pipe.PExpire(ctx, redisKey, rc.ttl)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, engineError(err)
+	}
+
+	for i, payload := range mget.Val() {
+		s, ok := payload.(string)
+		if !ok {
+			continue
+		}
+		input := unstamped([]byte(s))
+		if rc.chunkSize > 0 {
+			var err error
+			if input, err = rc.assemble(client, keys[i], input, refresh); errors.Is(err, ErrNotFound) || errors.Is(err, ErrCorrupted) {
+				continue
+			} else if err != nil {
+				return nil, wrapKeyError(OpGet, keys[i], err)
+			}
+		}
+		value, err := rc.decode(keys[i], input)
+		if err != nil {
+			// the error is logged by decode
+			continue
+		}
+		values[keys[i]] = value
+	}
+	return values, nil
+}
+
+// storedAtPrefix starts the header of the payloads stored with a TTL other than the TTL of the cache, whose age
+// cannot be derived from their remaining TTL; it is followed by "<unix milliseconds>:", 0 if the time is unknown
+const storedAtPrefix = "\x00cachier-stored:"
+
+// maxStoredAtHeader is the maximal length of the stored-at header
+const maxStoredAtHeader = len(storedAtPrefix) + 21
+
+// stamp prepends the stored-at header to the payload if it is stored with a TTL other than the TTL of the cache
+func (rc *RedisCache) stamp(payload []byte, ttl time.Duration, storedAt time.Time) []byte {
+	if ttl == rc.ttl {
+		return payload
+	}
+	var millis int64
+	if !storedAt.IsZero() {
+		millis = storedAt.UnixMilli()
+	}
+	header := storedAtPrefix + strconv.FormatInt(millis, 10) + ":"
+	return append([]byte(header), payload...)
+}
+
+// splitStoredAt splits the stored-at header from the payload; ok is false if the payload has none
+// and storedAt is zero if the time is unknown
+func splitStoredAt(payload []byte) (storedAt time.Time, rest []byte, ok bool) {
+	if !bytes.HasPrefix(payload, []byte(storedAtPrefix)) {
+		return time.Time{}, payload, false
+	}
+	end := bytes.IndexByte(payload[len(storedAtPrefix):], ':')
+	if end < 0 {
+		return time.Time{}, payload, false
+	}
+	millis, err := strconv.ParseInt(string(payload[len(storedAtPrefix):len(storedAtPrefix)+end]), 10, 64)
+	if err != nil {
+		return time.Time{}, payload, false
+	}
+	if millis != 0 {
+		storedAt = time.UnixMilli(millis)
+	}
+	return storedAt, payload[len(storedAtPrefix)+end+1:], true
+}
+
+// unstamped returns the payload without its stored-at header
+func unstamped(payload []byte) []byte {
+	_, rest, _ := splitStoredAt(payload)
+	return rest
+}
+
+// storedAt returns when the key was stored, read from its stored-at header or derived from its remaining TTL
+// if it has none; the time is zero if unknown. length is the length of the header (0 if there is none).
+func (rc *RedisCache) storedAt(pttl time.Duration, head string) (storedAt time.Time, length int) {
+	if at, rest, ok := splitStoredAt([]byte(head)); ok {
+		return at, len(head) - len(rest)
+	}
+	if rc.ttl <= 0 || pttl < 0 || pttl > rc.ttl {
+		// the key does not expire or it was not stored with the TTL of the cache (e.g. imported)
+		return time.Time{}, 0
+	}
+	return time.Now().Add(pttl - rc.ttl), 0
+}
+
+// Age returns how long ago the key was stored. It is derived from the remaining TTL of the keys stored with
+// the TTL of the cache, the keys stored with another TTL carry their write time.
+// ErrAgeUnknown is returned if the key does not expire and has no write time, or it was imported.
+func (rc *RedisCache) Age(key string) (time.Duration, error) {
+	pipe := rc.redisClient.Pipeline()
+	pttl := pipe.PTTL(ctx, rc.keyPrefix+key)
+	head := pipe.GetRange(ctx, rc.keyPrefix+key, 0, int64(maxStoredAtHeader-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, engineError(err)
+	}
+	if pttl.Val() == -2 {
+		return 0, ErrNotFound
+	}
+	storedAt, _ := rc.storedAt(pttl.Val(), head.Val())
+	if storedAt.IsZero() {
+		return 0, ErrAgeUnknown
+	}
+	return time.Since(storedAt), nil
+}
+
+// ExportEntry returns the stored payload of the key together with its remaining TTL
+func (rc *RedisCache) ExportEntry(key string) (*ExportedEntry, error) {
+	pipe := rc.redisClient.Pipeline()
+	get := pipe.Get(ctx, rc.keyPrefix+key)
+	pttl := pipe.PTTL(ctx, rc.keyPrefix+key)
+	if _, err := pipe.Exec(ctx); err == redis.Nil {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	payload, err := get.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	payload = unstamped(payload)
+	ttl := pttl.Val()
+	if ttl < 0 {
+		// no expiration
+		ttl = 0
+	}
+
+	return &ExportedEntry{
+		Key:     key,
+		TTL:     ttl,
+		Payload: payload,
+	}, nil
+}
+
+// ImportEntry stores the payload as is using the entry TTL; the age of the entry is unknown unless the TTL is
+// the TTL of the cache
+func (rc *RedisCache) ImportEntry(entry *ExportedEntry) error {
+	return rc.redisClient.Set(ctx, rc.keyPrefix+entry.Key, rc.stamp(entry.Payload, entry.TTL, time.Time{}), entry.TTL).Err()
+}
+
 // Delete removes a key from cache
 func (rc *RedisCache) Delete(key string) error {
-	return rc.redisClient.Del(ctx, rc.keyPrefix+key).Err()
+	return rc.intercept(&Call{Op: OpDelete, Key: key}, func(call *Call) error {
+		if rc.chunkSize > 0 {
+			return wrapKeyError(OpDelete, call.Key, rc.deleteChunked(call.Key))
+		}
+		if err := rc.redisClient.Del(ctx, rc.keyPrefix+call.Key).Err(); err != nil {
+			return wrapKeyError(OpDelete, call.Key, engineError(err))
+		}
+		return nil
+	})
+}
+
+// DeleteMulti removes the keys (together with their chunks) using UNLINK commands of at most scanCount keys
+// sent in a single pipeline
+func (rc *RedisCache) DeleteMulti(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	redisKeys := make([]string, len(keys))
+	for i, key := range keys {
+		redisKeys[i] = rc.keyPrefix + key
+	}
+
+	if rc.chunkSize > 0 {
+		payloads, err := rc.redisClient.MGet(ctx, redisKeys...).Result()
+		if err != nil {
+			return engineError(err)
+		}
+		for i, payload := range payloads {
+			s, ok := payload.(string)
+			if !ok {
+				continue
+			}
+			if manifest, ok := parseChunkManifest([]byte(s)); ok {
+				for chunk := 0; chunk < manifest.chunks; chunk++ {
+					redisKeys = append(redisKeys, rc.chunkKey(keys[i], chunk))
+				}
+			}
+		}
+	}
+
+	_, err := rc.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for start := 0; start < len(redisKeys); start += scanCount {
+			end := start + scanCount
+			if end > len(redisKeys) {
+				end = len(redisKeys)
+			}
+			pipe.Unlink(ctx, redisKeys[start:end]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return engineError(err)
+	}
+	return nil
 }
 
 // Keys returns all the keys in the cache
 func (rc *RedisCache) Keys() ([]string, error) {
-	keys, err := rc.redisClient.Keys(ctx, rc.keyPrefix+"*").Result()
+	keys, err := rc.reader().Keys(ctx, rc.keyPrefix+"*").Result()
 	if err != nil {
 		return nil, err
 	}
 
 	strippedKeys := make([]string, 0, len(keys))
 	for _, key := range keys {
-		strippedKeys = append(strippedKeys, strings.TrimPrefix(key, rc.keyPrefix))
+		if listed(key) {
+			strippedKeys = append(strippedKeys, strings.TrimPrefix(key, rc.keyPrefix))
+		}
 	}
 
 	return strippedKeys, nil
 }
 
+// Count returns the number of keys in the cache using DBSIZE if the cache has neither a key prefix nor chunking,
+// i.e. all the keys of the database are its keys, otherwise the keys are counted using SCAN without collecting them
+func (rc *RedisCache) Count() (int, error) {
+	if rc.keyPrefix == "" && rc.chunkSize <= 0 {
+		count, err := rc.reader().DBSize(ctx).Result()
+		return int(count), engineError(err)
+	}
+
+	count := 0
+	iter := rc.reader().Scan(ctx, 0, rc.keyPrefix+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		if listed(iter.Val()) {
+			count++
+		}
+	}
+	return count, engineError(iter.Err())
+}
+
+// RangeKeys calls fn for every key in the cache until fn returns false; the keys are fetched using SCAN
+func (rc *RedisCache) RangeKeys(fn func(key string) bool) error {
+	iter := rc.reader().Scan(ctx, 0, rc.keyPrefix+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		if listed(iter.Val()) && !fn(strings.TrimPrefix(iter.Val(), rc.keyPrefix)) {
+			return nil
+		}
+	}
+	return iter.Err()
+}
+
+// KeysPage returns a page of keys using SCAN; the cursor is the SCAN cursor.
+// As SCAN returns batches of keys, the page may contain slightly more than limit keys.
+func (rc *RedisCache) KeysPage(cursor string, limit int) ([]string, string, error) {
+	var scanCursor uint64
+	if cursor != "" {
+		var err error
+		if scanCursor, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+	if limit <= 0 {
+		limit = scanCount
+	}
+
+	keys := make([]string, 0, limit)
+	for {
+		batch, next, err := rc.redisClient.Scan(ctx, scanCursor, rc.keyPrefix+"*", int64(limit-len(keys))).Result()
+		if err != nil {
+			return nil, "", engineError(err)
+		}
+		for _, key := range batch {
+			if listed(key) {
+				keys = append(keys, strings.TrimPrefix(key, rc.keyPrefix))
+			}
+		}
+		scanCursor = next
+		if scanCursor == 0 {
+			return keys, "", nil
+		}
+		if len(keys) >= limit {
+			return keys, strconv.FormatUint(scanCursor, 10), nil
+		}
+	}
+}
+
+// KeysPredicate returns the keys satisfying the given predicate; the keys are fetched using SCAN
+func (rc *RedisCache) KeysPredicate(pred Predicate) ([]string, error) {
+	keys := make([]string, 0)
+	err := rc.RangeKeys(func(key string) bool {
+		if pred(key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// KeysWithPrefix returns the keys starting with given prefix using SCAN MATCH
+func (rc *RedisCache) KeysWithPrefix(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	iter := rc.reader().Scan(ctx, 0, rc.keyPrefix+escapeGlob(prefix)+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		if listed(iter.Val()) {
+			keys = append(keys, strings.TrimPrefix(iter.Val(), rc.keyPrefix))
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// PurgePrefix removes all the records with keys starting with the given prefix.
+// The keys are found using SCAN MATCH and removed in batches using UNLINK.
+func (rc *RedisCache) PurgePrefix(prefix string) error {
+	batch := make([]string, 0, scanCount)
+	iter := rc.redisClient.Scan(ctx, 0, rc.keyPrefix+escapeGlob(prefix)+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == scanCount {
+			if err := rc.redisClient.Unlink(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return rc.redisClient.Unlink(ctx, batch...).Err()
+	}
+	return nil
+}
+
+// escapeGlob escapes characters with special meaning in redis glob-style patterns
+func escapeGlob(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\', '^', '-':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
 // Purge removes all the records from the cache
 func (rc *RedisCache) Purge() error {
 	//FIXME: delete all keys from redis at once
@@ -194,3 +695,62 @@ func (rc *RedisCache) Purge() error {
 	}
 	return nil
 }
+
+// Ping checks the connection to redis
+func (rc *RedisCache) Ping(ctx context.Context) error {
+	if err := rc.redisClient.Ping(ctx).Err(); err != nil {
+		return engineError(err)
+	}
+	return nil
+}
+
+// Inspect returns the metadata of the entry read from its TTL and the footer of the payload.
+// StoredAt is known under the same conditions as Age.
+func (rc *RedisCache) Inspect(key string) (EntryInfo, error) {
+	pipe := rc.redisClient.Pipeline()
+	pttl := pipe.PTTL(ctx, rc.keyPrefix+key)
+	length := pipe.StrLen(ctx, rc.keyPrefix+key)
+	footer := pipe.GetRange(ctx, rc.keyPrefix+key, -compression.MaxFooterSize, -1)
+	head := pipe.GetRange(ctx, rc.keyPrefix+key, 0, int64(maxStoredAtHeader-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return EntryInfo{}, engineError(err)
+	}
+	if pttl.Val() == -2 {
+		return EntryInfo{}, ErrNotFound
+	}
+
+	storedAt, header := rc.storedAt(pttl.Val(), head.Val())
+	size := int(length.Val()) - header
+	info := EntryInfo{Size: size, UncompressedSize: size, StoredAt: storedAt, Source: SourceEngine}
+	if pttl.Val() > 0 {
+		info.TTL = pttl.Val()
+	}
+	if rc.compressionEngine != nil {
+		providerID, size, err := rc.compressionEngine.InspectFooter([]byte(footer.Val()), info.Size)
+		if err != nil {
+			return EntryInfo{}, err
+		}
+		info.Compression = compression.GetProviderName(providerID)
+		info.UncompressedSize = size
+	}
+	return info, nil
+}
+
+// GetRaw gets the stored payload (the marshaled value, compressed if the compression engine is set)
+func (rc *RedisCache) GetRaw(key string) ([]byte, error) {
+	payload, err := rc.redisClient.Get(ctx, rc.keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, engineError(err)
+	}
+	return unstamped(payload), nil
+}
+
+// SetRaw stores the payload as it is with the TTL of the cache
+func (rc *RedisCache) SetRaw(key string, payload []byte) error {
+	if err := rc.redisClient.Set(ctx, rc.keyPrefix+key, payload, rc.ttl).Err(); err != nil {
+		return engineError(err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cachier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisAge(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	compressionEngine, err := compression.NewEngine(compression.ProviderIDZstd, map[string]interface{}{"minInputLen": 0})
+	require.Nil(t, err)
+	codec := JSONCodec[string]{}
+	rc := NewRedisCache(redisClient, "age:", codec.Marshal, codec.Unmarshal, time.Hour, compressionEngine)
+	require.Nil(t, rc.Purge())
+	defer rc.Purge()
+
+	require.Nil(t, rc.Set("default", "a"))
+	require.Nil(t, rc.SetWithTTL("short", "b", time.Minute))
+	require.Nil(t, rc.SetWithTTL("persistent", "c", 0))
+	time.Sleep(20 * time.Millisecond)
+
+	for _, key := range []string{"default", "short", "persistent"} {
+		age, err := rc.Age(key)
+		require.Nil(t, err, key)
+		if key != "default" {
+			// the age of the other keys is not derived from the remaining TTL
+			assert.GreaterOrEqual(t, age, 20*time.Millisecond, key)
+		}
+		assert.Less(t, age, time.Second, key)
+
+		info, err := rc.Inspect(key)
+		require.Nil(t, err, key)
+		assert.WithinDuration(t, time.Now().Add(-age), info.StoredAt, 100*time.Millisecond, key)
+		assert.Equal(t, "zstd", info.Compression, key)
+	}
+
+	// the write time does not leak into the values
+	value, err := rc.Get("short")
+	require.Nil(t, err)
+	assert.Equal(t, "b", *value.(*string))
+	payload, err := rc.GetRaw("short")
+	require.Nil(t, err)
+	def, err := rc.GetRaw("default")
+	require.Nil(t, err)
+	assert.Equal(t, len(def), len(payload))
+
+	// imported entries have no known age unless they are stored with the TTL of the cache
+	entry, err := rc.ExportEntry("short")
+	require.Nil(t, err)
+	entry.Key = "imported"
+	require.Nil(t, rc.ImportEntry(entry))
+	_, err = rc.Age("imported")
+	assert.ErrorIs(t, err, ErrAgeUnknown)
+	value, err = rc.Get("imported")
+	require.Nil(t, err)
+	assert.Equal(t, "b", *value.(*string))
+
+	_, err = rc.Age("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,156 @@
+package cachier
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisCompareAndSwapScript sets KEYS[1] to ARGV[2] (with PX ARGV[3] if > 0)
+// if SHA-1 of its current payload equals ARGV[1]; it returns the replaced payload, nil if nothing was set
+var redisCompareAndSwapScript = redis.NewScript(`
+local current = redis.call("get", KEYS[1])
+if not current or redis.sha1hex(current) ~= ARGV[1] then
+	return false
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("set", KEYS[1], ARGV[2], "px", ARGV[3])
+else
+	redis.call("set", KEYS[1], ARGV[2])
+end
+return current
+`)
+
+// redisReleaseLeaseScript deletes KEYS[1] if it holds ARGV[1]
+var redisReleaseLeaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// redisGetAndTouchScript returns KEYS[1] and sets its expiration to ARGV[1] milliseconds (persists it if <= 0)
+var redisGetAndTouchScript = redis.NewScript(`
+local value = redis.call("get", KEYS[1])
+if value then
+	if tonumber(ARGV[1]) > 0 then
+		redis.call("pexpire", KEYS[1], ARGV[1])
+	else
+		redis.call("persist", KEYS[1])
+	end
+end
+return value
+`)
+
+// payloadVersion returns the version of a stored payload; it matches redis.sha1hex used by the scripts
+func payloadVersion(payload []byte) string {
+	sum := sha1.Sum(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// SetIfAbsent stores the value using SET NX only if the key does not exist
+func (rc *RedisCache) SetIfAbsent(key string, value interface{}) (bool, error) {
+	input, store, err := rc.encode(key, value)
+	if !store {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+	if rc.chunkedConditionally(input) {
+		return false, wrapKeyError(OpSet, key, ErrChunkedConditionalWrite)
+	}
+	stored, err := rc.redisClient.SetNX(ctx, rc.keyPrefix+key, input, rc.ttl).Result()
+	if err != nil {
+		return false, wrapKeyError(OpSet, key, engineError(err))
+	}
+	return stored, nil
+}
+
+// GetWithVersion returns the value and the SHA-1 of its stored payload as the version;
+// the version of a chunked value is the SHA-1 of its manifest, which includes the checksum of the payload
+func (rc *RedisCache) GetWithVersion(key string) (interface{}, string, error) {
+	payload, err := rc.redisClient.Get(ctx, rc.keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, "", wrapKeyError(OpGet, key, ErrNotFound)
+	} else if err != nil {
+		return nil, "", wrapKeyError(OpGet, key, engineError(err))
+	}
+	input := unstamped(payload)
+	if rc.chunkSize > 0 {
+		if input, err = rc.assemble(rc.redisClient, key, input, false); err != nil {
+			return nil, "", wrapKeyError(OpGet, key, err)
+		}
+	}
+	value, err := rc.decode(key, input)
+	if err != nil {
+		return nil, "", wrapKeyError(OpGet, key, err)
+	}
+	return value, payloadVersion(payload), nil
+}
+
+// CompareAndSwap stores the value using a Lua script only if the stored payload still has the given version
+func (rc *RedisCache) CompareAndSwap(key string, version string, value interface{}) (bool, error) {
+	input, store, err := rc.encode(key, value)
+	if !store {
+		return false, wrapKeyError(OpSet, key, err)
+	}
+	if rc.chunkedConditionally(input) {
+		return false, wrapKeyError(OpSet, key, ErrChunkedConditionalWrite)
+	}
+	previous, err := redisCompareAndSwapScript.Run(ctx, rc.redisClient, []string{rc.keyPrefix + key},
+		version, input, rc.ttl.Milliseconds()).Text()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, wrapKeyError(OpSet, key, engineError(err))
+	}
+	if manifest, ok := parseChunkManifest([]byte(previous)); ok {
+		// the swapped value replaced a chunked one, its chunks are not needed anymore
+		chunkKeys := make([]string, manifest.chunks)
+		for i := range chunkKeys {
+			chunkKeys[i] = rc.chunkKey(key, i)
+		}
+		if err := rc.redisClient.Del(ctx, chunkKeys...).Err(); err != nil {
+			return true, wrapKeyError(OpSet, key, engineError(err))
+		}
+	}
+	return true, nil
+}
+
+// GetAndTouch returns the value and sets its expiration to ttl using a Lua script
+func (rc *RedisCache) GetAndTouch(key string, ttl time.Duration) (interface{}, error) {
+	payload, err := redisGetAndTouchScript.Run(ctx, rc.redisClient, []string{rc.keyPrefix + key}, ttl.Milliseconds()).Text()
+	if err == redis.Nil {
+		return nil, wrapKeyError(OpGet, key, ErrNotFound)
+	} else if err != nil {
+		return nil, wrapKeyError(OpGet, key, engineError(err))
+	}
+	input := unstamped([]byte(payload))
+	if manifest, ok := parseChunkManifest(input); ok && rc.chunkSize > 0 {
+		if input, err = rc.assemble(rc.redisClient, key, input, false); err != nil {
+			return nil, wrapKeyError(OpGet, key, err)
+		}
+		if err := rc.touchChunks(key, manifest, ttl); err != nil {
+			return nil, wrapKeyError(OpGet, key, err)
+		}
+	}
+	value, err := rc.decode(key, input)
+	return value, wrapKeyError(OpGet, key, err)
+}
+
+// AcquireLease stores the token as it is (not marshaled) using SET NX PX
+func (rc *RedisCache) AcquireLease(key string, token string, ttl time.Duration) (bool, error) {
+	acquired, err := rc.redisClient.SetNX(ctx, rc.keyPrefix+key, token, ttl).Result()
+	if err != nil {
+		return false, wrapKeyError(OpSet, key, engineError(err))
+	}
+	return acquired, nil
+}
+
+// ReleaseLease deletes the lease using a Lua script if it still holds the token
+func (rc *RedisCache) ReleaseLease(key string, token string) error {
+	if err := redisReleaseLeaseScript.Run(ctx, rc.redisClient, []string{rc.keyPrefix + key}, token).Err(); err != nil {
+		return wrapKeyError(OpDelete, key, engineError(err))
+	}
+	return nil
+}
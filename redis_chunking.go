@@ -0,0 +1,215 @@
+package cachier
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// chunkKeySeparator separates the key and the index of its chunk; keys containing it are not listed
+const chunkKeySeparator = "\x00chunk:"
+
+// chunkedSetRetries is the number of attempts to store a chunked value while other writers change the key
+const chunkedSetRetries = 10
+
+// ErrChunkedConditionalWrite is returned by SetIfAbsent and CompareAndSwap of a RedisCache with chunking
+// when the value is larger than the chunk size
+var ErrChunkedConditionalWrite = errors.New("values larger than the chunk size cannot be written conditionally")
+
+// chunkManifestPrefix starts the manifest stored under the key of a chunked value;
+// it is followed by "<chunks>:<length>:<crc32>"
+const chunkManifestPrefix = "\x00cachier-chunks:"
+
+// WithChunking makes RedisCache split payloads (marshaled and compressed values) larger than chunkSize bytes
+// into chunks stored under separate keys, with a manifest stored under the key itself, so values above
+// the limits of Redis or a proxy can be stored. Chunks are read back by a single MGET and are not listed by Keys.
+// chunkSize <= 0 disables chunking. GetWithVersion and GetAndTouch reassemble chunked values, SetIfAbsent and
+// CompareAndSwap refuse values larger than chunkSize with ErrChunkedConditionalWrite. GetRaw and ExportEntry
+// work with the manifest.
+func (rc *RedisCache) WithChunking(chunkSize int) *RedisCache {
+	rc.chunkSize = chunkSize
+	return rc
+}
+
+// chunkManifest describes a chunked value
+type chunkManifest struct {
+	chunks   int
+	length   int
+	checksum uint32
+}
+
+func (m chunkManifest) encode() []byte {
+	return []byte(chunkManifestPrefix + strconv.Itoa(m.chunks) + ":" + strconv.Itoa(m.length) + ":" +
+		strconv.FormatUint(uint64(m.checksum), 10))
+}
+
+// parseChunkManifest parses the payload as a manifest; ok is false if it is not a manifest
+func parseChunkManifest(payload []byte) (manifest chunkManifest, ok bool) {
+	payload = unstamped(payload)
+	if !bytes.HasPrefix(payload, []byte(chunkManifestPrefix)) {
+		return chunkManifest{}, false
+	}
+	fields := strings.Split(string(payload[len(chunkManifestPrefix):]), ":")
+	if len(fields) != 3 {
+		return chunkManifest{}, false
+	}
+	chunks, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return chunkManifest{}, false
+	}
+	length, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return chunkManifest{}, false
+	}
+	checksum, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return chunkManifest{}, false
+	}
+	return chunkManifest{chunks: chunks, length: length, checksum: uint32(checksum)}, true
+}
+
+// chunkKey returns the redis key of the i-th chunk of the key
+func (rc *RedisCache) chunkKey(key string, i int) string {
+	return rc.keyPrefix + key + chunkKeySeparator + strconv.Itoa(i)
+}
+
+// listed reports whether the redis key is listed by Keys, i.e. it is not a chunk
+func listed(key string) bool {
+	return !strings.Contains(key, chunkKeySeparator)
+}
+
+// pipeSet adds the commands storing the payload to the pipeline, splitting it into chunks if needed
+func (rc *RedisCache) pipeSet(pipe redis.Pipeliner, key string, payload []byte, ttl time.Duration) {
+	if rc.chunkSize <= 0 || len(payload) <= rc.chunkSize {
+		pipe.Set(ctx, rc.keyPrefix+key, rc.stamp(payload, ttl, time.Now()), ttl)
+		return
+	}
+
+	manifest := chunkManifest{length: len(payload), checksum: crc32.ChecksumIEEE(payload)}
+	for start := 0; start < len(payload); start += rc.chunkSize {
+		end := start + rc.chunkSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		pipe.Set(ctx, rc.chunkKey(key, manifest.chunks), payload[start:end], ttl)
+		manifest.chunks++
+	}
+	pipe.Set(ctx, rc.keyPrefix+key, rc.stamp(manifest.encode(), ttl, time.Now()), ttl)
+}
+
+// setChunked stores the payload (chunked if needed) and removes the chunks of the previous value which are not
+// overwritten. The previous manifest is watched, so the transaction is retried if another writer changed the key
+// meanwhile and no chunks are left behind.
+func (rc *RedisCache) setChunked(key string, payload []byte, ttl time.Duration) error {
+	chunks := 0
+	if len(payload) > rc.chunkSize {
+		chunks = (len(payload) + rc.chunkSize - 1) / rc.chunkSize
+	}
+
+	set := func(tx *redis.Tx) error {
+		previous, err := tx.Get(ctx, rc.keyPrefix+key).Bytes()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		stale, _ := parseChunkManifest(previous)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			rc.pipeSet(pipe, key, payload, ttl)
+			for i := chunks; i < stale.chunks; i++ {
+				pipe.Del(ctx, rc.chunkKey(key, i))
+			}
+			return nil
+		})
+		return err
+	}
+	var err error
+	for attempt := 0; attempt < chunkedSetRetries; attempt++ {
+		if err = rc.redisClient.Watch(ctx, set, rc.keyPrefix+key); err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err != nil {
+		return engineError(err)
+	}
+	return nil
+}
+
+// chunkedConditionally reports whether the payload would be chunked, so it cannot be written conditionally
+func (rc *RedisCache) chunkedConditionally(payload []byte) bool {
+	return rc.chunkSize > 0 && len(payload) > rc.chunkSize
+}
+
+// touchChunks sets the expiration of the chunks of the manifest to ttl (persists them if ttl <= 0)
+func (rc *RedisCache) touchChunks(key string, manifest chunkManifest, ttl time.Duration) error {
+	_, err := rc.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := 0; i < manifest.chunks; i++ {
+			if ttl > 0 {
+				pipe.PExpire(ctx, rc.chunkKey(key, i), ttl)
+			} else {
+				pipe.Persist(ctx, rc.chunkKey(key, i))
+			}
+		}
+		return nil
+	})
+	return engineError(err)
+}
+
+// assemble returns the payload of the key; chunked payloads are read using MGET from the client the manifest
+// was read from. If refresh is true the expiration of the chunks is reset to the TTL of the cache in the same pipeline.
+func (rc *RedisCache) assemble(client *redis.Client, key string, payload []byte, refresh bool) ([]byte, error) {
+	manifest, ok := parseChunkManifest(payload)
+	if !ok {
+		return payload, nil
+	}
+
+	keys := make([]string, manifest.chunks)
+	for i := range keys {
+		keys[i] = rc.chunkKey(key, i)
+	}
+	pipe := client.Pipeline()
+	mget := pipe.MGet(ctx, keys...)
+	if refresh {
+		for _, chunkKey := range keys {
+			pipe.PExpire(ctx, chunkKey, rc.ttl)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, engineError(err)
+	}
+	chunks := mget.Val()
+	assembled := make([]byte, 0, manifest.length)
+	for _, chunk := range chunks {
+		s, ok := chunk.(string)
+		if !ok {
+			// a chunk expired or was evicted
+			return nil, ErrNotFound
+		}
+		assembled = append(assembled, s...)
+	}
+	if len(assembled) != manifest.length || crc32.ChecksumIEEE(assembled) != manifest.checksum {
+		return nil, ErrCorrupted
+	}
+	return assembled, nil
+}
+
+// deleteChunked removes the key together with its chunks
+func (rc *RedisCache) deleteChunked(key string) error {
+	payload, err := rc.redisClient.Get(ctx, rc.keyPrefix+key).Bytes()
+	if err != nil && err != redis.Nil {
+		return engineError(err)
+	}
+	keys := []string{rc.keyPrefix + key}
+	if manifest, ok := parseChunkManifest(payload); ok {
+		for i := 0; i < manifest.chunks; i++ {
+			keys = append(keys, rc.chunkKey(key, i))
+		}
+	}
+	if err := rc.redisClient.Del(ctx, keys...).Err(); err != nil {
+		return engineError(err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cachier
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisChunking(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "chunking:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil).WithChunking(100)
+	cache := MakeCache[string](rc)
+	defer cache.Purge()
+
+	large := strings.Repeat("a", 1000)
+	require.Nil(t, cache.Set("large", &large))
+	value, err := cache.Get("large")
+	require.Nil(t, err)
+	assert.Equal(t, large, *value)
+
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"large"}, keys)
+	chunks, err := redisClient.Keys(ctx, "chunking:large*").Result()
+	require.Nil(t, err)
+	assert.Len(t, chunks, 12)
+
+	// overwriting with a smaller value removes the stale chunks
+	small := strings.Repeat("b", 300)
+	require.Nil(t, cache.Set("large", &small))
+	value, err = cache.Get("large")
+	require.Nil(t, err)
+	assert.Equal(t, small, *value)
+	chunks, err = redisClient.Keys(ctx, "chunking:large*").Result()
+	require.Nil(t, err)
+	assert.Len(t, chunks, 5)
+
+	require.Nil(t, redisClient.Del(ctx, rc.chunkKey("large", 1)).Err())
+	_, err = cache.Get("large")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.Nil(t, cache.Delete("large"))
+	chunks, err = redisClient.Keys(ctx, "chunking:large*").Result()
+	require.Nil(t, err)
+	assert.Empty(t, chunks)
+}
+
+func TestRedisChunkingAtomic(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	compressionEngine, err := compression.NewEngine(compression.ProviderIDZstd, map[string]interface{}{"minInputLen": 0})
+	require.Nil(t, err)
+	rc := NewRedisCache(redisClient, "chunking-atomic:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, time.Hour, compressionEngine).WithChunking(40)
+	cache := MakeCache[string](rc)
+	defer cache.Purge()
+
+	var sb strings.Builder
+	for i := 0; i < 100; i++ {
+		sb.WriteString(strconv.Itoa(i * 7919 % 10007))
+	}
+	large := sb.String()
+	require.Nil(t, cache.Set("key", &large))
+
+	// the chunked value is reassembled instead of the manifest being decoded (and deleted as corrupted)
+	value, version, err := rc.GetWithVersion("key")
+	require.Nil(t, err)
+	assert.Equal(t, large, value)
+	value, err = rc.GetAndTouch("key", time.Minute)
+	require.Nil(t, err)
+	assert.Equal(t, large, value)
+	ttl, err := redisClient.PTTL(ctx, rc.chunkKey("key", 0)).Result()
+	require.Nil(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, time.Minute)
+
+	// values which would be chunked cannot be written conditionally
+	_, err = rc.CompareAndSwap("key", version, large+"x")
+	assert.ErrorIs(t, err, ErrChunkedConditionalWrite)
+	_, err = rc.SetIfAbsent("other", large)
+	assert.ErrorIs(t, err, ErrChunkedConditionalWrite)
+
+	// a small value swapped in removes the chunks of the previous one
+	swapped, err := rc.CompareAndSwap("key", version, "small")
+	require.Nil(t, err)
+	assert.True(t, swapped)
+	chunks, err := redisClient.Keys(ctx, "chunking-atomic:key*").Result()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"chunking-atomic:key"}, chunks)
+	got, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, "small", *got)
+}
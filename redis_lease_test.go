@@ -0,0 +1,71 @@
+package cachier
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLeases(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	codec := JSONCodec[string]{}
+	rc := NewRedisCache(redisClient, "leases:", codec.Marshal, codec.Unmarshal, time.Hour, nil)
+	defer rc.Purge()
+
+	// the leases do not go through the codec of the values
+	locker := NewEngineLocker(rc)
+	unlock, acquired, err := locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	require.True(t, acquired)
+	_, acquired, err = locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	assert.False(t, acquired)
+	ttl, err := rc.TTL("key" + leaseKeySuffix)
+	require.Nil(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	require.Nil(t, unlock())
+	unlock, acquired, err = locker.Lock("key", time.Second)
+	require.Nil(t, err)
+	require.True(t, acquired)
+	require.Nil(t, unlock())
+}
+
+func TestRedisLeasesMiss(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	purged := NewJSONRedisCache[int](redisClient, "leases-miss:", time.Hour, nil)
+	require.Nil(t, purged.Purge())
+	defer purged.Purge()
+	var evaluations atomic.Int32
+	evaluator := func() (*int, error) {
+		evaluations.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		value := 1
+		return &value, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every caller has its own Cache as if it ran in another process
+			cache := NewJSONRedisCache[int](redisClient, "leases-miss:", time.Hour, nil, WithLeases(time.Second, time.Second))
+			value, err := cache.GetOrCompute("key", evaluator)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, *value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), evaluations.Load())
+}
@@ -0,0 +1,108 @@
+package cachier
+
+import (
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// MemoryUsage returns the number of bytes used by the key according to MEMORY USAGE;
+// the chunks of chunked values are included
+func (rc *RedisCache) MemoryUsage(key string) (int64, error) {
+	pipe := rc.redisClient.Pipeline()
+	usage := pipe.MemoryUsage(ctx, rc.keyPrefix+key)
+	var get *redis.StringCmd
+	if rc.chunkSize > 0 {
+		get = pipe.Get(ctx, rc.keyPrefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err == redis.Nil {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, engineError(err)
+	}
+	if get == nil {
+		return usage.Val(), nil
+	}
+
+	manifest, ok := parseChunkManifest([]byte(get.Val()))
+	if !ok {
+		return usage.Val(), nil
+	}
+	pipe = rc.redisClient.Pipeline()
+	chunks := make([]*redis.IntCmd, manifest.chunks)
+	for i := range chunks {
+		chunks[i] = pipe.MemoryUsage(ctx, rc.chunkKey(key, i))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, engineError(err)
+	}
+	size := usage.Val()
+	for _, chunk := range chunks {
+		// missing chunks are not counted
+		size += chunk.Val()
+	}
+	return size, nil
+}
+
+// TopKeysBySize returns at most n keys using the most memory according to MEMORY USAGE, largest first.
+// The keys are scanned using SCAN and measured in pipelined batches, so it may take a while on large databases.
+// With chunking the sizes of all the keys are kept in memory while scanning to add the chunks to their keys.
+func (rc *RedisCache) TopKeysBySize(n int) ([]KeySize, error) {
+	top := &topKeySizes{n: n}
+	if n <= 0 {
+		return top.top(), nil
+	}
+	var chunked map[string]int64
+	if rc.chunkSize > 0 {
+		chunked = make(map[string]int64)
+	}
+
+	measure := func(keys []string) error {
+		pipe := rc.redisClient.Pipeline()
+		usages := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			usages[i] = pipe.MemoryUsage(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return engineError(err)
+		}
+		for i, key := range keys {
+			if usages[i].Err() == redis.Nil {
+				// expired or deleted meanwhile
+				continue
+			}
+			key = strings.TrimPrefix(key, rc.keyPrefix)
+			if chunked != nil {
+				if sep := strings.Index(key, chunkKeySeparator); sep >= 0 {
+					key = key[:sep]
+				}
+				chunked[key] += usages[i].Val()
+				continue
+			}
+			top.add(KeySize{Key: key, Size: usages[i].Val()})
+		}
+		return nil
+	}
+
+	batch := make([]string, 0, scanCount)
+	iter := rc.redisClient.Scan(ctx, 0, rc.keyPrefix+"*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		if batch = append(batch, iter.Val()); len(batch) == scanCount {
+			if err := measure(batch); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, engineError(err)
+	}
+	if err := measure(batch); err != nil {
+		return nil, err
+	}
+
+	for key, size := range chunked {
+		top.add(KeySize{Key: key, Size: size})
+	}
+	return top.top(), nil
+}
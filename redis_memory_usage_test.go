@@ -0,0 +1,48 @@
+package cachier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisMemoryUsage(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "memory:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil).WithChunking(1000)
+	cache := MakeCache[string](rc)
+	defer cache.Purge()
+
+	values := map[string]string{
+		"small":   "a",
+		"medium":  strings.Repeat("b", 500),
+		"chunked": strings.Repeat("c", 5000),
+	}
+	for key, value := range values {
+		value := value
+		require.Nil(t, cache.Set(key, &value))
+	}
+
+	chunked, err := cache.MemoryUsage("chunked")
+	require.Nil(t, err)
+	assert.Greater(t, chunked, int64(5000))
+	_, err = cache.MemoryUsage("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	top, err := cache.TopKeysBySize(2)
+	require.Nil(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, KeySize{Key: "chunked", Size: chunked}, top[0])
+	assert.Equal(t, "medium", top[1].Key)
+
+	top, err = cache.TopKeysBySize(10)
+	require.Nil(t, err)
+	assert.Len(t, top, 3)
+}
@@ -0,0 +1,41 @@
+package cachier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotifyExpired subscribes to the expired and evicted keyspace notifications of the database and calls fn
+// with the keys of the cache (without the key prefix) until stop is called. Redis publishes them only if
+// notify-keyspace-events enables them, e.g. CONFIG SET notify-keyspace-events Exe.
+// The notifications are not persistent: keys expiring while the subscription is disconnected are not reported.
+func (rc *RedisCache) NotifyExpired(fn func(key string)) (stop func() error, err error) {
+	db := rc.redisClient.Options().DB
+	pubsub := rc.redisClient.Subscribe(ctx,
+		fmt.Sprintf("__keyevent@%d__:expired", db),
+		fmt.Sprintf("__keyevent@%d__:evicted", db),
+	)
+	// wait for the confirmation of the subscription
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, engineError(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for message := range pubsub.Channel() {
+			key := message.Payload
+			if !strings.HasPrefix(key, rc.keyPrefix) || !listed(key) {
+				continue
+			}
+			fn(strings.TrimPrefix(key, rc.keyPrefix))
+		}
+	}()
+
+	return func() error {
+		err := pubsub.Close()
+		<-done
+		return err
+	}, nil
+}
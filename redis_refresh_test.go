@@ -0,0 +1,57 @@
+package cachier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRefreshOnGet(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "refresh:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, time.Hour, nil).WithChunking(100).WithRefreshOnGet()
+	cache := MakeCache[string](rc)
+	defer cache.Purge()
+
+	small := "small"
+	large := strings.Repeat("a", 1000)
+	require.Nil(t, cache.Set("small", &small))
+	require.Nil(t, cache.Set("large", &large))
+	for _, key := range []string{"refresh:small", "refresh:large", rc.chunkKey("large", 3)} {
+		require.Nil(t, redisClient.PExpire(ctx, key, time.Minute).Err())
+	}
+
+	// Peek does not refresh the TTL
+	_, err = cache.Peek("small")
+	require.Nil(t, err)
+	ttl, err := rc.TTL("small")
+	require.Nil(t, err)
+	assert.LessOrEqual(t, ttl, time.Minute)
+
+	value, err := cache.Get("small")
+	require.Nil(t, err)
+	assert.Equal(t, small, *value)
+	ttl, err = rc.TTL("small")
+	require.Nil(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	value, err = cache.Get("large")
+	require.Nil(t, err)
+	assert.Equal(t, large, *value)
+	for _, key := range []string{"refresh:large", rc.chunkKey("large", 3)} {
+		ttl, err := redisClient.PTTL(ctx, key).Result()
+		require.Nil(t, err)
+		assert.Greater(t, ttl, time.Minute, key)
+	}
+
+	_, err = cache.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
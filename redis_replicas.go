@@ -0,0 +1,122 @@
+package cachier
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// replicaCheckInterval is how often the replication state of a replica is checked with WithMaxReplicaLag
+const replicaCheckInterval = time.Second
+
+// replicaSet routes reads to the read replicas of RedisCache
+type replicaSet struct {
+	clients []*redis.Client
+	next    atomic.Uint32
+	maxLag  time.Duration
+	clock   Clock
+
+	mutex sync.Mutex
+	// states holds the last known replication state of the clients
+	states []replicaState
+}
+
+type replicaState struct {
+	checkedAt time.Time
+	usable    bool
+}
+
+// WithReadReplicas makes Get, Peek, GetMulti and key listing read from the replicas (round robin)
+// while all the writes go to the primary client. Reads refreshing the TTL (WithRefreshOnGet) and KeysPage,
+// whose cursor is valid only on one server, use the primary as well.
+// Replicas may return stale values; see WithMaxReplicaLag.
+func (rc *RedisCache) WithReadReplicas(replicas ...*redis.Client) *RedisCache {
+	if len(replicas) == 0 {
+		rc.replicas = nil
+		return rc
+	}
+	rc.replicas = &replicaSet{
+		clients: replicas,
+		clock:   SystemClock{},
+		states:  make([]replicaState, len(replicas)),
+	}
+	return rc
+}
+
+// WithMaxReplicaLag skips the replicas whose link to the primary is down or which have not heard from it
+// for longer than maxLag (reads then go to the primary). The state is read from INFO replication at most
+// once per second per replica; Redis reports it in whole seconds. maxLag <= 0 disables the check.
+// It must be called after WithReadReplicas.
+func (rc *RedisCache) WithMaxReplicaLag(maxLag time.Duration) *RedisCache {
+	if rc.replicas != nil {
+		rc.replicas.maxLag = maxLag
+	}
+	return rc
+}
+
+// reader returns the client used for reads
+func (rc *RedisCache) reader() *redis.Client {
+	if rc.replicas == nil {
+		return rc.redisClient
+	}
+	return rc.replicas.pick(rc.redisClient)
+}
+
+// pick returns the next usable replica or the primary if there is none
+func (rs *replicaSet) pick(primary *redis.Client) *redis.Client {
+	start := int(rs.next.Add(1))
+	for i := 0; i < len(rs.clients); i++ {
+		index := (start + i) % len(rs.clients)
+		if rs.usable(index) {
+			return rs.clients[index]
+		}
+	}
+	return primary
+}
+
+// usable reports whether the replica lags behind the primary at most by maxLag
+func (rs *replicaSet) usable(index int) bool {
+	if rs.maxLag <= 0 {
+		return true
+	}
+
+	rs.mutex.Lock()
+	state := rs.states[index]
+	rs.mutex.Unlock()
+	now := rs.clock.Now()
+	if now.Sub(state.checkedAt) < replicaCheckInterval {
+		return state.usable
+	}
+
+	info, err := rs.clients[index].Info(ctx, "replication").Result()
+	state = replicaState{checkedAt: now, usable: err == nil && replicaLagWithin(info, rs.maxLag)}
+	rs.mutex.Lock()
+	rs.states[index] = state
+	rs.mutex.Unlock()
+	return state.usable
+}
+
+// replicaLagWithin parses INFO replication of a replica and reports whether its link to the primary is up
+// and it has heard from the primary within maxLag
+func replicaLagWithin(info string, maxLag time.Duration) bool {
+	linkUp, lag := false, time.Duration(-1)
+	for _, line := range strings.Split(info, "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		switch name {
+		case "master_link_status":
+			linkUp = value == "up"
+		case "master_last_io_seconds_ago":
+			if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+				lag = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return linkUp && lag >= 0 && lag <= maxLag
+}
@@ -0,0 +1,55 @@
+package cachier
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaLagWithin(t *testing.T) {
+	info := "# Replication\r\nrole:slave\r\nmaster_link_status:up\r\nmaster_last_io_seconds_ago:2\r\n"
+	assert.True(t, replicaLagWithin(info, 2*time.Second))
+	assert.False(t, replicaLagWithin(info, time.Second))
+	assert.False(t, replicaLagWithin("role:slave\r\nmaster_link_status:down\r\nmaster_last_io_seconds_ago:0\r\n", time.Hour))
+	assert.False(t, replicaLagWithin("role:master\r\n", time.Hour))
+}
+
+func TestRedisReadReplicas(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	// another database of the same server stands in for the replica, so reads from it can be told apart
+	options := *redisClient.Options()
+	options.DB = 1
+	replica := redis.NewClient(&options)
+	defer replica.Close()
+
+	rc := NewRedisCache(redisClient, "replicas:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil).WithReadReplicas(replica)
+	cache := MakeCache[string](rc)
+	defer cache.Purge()
+	defer replica.FlushDB(ctx)
+
+	primaryValue := "primary"
+	require.Nil(t, cache.Set("key", &primaryValue))
+	require.Nil(t, replica.Set(ctx, "replicas:key", `"replica"`, 0).Err())
+
+	value, err := cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, "replica", *value)
+	values, err := cache.GetMulti([]string{"key"})
+	require.Nil(t, err)
+	assert.Equal(t, "replica", *values["key"])
+
+	// the replication state of the stand-in cannot be read, so it is skipped
+	rc.WithMaxReplicaLag(time.Second)
+	value, err = cache.Get("key")
+	require.Nil(t, err)
+	assert.Equal(t, primaryValue, *value)
+}
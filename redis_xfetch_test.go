@@ -0,0 +1,44 @@
+package cachier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisEarlyExpiration(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	compressionEngine, err := compression.NewEngine(compression.ProviderIDZstd, map[string]interface{}{"minInputLen": 0})
+	require.Nil(t, err)
+	newCache := func() *Cache[string] {
+		return NewJSONRedisCache[string](redisClient, "xfetch:", time.Hour, compressionEngine, WithEarlyExpiration(1e9))
+	}
+	cache := newCache()
+	require.Nil(t, cache.Purge())
+	defer cache.Purge()
+
+	evaluations := 0
+	evaluator := func() (*string, time.Duration, error) {
+		evaluations++
+		time.Sleep(10 * time.Millisecond)
+		value := "value"
+		return &value, time.Hour, nil
+	}
+	_, err = cache.GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+
+	// the cost does not go through the codec of the values
+	value, err := newCache().GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, "value", *value)
+	assert.Equal(t, 2, evaluations)
+}
@@ -0,0 +1,188 @@
+package remote
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// message is implemented by the messages defined in cachier.proto.
+// They are encoded directly with protowire, so no generated code is needed
+// while staying wire compatible with clients generated from cachier.proto.
+type message interface {
+	marshal() []byte
+	unmarshal(b []byte) error
+}
+
+// codec encodes cachier messages and delegates any other message to the registered proto codec
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(message); ok {
+		return m.marshal(), nil
+	}
+	return encoding.GetCodec("proto").Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(message); ok {
+		return m.unmarshal(data)
+	}
+	return encoding.GetCodec("proto").Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+type empty struct{}
+
+func (m *empty) marshal() []byte {
+	return nil
+}
+
+func (m *empty) unmarshal(b []byte) error {
+	return consumeFields(b, func(protowire.Number, protowire.Type, []byte) (int, bool) {
+		return 0, false
+	})
+}
+
+type keyRequest struct {
+	key string
+}
+
+func (m *keyRequest) marshal() []byte {
+	return appendString(nil, 1, m.key)
+}
+
+func (m *keyRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(b)
+			m.key = v
+			return n, true
+		}
+		return 0, false
+	})
+}
+
+type valueResponse struct {
+	value []byte
+	found bool
+}
+
+func (m *valueResponse) marshal() []byte {
+	b := appendBytes(nil, 1, m.value)
+	if m.found {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	return b
+}
+
+func (m *valueResponse) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.value = append([]byte(nil), v...)
+			return n, true
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.found = v != 0
+			return n, true
+		}
+		return 0, false
+	})
+}
+
+type setRequest struct {
+	key   string
+	value []byte
+}
+
+func (m *setRequest) marshal() []byte {
+	b := appendString(nil, 1, m.key)
+	return appendBytes(b, 2, m.value)
+}
+
+func (m *setRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			m.key = v
+			return n, true
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.value = append([]byte(nil), v...)
+			return n, true
+		}
+		return 0, false
+	})
+}
+
+type keysResponse struct {
+	keys []string
+}
+
+func (m *keysResponse) marshal() []byte {
+	var b []byte
+	for _, key := range m.keys {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, key)
+	}
+	return b
+}
+
+func (m *keysResponse) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, bool) {
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(b)
+			m.keys = append(m.keys, v)
+			return n, true
+		}
+		return 0, false
+	})
+}
+
+// appendString appends a string field, omitting the default value as proto3 does
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendBytes appends a bytes field, omitting the default value as proto3 does
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// consumeFields walks the fields of an encoded message.
+// field returns the number of consumed bytes, or -1 for unknown fields which are skipped.
+func consumeFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, bool)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, known := field(num, typ, b)
+		if !known {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("cannot decode field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package remote
+
+import (
+	"context"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"google.golang.org/grpc"
+)
+
+// Engine is a cachier.CacheEngine backed by a remote Server
+type Engine struct {
+	conn      grpc.ClientConnInterface
+	marshal   func(value interface{}) ([]byte, error)
+	unmarshal func(b []byte, value *interface{}) error
+	timeout   time.Duration
+}
+
+// NewEngine creates an Engine using the connection to a Server.
+// marshal and unmarshal must be compatible with the ones used by the Server.
+func NewEngine(
+	conn grpc.ClientConnInterface,
+	marshal func(value interface{}) ([]byte, error),
+	unmarshal func(b []byte, value *interface{}) error,
+) *Engine {
+	return &Engine{
+		conn:      conn,
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
+}
+
+// WithTimeout sets the deadline of every remote call; 0 means no deadline
+func (e *Engine) WithTimeout(timeout time.Duration) *Engine {
+	e.timeout = timeout
+	return e
+}
+
+func (e *Engine) invoke(method string, req message, resp message) error {
+	ctx := context.Background()
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	return e.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, resp, grpc.ForceCodec(codec{}))
+}
+
+func (e *Engine) get(method string, key string) (interface{}, error) {
+	resp := &valueResponse{}
+	if err := e.invoke(method, &keyRequest{key: key}, resp); err != nil {
+		return nil, err
+	}
+	if !resp.found {
+		return nil, cachier.ErrNotFound
+	}
+
+	var value interface{}
+	if err := e.unmarshal(resp.value, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Get gets a value by given key
+func (e *Engine) Get(key string) (interface{}, error) {
+	return e.get("Get", key)
+}
+
+// Peek gets a value by given key without side-effects on the remote engine
+func (e *Engine) Peek(key string) (interface{}, error) {
+	return e.get("Peek", key)
+}
+
+// Set stores given key-value pair
+func (e *Engine) Set(key string, value interface{}) error {
+	b, err := e.marshal(value)
+	if err != nil {
+		return err
+	}
+	return e.invoke("Set", &setRequest{key: key, value: b}, &empty{})
+}
+
+// Delete removes a key
+func (e *Engine) Delete(key string) error {
+	return e.invoke("Delete", &keyRequest{key: key}, &empty{})
+}
+
+// Keys returns all the keys
+func (e *Engine) Keys() ([]string, error) {
+	resp := &keysResponse{}
+	if err := e.invoke("Keys", &empty{}, resp); err != nil {
+		return nil, err
+	}
+	if resp.keys == nil {
+		return []string{}, nil
+	}
+	return resp.keys, nil
+}
+
+// Purge removes all the records
+func (e *Engine) Purge() error {
+	return e.invoke("Purge", &empty{}, &empty{})
+}
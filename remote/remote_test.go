@@ -0,0 +1,78 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type A struct {
+	ID  int
+	Key string
+}
+
+func marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func unmarshal(b []byte, value *interface{}) error {
+	var a A
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	*value = a
+	return nil
+}
+
+func TestRemoteEngine(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer(ServerOption())
+	NewServer(cachier.NewMemoryCache(0, 0), marshal, unmarshal).Register(gs)
+	go gs.Serve(listener)
+	defer gs.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	cache := cachier.MakeCache[A](NewEngine(conn, marshal, unmarshal))
+
+	_, err = cache.Get("missing")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	a := A{ID: 1, Key: "hello"}
+	require.Nil(t, cache.Set("a", &a))
+	output, err := cache.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, a, *output)
+
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+
+	require.Nil(t, cache.Delete("a"))
+	require.Nil(t, cache.Purge())
+	keys, err = cache.Keys()
+	require.Nil(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestCodecUnknownFields(t *testing.T) {
+	req := &setRequest{key: "k", value: []byte("v")}
+	var decoded keyRequest
+	require.Nil(t, decoded.unmarshal(req.marshal()))
+	assert.Equal(t, "k", decoded.key)
+}
@@ -0,0 +1,175 @@
+// Package remote exposes any cachier.CacheEngine over gRPC (see cachier.proto)
+// and provides Engine, a cachier.CacheEngine talking to such a server.
+package remote
+
+import (
+	"context"
+	"errors"
+
+	"github.com/datasapiens/cachier"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const serviceName = "cachier.v1.Cache"
+
+// Server serves a cachier.CacheEngine over gRPC.
+// Values are converted between the engine and the wire by the marshal and unmarshal functions.
+type Server struct {
+	engine    cachier.CacheEngine
+	marshal   func(value interface{}) ([]byte, error)
+	unmarshal func(b []byte, value *interface{}) error
+}
+
+// cacheServer is the handler type of the service description
+type cacheServer interface {
+	get(key string, peek bool) (*valueResponse, error)
+}
+
+// NewServer creates a Server fronting the engine
+func NewServer(
+	engine cachier.CacheEngine,
+	marshal func(value interface{}) ([]byte, error),
+	unmarshal func(b []byte, value *interface{}) error,
+) *Server {
+	return &Server{
+		engine:    engine,
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
+}
+
+// ServerOption must be passed to grpc.NewServer serving cachier.
+// It installs the codec of cachier messages; other messages are still handled by the proto codec.
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(codec{})
+}
+
+// Register registers the cache service on the gRPC server
+func (s *Server) Register(gs *grpc.Server) {
+	gs.RegisterService(&serviceDesc, s)
+}
+
+func (s *Server) get(key string, peek bool) (*valueResponse, error) {
+	var value interface{}
+	var err error
+	if peek {
+		value, err = s.engine.Peek(key)
+	} else {
+		value, err = s.engine.Get(key)
+	}
+	if errors.Is(err, cachier.ErrNotFound) {
+		return &valueResponse{}, nil
+	} else if err != nil {
+		return nil, toStatus(err)
+	}
+
+	b, err := s.marshal(value)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &valueResponse{value: b, found: true}, nil
+}
+
+func (s *Server) set(req *setRequest) (*empty, error) {
+	var value interface{}
+	if err := s.unmarshal(req.value, &value); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &empty{}, toStatus(s.engine.Set(req.key, value))
+}
+
+func (s *Server) delete(req *keyRequest) (*empty, error) {
+	return &empty{}, toStatus(s.engine.Delete(req.key))
+}
+
+func (s *Server) keys() (*keysResponse, error) {
+	keys, err := s.engine.Keys()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &keysResponse{keys: keys}, nil
+}
+
+func (s *Server) purge() (*empty, error) {
+	return &empty{}, toStatus(s.engine.Purge())
+}
+
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// methodHandler is the type of grpc.MethodDesc.Handler
+type methodHandler = func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error)
+
+// unaryHandler adapts a typed handler to methodHandler
+func unaryHandler[Req any, PReq interface {
+	*Req
+	message
+}](method string, handle func(s *Server, req PReq) (interface{}, error)) methodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := PReq(new(Req))
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return handle(srv.(*Server), req.(PReq))
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + serviceName + "/" + method,
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*cacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler: unaryHandler("Get", func(s *Server, req *keyRequest) (interface{}, error) {
+				return s.get(req.key, false)
+			}),
+		},
+		{
+			MethodName: "Peek",
+			Handler: unaryHandler("Peek", func(s *Server, req *keyRequest) (interface{}, error) {
+				return s.get(req.key, true)
+			}),
+		},
+		{
+			MethodName: "Set",
+			Handler: unaryHandler("Set", func(s *Server, req *setRequest) (interface{}, error) {
+				return s.set(req)
+			}),
+		},
+		{
+			MethodName: "Delete",
+			Handler: unaryHandler("Delete", func(s *Server, req *keyRequest) (interface{}, error) {
+				return s.delete(req)
+			}),
+		},
+		{
+			MethodName: "Keys",
+			Handler: unaryHandler("Keys", func(s *Server, req *empty) (interface{}, error) {
+				return s.keys()
+			}),
+		},
+		{
+			MethodName: "Purge",
+			Handler: unaryHandler("Purge", func(s *Server, req *empty) (interface{}, error) {
+				return s.purge()
+			}),
+		},
+	},
+	Metadata: "cachier.proto",
+}
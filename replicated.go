@@ -0,0 +1,270 @@
+package cachier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQuorumNotReached is returned by ReplicatedEngine when too few engines acknowledged a write
+	ErrQuorumNotReached = errors.New("write quorum not reached")
+	// ErrNoReplicas is returned by ReplicatedEngine without any engine
+	ErrNoReplicas = errors.New("replicated engine has no engines")
+)
+
+// ReplicatedEngine is a CacheEngine replicating writes to several engines (e.g. Redis instances in different
+// regions) and reading from the first engine which does not fail. Writes are sent to all the engines concurrently
+// and succeed once the quorum of them acknowledged it; the rest of the writes finish in background and their
+// failures are only logged. The writes of a key are applied to every engine in the order they were made
+// (Purge after all the writes made before it), so a slow engine lags behind but does not diverge.
+// Failing engines are tried on every read, wrap them with NewCircuitBreakerEngine to skip them quickly.
+type ReplicatedEngine struct {
+	engines []CacheEngine
+	queues  []*replicaQueue
+	quorum  int
+	logger  Logger
+}
+
+// replicaQueue orders the writes sent to an engine: a write waits for the previous write of its key
+// (or for the previous Purge), Purge waits for all the previous writes
+type replicaQueue struct {
+	mutex   sync.Mutex
+	last    map[string]chan struct{}
+	barrier chan struct{}
+}
+
+// enqueue registers a write of the key (of all the keys for Purge) and returns the writes it must wait for
+// and the function to be called once it is done
+func (q *replicaQueue) enqueue(key string, all bool) ([]chan struct{}, func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	done := make(chan struct{})
+	var previous []chan struct{}
+	if all {
+		for _, last := range q.last {
+			previous = append(previous, last)
+		}
+		if q.barrier != nil {
+			previous = append(previous, q.barrier)
+		}
+		q.last = make(map[string]chan struct{})
+		q.barrier = done
+	} else {
+		if last, found := q.last[key]; found {
+			previous = append(previous, last)
+		} else if q.barrier != nil {
+			previous = append(previous, q.barrier)
+		}
+		q.last[key] = done
+	}
+	return previous, func() {
+		close(done)
+		q.mutex.Lock()
+		defer q.mutex.Unlock()
+		if all && q.barrier == done {
+			q.barrier = nil
+		} else if !all && q.last[key] == done {
+			delete(q.last, key)
+		}
+	}
+}
+
+// NewReplicatedEngine creates a best-effort ReplicatedEngine (the quorum is one engine); reads prefer
+// the engines in the given order
+func NewReplicatedEngine(engines ...CacheEngine) *ReplicatedEngine {
+	queues := make([]*replicaQueue, len(engines))
+	for i := range queues {
+		queues[i] = &replicaQueue{last: make(map[string]chan struct{})}
+	}
+	return &ReplicatedEngine{
+		engines: engines,
+		queues:  queues,
+		quorum:  1,
+		logger:  DummyLogger{},
+	}
+}
+
+// WithQuorum sets the number of engines which must acknowledge a write; quorum <= 1 means best-effort
+func (re *ReplicatedEngine) WithQuorum(quorum int) *ReplicatedEngine {
+	if quorum < 1 {
+		quorum = 1
+	}
+	re.quorum = quorum
+	return re
+}
+
+// WithLogger sets the logger used to report failed writes; nil means DummyLogger
+func (re *ReplicatedEngine) WithLogger(logger Logger) *ReplicatedEngine {
+	re.logger = loggerOrDefault(logger)
+	return re
+}
+
+// write runs op writing the key (all the keys for Purge) on all the engines concurrently, each after
+// the previous writes of the key to the engine, and waits until the quorum succeeds or cannot be reached
+func (re *ReplicatedEngine) write(key string, all bool, op func(engine CacheEngine) error) error {
+	if len(re.engines) == 0 {
+		return ErrNoReplicas
+	}
+	results := make(chan error, len(re.engines))
+	for i, engine := range re.engines {
+		previous, done := re.queues[i].enqueue(key, all)
+		go func(engine CacheEngine) {
+			defer done()
+			for _, write := range previous {
+				<-write
+			}
+			results <- op(engine)
+		}(engine)
+	}
+
+	acks := 0
+	errs := make([]error, 0)
+	for received := 1; received <= len(re.engines); received++ {
+		if err := <-results; err != nil {
+			re.logger.Error("replicated: write failed: ", err)
+			errs = append(errs, err)
+		} else {
+			acks++
+		}
+		if acks >= re.quorum || len(re.engines)-len(errs) < re.quorum {
+			go re.logRemaining(results, len(re.engines)-received)
+			break
+		}
+	}
+	if acks >= re.quorum {
+		return nil
+	}
+	return quorumError(errs)
+}
+
+// quorumError returns ErrQuorumNotReached wrapping the failures of the engines, if any
+func quorumError(errs []error) error {
+	if len(errs) == 0 {
+		// the quorum is larger than the number of engines
+		return ErrQuorumNotReached
+	}
+	return fmt.Errorf("%w: %w", ErrQuorumNotReached, errors.Join(errs...))
+}
+
+// logRemaining logs the failures of the writes still running after write returned
+func (re *ReplicatedEngine) logRemaining(results <-chan error, remaining int) {
+	for ; remaining > 0; remaining-- {
+		if err := <-results; err != nil {
+			re.logger.Error("replicated: write failed: ", err)
+		}
+	}
+}
+
+// read runs op on the engines in order until one does not fail; ErrNotFound is not a failure
+func (re *ReplicatedEngine) read(op func(engine CacheEngine) error) error {
+	err := ErrNoReplicas
+	for _, engine := range re.engines {
+		if err = op(engine); err == nil || !isEngineFailure(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// Get gets a value from the first engine which does not fail
+func (re *ReplicatedEngine) Get(key string) (value interface{}, err error) {
+	err = re.read(func(engine CacheEngine) (err error) {
+		value, err = engine.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// Peek gets a value from the first engine which does not fail
+func (re *ReplicatedEngine) Peek(key string) (value interface{}, err error) {
+	err = re.read(func(engine CacheEngine) (err error) {
+		value, err = engine.Peek(key)
+		return err
+	})
+	return value, err
+}
+
+// Set stores a value into all the engines
+func (re *ReplicatedEngine) Set(key string, value interface{}) error {
+	return re.write(key, false, func(engine CacheEngine) error {
+		return engine.Set(key, value)
+	})
+}
+
+// SetWithTTL stores a value which expires after ttl into all the engines
+func (re *ReplicatedEngine) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	return re.write(key, false, func(engine CacheEngine) error {
+		return setWithTTL(engine, key, value, ttl)
+	})
+}
+
+// TTL returns the remaining time to live of the key in the first engine which does not fail
+func (re *ReplicatedEngine) TTL(key string) (ttl time.Duration, err error) {
+	err = re.read(func(engine CacheEngine) (err error) {
+		ttl, err = engineTTL(engine, key)
+		return err
+	})
+	return ttl, err
+}
+
+// Age returns how long ago the key was stored in the first engine which does not fail
+func (re *ReplicatedEngine) Age(key string) (age time.Duration, err error) {
+	err = re.read(func(engine CacheEngine) (err error) {
+		age, err = engineAge(engine, key)
+		return err
+	})
+	return age, err
+}
+
+// Delete removes a value from all the engines
+func (re *ReplicatedEngine) Delete(key string) error {
+	return re.write(key, false, func(engine CacheEngine) error {
+		return engine.Delete(key)
+	})
+}
+
+// Keys lists the keys of the first engine which does not fail
+func (re *ReplicatedEngine) Keys() (keys []string, err error) {
+	err = re.read(func(engine CacheEngine) (err error) {
+		keys, err = engine.Keys()
+		return err
+	})
+	return keys, err
+}
+
+// Unwrap returns the replicated engines
+func (re *ReplicatedEngine) Unwrap() []CacheEngine {
+	return re.engines
+}
+
+// Purge removes all values from all the engines
+func (re *ReplicatedEngine) Purge() error {
+	return re.write("", true, func(engine CacheEngine) error {
+		return engine.Purge()
+	})
+}
+
+// Ping succeeds if at least the quorum of the engines is reachable; engines not implementing Pinger are reachable
+func (re *ReplicatedEngine) Ping(ctx context.Context) error {
+	if len(re.engines) == 0 {
+		return ErrNoReplicas
+	}
+	reachable := 0
+	errs := make([]error, 0)
+	for _, engine := range re.engines {
+		if pinger, ok := engine.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		reachable++
+	}
+	if reachable >= re.quorum {
+		return nil
+	}
+	return quorumError(errs)
+}
@@ -0,0 +1,102 @@
+package cachier_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicatedEngine(t *testing.T) {
+	local, remote := cachiertest.NewEngine(), cachiertest.NewEngine()
+	cache := cachier.MakeCache[string](cachier.NewReplicatedEngine(local, remote))
+
+	a := "a"
+	require.Nil(t, cache.Set("a", &a))
+	require.Eventually(t, func() bool {
+		return local.Len() == 1 && remote.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	// reads fall back to the next engine
+	local.InjectFault(cachiertest.OpGet, cachiertest.Fault{})
+	value, err := cache.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, a, *value)
+
+	// best-effort writes succeed while one engine is failing
+	local.InjectFault(cachiertest.OpSet, cachiertest.Fault{})
+	b := "b"
+	require.Nil(t, cache.Set("b", &b))
+	remote.InjectFault(cachiertest.OpSet, cachiertest.Fault{})
+	assert.ErrorIs(t, cache.Set("c", &b), cachier.ErrQuorumNotReached)
+}
+
+func TestReplicatedEngineQuorum(t *testing.T) {
+	engines := []*cachiertest.Engine{cachiertest.NewEngine(), cachiertest.NewEngine(), cachiertest.NewEngine()}
+	re := cachier.NewReplicatedEngine(engines[0], engines[1], engines[2]).WithQuorum(2)
+	cache := cachier.MakeCache[string](re)
+
+	a := "a"
+	engines[0].InjectFault(cachiertest.OpSet, cachiertest.Fault{})
+	require.Nil(t, cache.Set("a", &a))
+	engines[1].InjectFault(cachiertest.OpSet, cachiertest.Fault{})
+	err := cache.Set("a", &a)
+	assert.ErrorIs(t, err, cachier.ErrQuorumNotReached)
+	assert.ErrorIs(t, err, cachiertest.ErrInjected)
+}
+
+func TestReplicatedEngineWithoutEngines(t *testing.T) {
+	re := cachier.NewReplicatedEngine()
+	cache := cachier.MakeCache[string](re)
+
+	a := "a"
+	assert.ErrorIs(t, cache.Set("a", &a), cachier.ErrNoReplicas)
+	_, err := re.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNoReplicas)
+	assert.ErrorIs(t, re.Ping(context.Background()), cachier.ErrNoReplicas)
+
+	// a quorum larger than the number of engines is never reached
+	err = cachier.NewReplicatedEngine(cachiertest.NewEngine()).WithQuorum(2).Set("a", a)
+	assert.ErrorIs(t, err, cachier.ErrQuorumNotReached)
+	assert.NotContains(t, err.Error(), "%!")
+}
+
+// slowFirstSetEngine delays its first Set
+type slowFirstSetEngine struct {
+	*cachiertest.Engine
+	sets atomic.Int32
+}
+
+func (e *slowFirstSetEngine) Set(key string, value interface{}) error {
+	if e.sets.Add(1) == 1 {
+		time.Sleep(50 * time.Millisecond)
+	}
+	return e.Engine.Set(key, value)
+}
+
+func TestReplicatedEngineOrdersWritesPerKey(t *testing.T) {
+	fast, slow := cachiertest.NewEngine(), &slowFirstSetEngine{Engine: cachiertest.NewEngine()}
+	re := cachier.NewReplicatedEngine(fast, slow)
+
+	// the writes are acknowledged by the fast engine while the slow one is still storing the first value
+	require.Nil(t, re.Set("a", "first"))
+	require.Nil(t, re.Set("a", "second"))
+	require.Nil(t, re.Set("b", "b"))
+	require.Nil(t, re.Purge())
+	require.Nil(t, re.Set("c", "c"))
+
+	require.Eventually(t, func() bool {
+		return slow.Calls(cachiertest.OpPurge) == 1 && slow.Len() == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	keys, err := slow.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"c"}, keys)
+	_, err = slow.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
@@ -0,0 +1,38 @@
+package cachier
+
+import "time"
+
+// WithSlidingExpiration makes entries expire after being idle (not read by Get) for idle.
+// Set stores entries with the idle TTL and Get (also within GetOrCompute) refreshes it; Peek does not.
+// The TTL is refreshed atomically by engines implementing AtomicEngine (e.g. RedisCache, MemoryCache);
+// other engines implementing CacheEngineTTL store the value again, and for engines without TTL support
+// the emulated expiration (see SetWithTTL) is postponed.
+func WithSlidingExpiration(idle time.Duration) Option {
+	return func(o *options) {
+		o.slidingTTL = idle
+	}
+}
+
+// getAndTouch gets the value from the engine refreshing its TTL if sliding expiration is enabled
+func (c *Cache[T]) getAndTouch(engineKey string) (interface{}, error) {
+	idle := c.options.slidingTTL
+	if idle <= 0 {
+		return c.engine.Get(engineKey)
+	}
+
+	if atomicEngine, ok := c.engine.(AtomicEngine); ok {
+		return atomicEngine.GetAndTouch(engineKey, idle)
+	}
+
+	value, err := c.engine.Get(engineKey)
+	if err != nil {
+		return nil, err
+	}
+	if ttlEngine, ok := engineAs[CacheEngineTTL](c.engine); ok {
+		// the value was read successfully, a failed refresh only shortens its life
+		ttlEngine.SetWithTTL(engineKey, value, idle)
+		return value, nil
+	}
+	c.expiry.touch(engineKey, idle)
+	return value, nil
+}
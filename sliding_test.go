@@ -0,0 +1,44 @@
+package cachier_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingExpiration(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engines := map[string]cachier.CacheEngine{
+		"memory":   cachier.NewMemoryCacheWithClock(0, 0, clock),
+		"emulated": cachiertest.NewEngine(),
+	}
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			cache := cachier.MakeCache[int](engine, cachier.WithClock(clock), cachier.WithSlidingExpiration(time.Minute))
+			defer cache.Close()
+
+			value := 1
+			require.Nil(t, cache.Set("read", &value))
+			require.Nil(t, cache.Set("idle", &value))
+			require.Nil(t, cache.SetMulti(map[string]*int{"idle-multi": &value}))
+
+			for i := 0; i < 3; i++ {
+				clock.Advance(40 * time.Second)
+				_, err := cache.Get("read")
+				require.Nil(t, err)
+			}
+			ttl, err := cache.TTL("read")
+			require.Nil(t, err)
+			assert.Equal(t, time.Minute, ttl)
+
+			_, err = cache.Peek("idle")
+			assert.ErrorIs(t, err, cachier.ErrNotFound)
+			_, err = cache.Peek("idle-multi")
+			assert.ErrorIs(t, err, cachier.ErrNotFound)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package cachier_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftTTL(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	mc := cachier.NewMemoryCacheWithClock(time.Hour, 0, clock)
+	cache := cachier.MakeCache[int](mc, cachier.WithSoftTTL(time.Minute))
+
+	stale := 1
+	require.Nil(t, cache.Set("key", &stale))
+
+	evaluations := 0
+	failing := func() (*int, error) {
+		evaluations++
+		return nil, errors.New("upstream down")
+	}
+
+	value, err := cache.GetOrCompute("key", failing)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *value)
+	assert.Equal(t, 0, evaluations)
+
+	// soft expired: evaluator fails, stale value is served
+	clock.Advance(time.Minute)
+	value, err = cache.GetOrCompute("key", failing)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *value)
+	assert.Equal(t, 1, evaluations)
+
+	// soft expired: evaluator succeeds
+	value, err = cache.GetOrCompute("key", func() (*int, error) {
+		fresh := 2
+		return &fresh, nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 2, *value)
+	// the computed value is stored in background
+	require.Eventually(t, func() bool {
+		value, err := mc.Peek("key")
+		return err == nil && *value.(*int) == 2
+	}, time.Second, time.Millisecond)
+
+	// hard expired: evaluator error is returned
+	clock.Advance(time.Hour)
+	_, err = cache.GetOrCompute("key", failing)
+	assert.Error(t, err)
+}
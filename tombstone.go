@@ -0,0 +1,74 @@
+package cachier
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTombstoned is returned by GetOrCompute of caches with WithTombstones for keys deleted by SoftDelete
+// whose tombstone has not expired yet; it also matches ErrNotFound
+var ErrTombstoned = errors.New("key recently deleted")
+
+// tombstoneKeySuffix is appended to the key to get the key holding its tombstone
+const tombstoneKeySuffix = "\x00tombstone"
+
+// WithTombstones makes GetOrCompute check the tombstones written by SoftDelete on misses: keys deleted recently
+// are not recomputed, ErrTombstoned is returned instead. It costs an extra engine read per miss.
+func WithTombstones() Option {
+	return func(o *options) {
+		o.tombstones = true
+	}
+}
+
+// SoftDelete removes the key and leaves a tombstone in its place for tombstoneTTL, so GetOrCompute
+// (with WithTombstones) does not recompute the key until the tombstone expires, e.g. when the source data
+// were deleted as well. The tombstone is stored in the engine under a derived key (the key followed by
+// "\x00tombstone"), so it is shared by the processes using the engine and listed by Keys.
+// tombstoneTTL <= 0 means a plain Delete.
+func (c *Cache[T]) SoftDelete(key string, tombstoneTTL time.Duration) error {
+	if tombstoneTTL <= 0 {
+		return c.Delete(key)
+	}
+	if err := c.writeDirectly(key); err != nil {
+		return wrapKeyError(OpDelete, key, err)
+	}
+
+	lock := c.lockKey(key)
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		c.unlock(lock)
+		return wrapKeyError(OpDelete, key, err)
+	}
+	// the tombstone is written first, so the key is never missing without it
+	tombstoneKey := engineKey + tombstoneKeySuffix
+	if ttlEngine, ok := engineAs[CacheEngineTTL](c.engine); ok {
+		err = ttlEngine.SetWithTTL(tombstoneKey, new(T), tombstoneTTL)
+	} else {
+		err = c.expiry.set(c.engine, tombstoneKey, new(T), tombstoneTTL)
+	}
+	c.unlock(lock)
+	if err != nil {
+		return wrapKeyError(OpDelete, key, err)
+	}
+	return c.Delete(key)
+}
+
+// tombstoned reports whether the key has an unexpired tombstone
+func (c *Cache[T]) tombstoned(key string) bool {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return false
+	}
+	tombstoneKey := engineKey + tombstoneKeySuffix
+	if c.expiry.expire(c.engine, tombstoneKey) {
+		return false
+	}
+	_, err = c.engine.Peek(tombstoneKey)
+	return err == nil
+}
+
+// tombstoneError returns the error of GetOrCompute for a tombstoned key
+func tombstoneError(key string) error {
+	return wrapKeyError(OpGet, key, fmt.Errorf("%w: %w", ErrTombstoned, ErrNotFound))
+}
@@ -0,0 +1,57 @@
+package cachier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	cache := cachier.MakeCache[int](lc, cachier.WithClock(clock), cachier.WithTombstones())
+	defer cache.Close()
+
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	require.Nil(t, cache.SoftDelete("a", time.Minute))
+	_, err = cache.Get("a")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+
+	calls := 0
+	evaluator := func() (*int, error) {
+		calls++
+		return &value, nil
+	}
+	_, err = cache.GetOrCompute("a", evaluator)
+	assert.ErrorIs(t, err, cachier.ErrTombstoned)
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	assert.Equal(t, 0, calls)
+
+	// the tombstone does not affect caches without WithTombstones
+	plain := cachier.MakeCache[int](lc, cachier.WithClock(clock))
+	_, err = plain.GetOrCompute("b", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, 1, calls)
+
+	clock.Advance(2 * time.Minute)
+	got, err := cache.GetOrCompute("a", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *got)
+	assert.Equal(t, 2, calls)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+	_, err = cache.Get("a")
+	assert.Nil(t, err)
+
+	// no TTL means a plain delete
+	require.Nil(t, cache.SoftDelete("a", 0))
+	_, err = cache.GetOrCompute("a", evaluator)
+	assert.Nil(t, err)
+}
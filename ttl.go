@@ -0,0 +1,232 @@
+package cachier
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultTTLSweepInterval is the default interval of removing expired entries of engines without TTL support
+const defaultTTLSweepInterval = time.Minute
+
+// WithClock sets the clock used by the cache for time dependent behaviour (e.g. emulated TTL)
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithTTLSweepInterval sets how often entries stored by SetWithTTL into engines without
+// CacheEngineTTL support are checked for expiration (default 1 minute)
+func WithTTLSweepInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.ttlSweepInterval = interval
+	}
+}
+
+// ttlEmulation emulates expiration for engines which do not implement CacheEngineTTL
+type ttlEmulation struct {
+	clock     Clock
+	interval  time.Duration
+	active    atomic.Bool
+	mutex     sync.Mutex
+	deadlines map[string]time.Time
+	start     sync.Once
+	stop      chan struct{}
+	stopped   sync.Once
+}
+
+func newTTLEmulation(clock Clock, interval time.Duration) *ttlEmulation {
+	if interval <= 0 {
+		interval = defaultTTLSweepInterval
+	}
+	return &ttlEmulation{
+		clock:     clockOrDefault(clock),
+		interval:  interval,
+		deadlines: make(map[string]time.Time),
+		stop:      make(chan struct{}),
+	}
+}
+
+// set stores the value and remembers when it expires; the sweeper is started on the first use
+func (e *ttlEmulation) set(engine CacheEngine, key string, value interface{}, ttl time.Duration) error {
+	e.start.Do(func() {
+		e.active.Store(true)
+		go e.sweeper(engine, e.clock.NewTicker(e.interval))
+	})
+	e.forget(key)
+	if err := engine.Set(key, value); err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.deadlines[key] = e.clock.Now().Add(ttl)
+	return nil
+}
+
+// forget drops the expiration of the key; it must be called before the key is written or deleted,
+// so the sweeper does not remove the new value
+func (e *ttlEmulation) forget(key string) {
+	if !e.active.Load() {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.deadlines, key)
+}
+
+// expire removes the key from the engine if it has expired and reports it
+func (e *ttlEmulation) expire(engine CacheEngine, key string) bool {
+	if !e.active.Load() {
+		return false
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	deadline, found := e.deadlines[key]
+	if !found || e.clock.Now().Before(deadline) || pinned(engine, key) {
+		return false
+	}
+	delete(e.deadlines, key)
+	engine.Delete(key)
+	return true
+}
+
+// touch postpones the expiration of the key to ttl from now if the key has an emulated expiration
+func (e *ttlEmulation) touch(key string, ttl time.Duration) {
+	if !e.active.Load() {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, found := e.deadlines[key]; found {
+		e.deadlines[key] = e.clock.Now().Add(ttl)
+	}
+}
+
+// ttl returns the remaining time to live of the key; found is false if the key has no emulated expiration
+func (e *ttlEmulation) ttl(key string) (ttl time.Duration, found bool) {
+	if !e.active.Load() {
+		return 0, false
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	deadline, found := e.deadlines[key]
+	if !found {
+		return 0, false
+	}
+	return deadline.Sub(e.clock.Now()), true
+}
+
+// purge forgets all the expirations
+func (e *ttlEmulation) purge() {
+	if !e.active.Load() {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.deadlines = make(map[string]time.Time)
+}
+
+// sweep removes all the expired keys from the engine
+func (e *ttlEmulation) sweep(engine CacheEngine) {
+	now := e.clock.Now()
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for key, deadline := range e.deadlines {
+		if !now.Before(deadline) && !pinned(engine, key) {
+			delete(e.deadlines, key)
+			engine.Delete(key)
+		}
+	}
+}
+
+func (e *ttlEmulation) sweeper(engine CacheEngine, ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			e.sweep(engine)
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *ttlEmulation) close() {
+	e.stopped.Do(func() {
+		close(e.stop)
+	})
+}
+
+// SetWithTTL stores a key-value pair which expires after ttl (ttl <= 0 means no expiration).
+// If the engine implements CacheEngineTTL the expiration is handled by the engine, otherwise
+// it is emulated by the cache: expired entries are not returned and are removed by a background
+// sweeper (see WithTTLSweepInterval), which is stopped by Close.
+func (c *Cache[T]) SetWithTTL(key string, value *T, ttl time.Duration) error {
+	if ttl <= 0 {
+		return c.Set(key, value)
+	}
+	if err := c.validate(key, value); err != nil {
+		return err
+	}
+	if held, err := c.hold(heldWrite[T]{key: key, value: value, ttl: ttl}); held || err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+	return c.setWithTTL(key, value, ttl)
+}
+
+// setWithTTL stores the validated key-value pair with ttl > 0 into the engine
+func (c *Cache[T]) setWithTTL(key string, value *T, ttl time.Duration) error {
+	if err := c.waitWrite(value); err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+	lock := c.lockKey(key)
+	defer c.unlock(lock)
+	return c.setWithTTLUnlocked(key, value, ttl)
+}
+
+// setWithTTLUnlocked stores the validated key-value pair with ttl > 0 into the engine; the caller holds the key lock
+func (c *Cache[T]) setWithTTLUnlocked(key string, value *T, ttl time.Duration) error {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return wrapKeyError(OpSet, key, err)
+	}
+
+	return wrapKeyError(OpSet, key, c.audited(OpSet, key, value, func() error {
+		return c.timed(OpSet, func() error {
+			if ttlEngine, ok := engineAs[CacheEngineTTL](c.engine); ok {
+				return ttlEngine.SetWithTTL(engineKey, value, ttl)
+			}
+			return c.expiry.set(c.engine, engineKey, value, ttl)
+		})
+	}))
+}
+
+// TTL returns the remaining time to live of the key; 0 means the key does not expire
+func (c *Cache[T]) TTL(key string) (time.Duration, error) {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return 0, wrapKeyError(OpGet, key, err)
+	}
+
+	if ttlEngine, ok := engineAs[CacheEngineTTL](c.engine); ok {
+		ttl, err := ttlEngine.TTL(engineKey)
+		return ttl, wrapKeyError(OpGet, key, err)
+	}
+
+	if ttl, found := c.expiry.ttl(engineKey); found && !pinned(c.engine, engineKey) {
+		if ttl <= 0 {
+			return 0, wrapKeyError(OpGet, key, ErrNotFound)
+		}
+		return ttl, nil
+	}
+	if _, err := c.engine.Peek(engineKey); err != nil {
+		return 0, wrapKeyError(OpGet, key, err)
+	}
+	return 0, nil
+}
+
+// Close stops the background goroutines of the cache (the TTL sweeper); it does not close the engine
+func (c *Cache[T]) Close() {
+	c.expiry.close()
+}
@@ -0,0 +1,126 @@
+package cachier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWithTTLEmulated(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachiertest.NewEngine()
+	cache := cachier.MakeCache[int](engine, cachier.WithClock(clock), cachier.WithTTLSweepInterval(time.Second))
+	defer cache.Close()
+
+	value := 1
+	require.Nil(t, cache.SetWithTTL("short", &value, time.Second))
+	require.Nil(t, cache.SetWithTTL("long", &value, time.Hour))
+	require.Nil(t, cache.Set("forever", &value))
+
+	ttl, err := cache.TTL("short")
+	require.Nil(t, err)
+	assert.Equal(t, time.Second, ttl)
+	ttl, err = cache.TTL("forever")
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool {
+		return engine.Len() == 2
+	}, time.Second, time.Millisecond)
+	_, err = cache.Get("short")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	_, err = cache.Get("long")
+	assert.Nil(t, err)
+
+	// plain Set removes the expiration
+	require.Nil(t, cache.Set("long", &value))
+	clock.Advance(time.Hour)
+	_, err = cache.Get("long")
+	assert.Nil(t, err)
+}
+
+func TestSetWithTTLEngine(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	cache := cachier.MakeCache[int](cachier.NewMemoryCacheWithClock(0, 0, clock))
+
+	value := 1
+	require.Nil(t, cache.SetWithTTL("key", &value, time.Minute))
+	ttl, err := cache.TTL("key")
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	clock.Advance(time.Minute)
+	_, err = cache.Get("key")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+}
+
+func TestSubcacheTTL(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	primary := cachier.MakeCache[int](cachier.NewMemoryCacheWithClock(0, 0, clock))
+	subcache := cachier.MakeCache[int](cachiertest.NewEngine(), cachier.WithClock(clock))
+	cache := cachier.MakeCache[int](&cachier.CacheWithSubcache[int]{
+		Cache:       primary,
+		Subcache:    subcache,
+		SubcacheTTL: 30 * time.Second,
+	})
+
+	value := 1
+	require.Nil(t, cache.Set("forever", &value))
+	require.Nil(t, cache.SetWithTTL("short", &value, 10*time.Second))
+
+	ttl, err := subcache.TTL("forever")
+	require.Nil(t, err)
+	assert.Equal(t, 30*time.Second, ttl)
+	ttl, err = subcache.TTL("short")
+	require.Nil(t, err)
+	assert.Equal(t, 10*time.Second, ttl)
+
+	clock.Advance(30 * time.Second)
+	_, err = subcache.Get("forever")
+	assert.ErrorIs(t, err, cachier.ErrNotFound)
+	_, err = cache.Get("forever")
+	assert.Nil(t, err)
+	ttl, err = primary.TTL("forever")
+	require.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+}
+
+func TestGetOrComputeWithTTL(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	cache := cachier.MakeCache[int](cachier.NewMemoryCacheWithClock(0, 0, clock))
+
+	calls := 0
+	evaluator := func() (*int, time.Duration, error) {
+		calls++
+		value := calls
+		return &value, time.Duration(calls) * time.Minute, nil
+	}
+	value, err := cache.GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *value)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+
+	ttl, err := cache.TTL("key")
+	require.Nil(t, err)
+	assert.Equal(t, time.Minute, ttl)
+	value, err = cache.GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, 1, *value)
+
+	clock.Advance(time.Minute)
+	value, err = cache.GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, 2, *value)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+	ttl, err = cache.TTL("key")
+	require.Nil(t, err)
+	assert.Equal(t, 2*time.Minute, ttl)
+}
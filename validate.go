@@ -0,0 +1,55 @@
+package cachier
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidValue is returned by the writes of values rejected by the validator of WithSetValidator
+var ErrInvalidValue = errors.New("invalid value")
+
+// WithSetValidator makes the cache check the values before storing them (Set, SetWithTTL, SetMulti,
+// SetIfAbsent, CompareAndSwap, Update and the values computed by GetOrCompute), e.g. to refuse malformed
+// or oversized values instead of caching them. Rejected values are not stored and the write returns
+// the error of the validator wrapped with ErrInvalidValue; GetOrCompute returns it as an evaluator error.
+// T must be the type of the cache, otherwise all the values are rejected with ErrWrongDataType.
+func WithSetValidator[T any](validator func(key string, value *T) error) Option {
+	return func(o *options) {
+		o.validator = func(key string, value interface{}) error {
+			typedValue, ok := value.(*T)
+			if !ok {
+				return ErrWrongDataType
+			}
+			return validator(key, typedValue)
+		}
+	}
+}
+
+// validate checks the value with the validator of WithSetValidator
+func (c *Cache[T]) validate(key string, value *T) error {
+	if c.options.validator == nil {
+		return nil
+	}
+	if err := c.options.validator(key, value); err != nil {
+		return wrapKeyError(OpSet, key, fmt.Errorf("%w: %w", ErrInvalidValue, err))
+	}
+	return nil
+}
+
+// validated wraps the evaluator to check the computed values with the validator of WithSetValidator
+func (c *Cache[T]) validated(key string, evaluator func() (*T, time.Duration, error)) func() (*T, time.Duration, error) {
+	if c.options.validator == nil {
+		return evaluator
+	}
+	return func() (*T, time.Duration, error) {
+		value, ttl, err := evaluator()
+		if err != nil {
+			return value, ttl, err
+		}
+		if err := c.validate(key, value); err != nil {
+			return nil, 0, err
+		}
+		return value, ttl, nil
+	}
+}
@@ -0,0 +1,58 @@
+package cachier_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetValidator(t *testing.T) {
+	errTooLong := errors.New("too long")
+	cache := cachier.MakeCache[string](cachier.NewMemoryCache(0, 0), cachier.WithSetValidator(func(key string, value *string) error {
+		if len(*value) > 5 {
+			return errTooLong
+		}
+		return nil
+	}))
+
+	valid, invalid := "short", strings.Repeat("long", 10)
+	require.Nil(t, cache.Set("a", &valid))
+	err := cache.Set("b", &invalid)
+	assert.ErrorIs(t, err, cachier.ErrInvalidValue)
+	assert.ErrorIs(t, err, errTooLong)
+	var keyErr *cachier.KeyError
+	require.ErrorAs(t, err, &keyErr)
+	assert.Equal(t, "b", keyErr.Key)
+
+	assert.ErrorIs(t, cache.SetMulti(map[string]*string{"c": &valid, "d": &invalid}), cachier.ErrInvalidValue)
+	_, err = cache.SetIfAbsent("e", &invalid)
+	assert.ErrorIs(t, err, cachier.ErrInvalidValue)
+	_, err = cache.Update("a", func(old *string) (*string, error) { return &invalid, nil })
+	assert.ErrorIs(t, err, cachier.ErrInvalidValue)
+
+	value, err := cache.GetOrCompute("f", func() (*string, error) { return &invalid, nil })
+	assert.ErrorIs(t, err, cachier.ErrInvalidValue)
+	assert.Nil(t, value)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+	got, err := cache.Get("a")
+	require.Nil(t, err)
+	assert.Equal(t, valid, *got)
+}
+
+func TestSetValidatorWrongType(t *testing.T) {
+	cache := cachier.MakeCache[string](cachier.NewMemoryCache(0, 0), cachier.WithSetValidator(func(key string, value *int) error {
+		return nil
+	}))
+	value := "a"
+	assert.ErrorIs(t, cache.Set("a", &value), cachier.ErrWrongDataType)
+}
@@ -0,0 +1,25 @@
+package cachier
+
+import "errors"
+
+// ErrValueTooLarge is returned by Set when the marshaled value exceeds the maximum value size
+var ErrValueTooLarge = errors.New("value too large")
+
+// maxValueSize is the maximum size of a marshaled value an engine stores
+type maxValueSize struct {
+	limit int
+	skip  bool
+}
+
+// check reports whether a marshaled value of the given size may be stored.
+// Too large values are logged and refused with ErrValueTooLarge, or silently skipped if skip is set.
+func (m maxValueSize) check(logger Logger, engine string, key string, size int) (bool, error) {
+	if m.limit <= 0 || size <= m.limit {
+		return true, nil
+	}
+	logger.Warn(engine, ": value of key ", key, " too large: ", size, " bytes")
+	if m.skip {
+		return false, nil
+	}
+	return false, ErrValueTooLarge
+}
@@ -0,0 +1,74 @@
+package cachier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/datasapiens/cachier/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxValueSize(t *testing.T) {
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	recorder := &recordingLogger{}
+	lc, err := NewLRUCacheWithLogger(10,
+		func(value interface{}) ([]byte, error) {
+			return json.Marshal(value)
+		},
+		func(b []byte, value *interface{}) error {
+			return json.Unmarshal(b, value)
+		},
+		recorder,
+		engine)
+	require.Nil(t, err)
+	cache := MakeCache[string](lc.WithMaxValueSize(100, false))
+
+	small, large := "small", strings.Repeat("x", 200)
+	assert.Nil(t, cache.Set("small", &small))
+	assert.ErrorIs(t, cache.Set("large", &large), ErrValueTooLarge)
+	assert.Equal(t, []string{"warn: lru: value of key large too large: 202 bytes"}, recorder.Messages())
+
+	lc.WithMaxValueSize(100, true)
+	assert.Nil(t, cache.Set("large", &large))
+	_, err = cache.Get("large")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMaxValueSizeSkipOverwrite(t *testing.T) {
+	engine, err := compression.NewEngine(compression.ProviderIDZstd, nil)
+	require.Nil(t, err)
+	lc, err := NewLRUCache(10,
+		func(value interface{}) ([]byte, error) {
+			return json.Marshal(value)
+		},
+		func(b []byte, value *interface{}) error {
+			return json.Unmarshal(b, value)
+		},
+		engine)
+	require.Nil(t, err)
+	caches := map[string]*Cache[string]{"lru": MakeCache[string](lc.WithMaxValueSize(100, true))}
+	if redisClient, err := InitRedis(); err == nil {
+		rc := NewRedisCache(redisClient, "max-value-size:", json.Marshal, func(b []byte, value *interface{}) error {
+			return json.Unmarshal(b, value)
+		}, 0, nil).WithMaxValueSize(100, true)
+		caches["redis"] = MakeCache[string](rc)
+	}
+
+	old, large := "old", strings.Repeat("x", 200)
+	for name, cache := range caches {
+		// the skipped value must not leave the previous one readable
+		require.Nil(t, cache.Set("key", &old), name)
+		require.Nil(t, cache.Set("key", &large), name)
+		_, err = cache.Get("key")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		require.Nil(t, cache.Set("key", &old), name)
+		require.Nil(t, cache.SetMulti(map[string]*string{"key": &large}), name)
+		_, err = cache.Get("key")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+		require.Nil(t, cache.Purge(), name)
+	}
+}
@@ -0,0 +1,101 @@
+package cachier
+
+import (
+	"context"
+	"regexp"
+	"sort"
+)
+
+// warmBatchSize is the number of keys read from the primary cache at once by WarmSubcache
+const warmBatchSize = 1000
+
+// WarmSubcache copies at most limit entries whose keys match the regular expression keyPattern ("" matches all
+// the keys) from the primary cache into the subcache, e.g. at startup to avoid the latency of a cold subcache
+// after deploys; limit <= 0 means no limit. If the primary engine implements EntryAger (e.g. RedisCache with TTL,
+// MemoryCache) the most recently stored entries are copied, which asks the engine for the age of every matching
+// key; otherwise arbitrary matching entries are copied. It returns the number of copied entries.
+func (cs *CacheWithSubcache[T]) WarmSubcache(ctx context.Context, keyPattern string, limit int) (int, error) {
+	re, err := regexp.Compile(keyPattern)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := cs.Cache.KeysPredicate(re.MatchString)
+	if err != nil {
+		return 0, err
+	}
+
+	if ager, ok := engineAs[EntryAger](cs.Cache.engine); ok {
+		if keys, err = cs.mostRecent(ctx, ager, keys, limit); err != nil {
+			return 0, err
+		}
+	} else if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return cs.WarmSubcacheKeys(ctx, keys)
+}
+
+// mostRecent returns at most limit of the keys stored most recently, the oldest first;
+// keys whose age is unknown are considered the oldest
+func (cs *CacheWithSubcache[T]) mostRecent(ctx context.Context, ager EntryAger, keys []string, limit int) ([]string, error) {
+	type keyAge struct {
+		key   string
+		age   int64
+		known bool
+	}
+	ages := make([]keyAge, 0, len(keys))
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		age, err := ager.Age(key)
+		ages = append(ages, keyAge{key: key, age: int64(age), known: err == nil})
+	}
+	sort.SliceStable(ages, func(i, j int) bool {
+		if ages[i].known != ages[j].known {
+			return ages[i].known
+		}
+		return ages[i].age < ages[j].age
+	})
+	if limit > 0 && len(ages) > limit {
+		ages = ages[:limit]
+	}
+
+	// the most recent entries are copied last, so they are the most recently used ones in the subcache
+	recent := make([]string, len(ages))
+	for i, age := range ages {
+		recent[len(ages)-1-i] = age.key
+	}
+	return recent, nil
+}
+
+// WarmSubcacheKeys copies the entries of the keys from the primary cache into the subcache in the given order;
+// keys missing in the primary cache and values exceeding SubcacheMaxValueSize are skipped.
+// It returns the number of copied entries.
+func (cs *CacheWithSubcache[T]) WarmSubcacheKeys(ctx context.Context, keys []string) (int, error) {
+	warmed := 0
+	for start := 0; start < len(keys); start += warmBatchSize {
+		if err := ctx.Err(); err != nil {
+			return warmed, err
+		}
+		end := start + warmBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+		values, err := cs.Cache.GetMulti(batch)
+		if err != nil {
+			return warmed, err
+		}
+		for _, key := range batch {
+			value, found := values[key]
+			if !found || cs.bypassSubcache(value) {
+				continue
+			}
+			if err := cs.setSubcache(key, value, 0); err != nil {
+				return warmed, err
+			}
+			warmed++
+		}
+	}
+	return warmed, nil
+}
@@ -0,0 +1,74 @@
+package cachier_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWarmupCache(t *testing.T, primaryEngine cachier.CacheEngine) (*cachier.CacheWithSubcache[int], *cachier.Cache[int]) {
+	lc, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	subcache := cachier.MakeCache[int](lc)
+	return &cachier.CacheWithSubcache[int]{Cache: cachier.MakeCache[int](primaryEngine), Subcache: subcache}, subcache
+}
+
+func TestWarmSubcacheMostRecent(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	cs, subcache := newWarmupCache(t, cachier.NewMemoryCacheWithClock(0, 0, clock))
+
+	for i := 0; i < 5; i++ {
+		value := i
+		require.Nil(t, cs.Cache.Set(fmt.Sprintf("user:%d", i), &value))
+		require.Nil(t, cs.Cache.Set(fmt.Sprintf("order:%d", i), &value))
+		clock.Advance(time.Second)
+	}
+
+	warmed, err := cs.WarmSubcache(context.Background(), "^user:", 2)
+	require.Nil(t, err)
+	assert.Equal(t, 2, warmed)
+	keys, err := subcache.Keys()
+	require.Nil(t, err)
+	// the most recent entry is the most recently used one
+	assert.Equal(t, []string{"user:3", "user:4"}, keys)
+	value, err := subcache.Get("user:4")
+	require.Nil(t, err)
+	assert.Equal(t, 4, *value)
+}
+
+func TestWarmSubcacheKeys(t *testing.T) {
+	primary, err := cachier.NewLRUCache(10, nil, nil, nil)
+	require.Nil(t, err)
+	cs, subcache := newWarmupCache(t, primary)
+	cs.SubcacheMaxValueSize = 3
+
+	small, large := 1, 1000
+	require.Nil(t, cs.Cache.Set("a", &small))
+	require.Nil(t, cs.Cache.Set("b", &small))
+	require.Nil(t, cs.Cache.Set("large", &large))
+
+	warmed, err := cs.WarmSubcacheKeys(context.Background(), []string{"a", "missing", "large"})
+	require.Nil(t, err)
+	assert.Equal(t, 1, warmed)
+	keys, err := subcache.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+
+	// without EntryAger any matching entries are copied
+	warmed, err = cs.WarmSubcache(context.Background(), "", 0)
+	require.Nil(t, err)
+	assert.Equal(t, 2, warmed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cs.WarmSubcache(ctx, "", 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = cs.WarmSubcache(context.Background(), "(", 0)
+	assert.NotNil(t, err)
+}
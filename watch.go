@@ -0,0 +1,113 @@
+package cachier
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OpExpire identifies the entries expired or evicted by the engine in ChangeEvent
+const OpExpire = "expire"
+
+// watchBufferSize is the number of events buffered for a watcher
+const watchBufferSize = 256
+
+// ChangeEvent describes a change of the cache
+type ChangeEvent struct {
+	// Op is OpSet, OpDelete, OpPurge or OpExpire
+	Op string
+	// Key is the changed key; the purged prefix for OpPurge ("" for Purge). Keys deleted by DeletePredicate
+	// and DeleteWithPrefix and the keys reported by the engine are the keys of the engine as listed by Keys.
+	Key string
+	// Missed is the number of events dropped before this one because the watcher did not keep up
+	Missed int
+}
+
+// watchHub delivers the change events to the watchers
+type watchHub struct {
+	mutex    sync.Mutex
+	watchers map[*watcher]struct{}
+	// count is the number of watchers, so writes do not lock the hub when no one watches
+	count atomic.Int32
+}
+
+type watcher struct {
+	prefix string
+	events chan ChangeEvent
+	missed int
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{watchers: make(map[*watcher]struct{})}
+}
+
+// publish sends the event to the watchers of its key without blocking
+func (h *watchHub) publish(op string, key string) {
+	if h.count.Load() == 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for w := range h.watchers {
+		w.send(op, key)
+	}
+}
+
+// send sends the event to the watcher if it matches its prefix; the hub must be locked
+func (w *watcher) send(op string, key string) {
+	if op == OpPurge {
+		// a purge of a prefix changes the watched keys if one of the prefixes contains the other
+		if !strings.HasPrefix(key, w.prefix) && !strings.HasPrefix(w.prefix, key) {
+			return
+		}
+	} else if !strings.HasPrefix(key, w.prefix) {
+		return
+	}
+	select {
+	case w.events <- ChangeEvent{Op: op, Key: key, Missed: w.missed}:
+		w.missed = 0
+	default:
+		w.missed++
+	}
+}
+
+// Watch returns a channel of the changes of the keys starting with prefix ("" watches all the keys) until stop
+// is called, which closes the channel. The writes of this Cache are reported once they succeed; if the engine
+// implements ExpirationNotifier, the keys it expires or evicts are reported as OpExpire (writes of other
+// processes are not reported). Events are dropped when the receiver does not keep up, see ChangeEvent.Missed.
+func (c *Cache[T]) Watch(prefix string) (events <-chan ChangeEvent, stop func() error, err error) {
+	w := &watcher{prefix: prefix, events: make(chan ChangeEvent, watchBufferSize)}
+
+	stopEngine := func() error { return nil }
+	if notifier, ok := c.engine.(ExpirationNotifier); ok {
+		stopEngine, err = notifier.NotifyExpired(func(key string) {
+			c.watchers.mutex.Lock()
+			defer c.watchers.mutex.Unlock()
+			if _, watching := c.watchers.watchers[w]; watching {
+				w.send(OpExpire, key)
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	c.watchers.mutex.Lock()
+	c.watchers.watchers[w] = struct{}{}
+	c.watchers.count.Add(1)
+	c.watchers.mutex.Unlock()
+
+	var once sync.Once
+	return w.events, func() error {
+		var stopErr error
+		once.Do(func() {
+			stopErr = stopEngine()
+			c.watchers.mutex.Lock()
+			defer c.watchers.mutex.Unlock()
+			delete(c.watchers.watchers, w)
+			c.watchers.count.Add(-1)
+			close(w.events)
+		})
+		return stopErr
+	}, nil
+}
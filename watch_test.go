@@ -0,0 +1,95 @@
+package cachier
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveEvent(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return ChangeEvent{}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	cache := InitLRUCache[int]()
+	events, stop, err := cache.Watch("a:")
+	require.Nil(t, err)
+
+	value := 1
+	require.Nil(t, cache.Set("a:1", &value))
+	require.Nil(t, cache.Set("b:1", &value))
+	require.Nil(t, cache.SetMulti(map[string]*int{"a:2": &value, "b:2": &value}))
+	require.Nil(t, cache.Delete("a:1"))
+	require.Nil(t, cache.PurgePrefix("b:"))
+	require.Nil(t, cache.Purge())
+
+	for _, expected := range []ChangeEvent{
+		{Op: OpSet, Key: "a:1"},
+		{Op: OpSet, Key: "a:2"},
+		{Op: OpDelete, Key: "a:1"},
+		{Op: OpPurge, Key: ""},
+	} {
+		assert.Equal(t, expected, receiveEvent(t, events))
+	}
+
+	require.Nil(t, stop())
+	require.Nil(t, cache.Set("a:3", &value))
+	_, open := <-events
+	assert.False(t, open)
+	require.Nil(t, stop())
+}
+
+func TestWatchMissed(t *testing.T) {
+	cache := InitLRUCache[int]()
+	events, stop, err := cache.Watch("")
+	require.Nil(t, err)
+	defer stop()
+
+	value := 1
+	for i := 0; i < watchBufferSize+10; i++ {
+		require.Nil(t, cache.Set(fmt.Sprint(i), &value))
+	}
+	for i := 0; i < watchBufferSize; i++ {
+		assert.Equal(t, 0, receiveEvent(t, events).Missed)
+	}
+	require.Nil(t, cache.Delete("0"))
+	assert.Equal(t, ChangeEvent{Op: OpDelete, Key: "0", Missed: 10}, receiveEvent(t, events))
+}
+
+func TestRedisWatchExpired(t *testing.T) {
+	redisClient, err := InitRedis()
+	if err != nil {
+		t.Skipf("skipping because of redis error: %s", err.Error())
+	}
+	rc := NewRedisCache(redisClient, "watch:", json.Marshal, func(b []byte, value *interface{}) error {
+		return json.Unmarshal(b, value)
+	}, 0, nil)
+	cache := MakeCache[int](rc)
+
+	events, stop, err := cache.Watch("a")
+	require.Nil(t, err)
+	defer stop()
+
+	value := 1
+	require.Nil(t, cache.Set("a", &value))
+	assert.Equal(t, ChangeEvent{Op: OpSet, Key: "a"}, receiveEvent(t, events))
+
+	// the notifications are published as redis does it, so the test does not depend on the server configuration
+	db := redisClient.Options().DB
+	for _, key := range []string{"watch:b", "watch:a"} {
+		require.Nil(t, redisClient.Publish(ctx, fmt.Sprintf("__keyevent@%d__:expired", db), key).Err())
+	}
+	assert.Equal(t, ChangeEvent{Op: OpExpire, Key: "a"}, receiveEvent(t, events))
+	require.Nil(t, cache.Delete("a"))
+}
@@ -0,0 +1,143 @@
+package cachier
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// computeCostKeySuffix is appended to the key to get the key holding the compute cost of its value
+const computeCostKeySuffix = "\x00xfetch"
+
+// xfetchOptions holds the configuration of WithEarlyExpiration
+type xfetchOptions struct {
+	beta   float64
+	random func() float64
+}
+
+// WithEarlyExpiration makes GetOrCompute recompute values probabilistically before they expire (the XFetch
+// algorithm), so hot keys are not recomputed by many callers at once when they expire. A value is recomputed
+// when cost * beta * -ln(random) exceeds its remaining TTL, where cost is how long its last computation took;
+// beta > 1 favours earlier recomputation (1 is a good default). The compute cost is stored in the engine with
+// the value under a derived key (the key followed by "\x00xfetch"), so it is shared by the processes using the
+// engine; engines implementing RawEngine keep it with their default TTL, the others with the TTL of the value.
+// The derived keys are not listed by Keys, Range, ForEach etc. (Count lists the keys to skip them) and they are
+// deleted together with their values. Values whose cost is not known and values without TTL are not recomputed
+// early, so the cost of values without TTL is not stored.
+// As with WithSoftTTL, the current value is served if the recomputation fails.
+func WithEarlyExpiration(beta float64) Option {
+	return func(o *options) {
+		o.xfetch = &xfetchOptions{
+			beta:   beta,
+			random: rand.Float64,
+		}
+	}
+}
+
+// recordCost stores the compute cost of the value of the key stored with ttl
+func (c *Cache[T]) recordCost(key string, cost time.Duration, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	if c.WritesPaused() {
+		return ErrFrozen
+	}
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return err
+	}
+	costKey := engineKey + computeCostKeySuffix
+	if rawEngine, ok := c.engine.(RawEngine); ok {
+		// the payload is stored as it is, so the cost does not depend on how the engine marshals the values
+		err = rawEngine.SetRaw(costKey, []byte(strconv.FormatInt(int64(cost), 10)))
+		if !errors.Is(err, ErrRawNotSupported) {
+			return err
+		}
+	}
+	if ttlEngine, ok := engineAs[CacheEngineTTL](c.engine); ok {
+		return ttlEngine.SetWithTTL(costKey, cost, ttl)
+	}
+	return c.expiry.set(c.engine, costKey, cost, ttl)
+}
+
+// forgetCosts deletes the compute costs of the values of the engine keys (best effort)
+func (c *Cache[T]) forgetCosts(engineKeys []string) {
+	if c.options.xfetch == nil || len(engineKeys) == 0 {
+		return
+	}
+	costKeys := make([]string, len(engineKeys))
+	for i, engineKey := range engineKeys {
+		costKeys[i] = engineKey + computeCostKeySuffix
+		c.expiry.forget(costKeys[i])
+	}
+	if multiDeleter, ok := c.engine.(MultiDeleter); ok {
+		multiDeleter.DeleteMulti(costKeys)
+		return
+	}
+	for _, costKey := range costKeys {
+		c.engine.Delete(costKey)
+	}
+}
+
+// isCostKey reports whether the engine key holds a compute cost, so it is not listed by Keys
+func isCostKey(key string) bool {
+	return strings.HasSuffix(key, computeCostKeySuffix)
+}
+
+// withoutCostKeys drops the keys holding compute costs from keys
+func withoutCostKeys(keys []string) []string {
+	filtered := keys[:0]
+	for _, key := range keys {
+		if !isCostKey(key) {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered
+}
+
+// computeCost returns the stored compute cost of the value of the key
+func (c *Cache[T]) computeCost(key string) (time.Duration, bool) {
+	engineKey, err := c.engineKey(key)
+	if err != nil {
+		return 0, false
+	}
+	costKey := engineKey + computeCostKeySuffix
+	if rawEngine, ok := c.engine.(RawEngine); ok {
+		payload, err := rawEngine.GetRaw(costKey)
+		if err == nil {
+			nanos, err := strconv.ParseInt(string(payload), 10, 64)
+			return time.Duration(nanos), err == nil
+		} else if !errors.Is(err, ErrRawNotSupported) {
+			return 0, false
+		}
+	}
+	if c.expiry.expire(c.engine, costKey) {
+		return 0, false
+	}
+	value, err := c.engine.Peek(costKey)
+	if err != nil {
+		return 0, false
+	}
+	cost, ok := value.(time.Duration)
+	return cost, ok
+}
+
+// expiresEarly reports whether the value of the key should be recomputed before its expiration
+func (c *Cache[T]) expiresEarly(key string) bool {
+	x := c.options.xfetch
+	if x == nil {
+		return false
+	}
+	cost, found := c.computeCost(key)
+	if !found {
+		return false
+	}
+	ttl, err := c.TTL(key)
+	if err != nil || ttl <= 0 {
+		return false
+	}
+	return float64(cost)*x.beta*-math.Log(x.random()) >= float64(ttl)
+}
@@ -0,0 +1,126 @@
+package cachier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datasapiens/cachier"
+	"github.com/datasapiens/cachier/cachiertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEarlyExpiration(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		beta       float64
+		recomputed bool
+	}{
+		{name: "never early", beta: 0, recomputed: false},
+		{name: "always early", beta: 1e9, recomputed: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			clock := cachiertest.NewFakeClock(time.Now())
+			engine := cachier.NewMemoryCacheWithClock(time.Hour, 0, clock)
+			cache := cachier.MakeCache[int](engine, cachier.WithClock(clock), cachier.WithEarlyExpiration(test.beta))
+
+			evaluations := 0
+			evaluator := func() (*int, time.Duration, error) {
+				evaluations++
+				// the computation takes 10s
+				clock.Advance(10 * time.Second)
+				return &evaluations, time.Hour, nil
+			}
+			_, err := cache.GetOrComputeWithTTL("key", evaluator)
+			require.Nil(t, err)
+			_, err = cache.DrainWithin(context.Background(), nil)
+			require.Nil(t, err)
+
+			value, err := cache.GetOrComputeWithTTL("key", evaluator)
+			require.Nil(t, err)
+			if test.recomputed {
+				assert.Equal(t, 2, evaluations)
+			} else {
+				assert.Equal(t, 1, evaluations)
+			}
+			assert.Equal(t, evaluations, *value)
+		})
+	}
+}
+
+func TestEarlyExpirationSharedCost(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachier.NewMemoryCacheWithClock(time.Hour, 0, clock)
+	evaluations := 0
+	evaluator := func() (*int, time.Duration, error) {
+		evaluations++
+		clock.Advance(10 * time.Second)
+		return &evaluations, time.Hour, nil
+	}
+
+	cache := cachier.MakeCache[int](engine, cachier.WithClock(clock), cachier.WithEarlyExpiration(1e9))
+	_, err := cache.GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+
+	// the cost is stored with the value, so another process (or a restarted one) knows it
+	other := cachier.MakeCache[int](engine, cachier.WithClock(clock), cachier.WithEarlyExpiration(1e9))
+	value, err := other.GetOrComputeWithTTL("key", evaluator)
+	require.Nil(t, err)
+	assert.Equal(t, 2, evaluations)
+	assert.Equal(t, 2, *value)
+}
+
+func TestEarlyExpirationCostKeys(t *testing.T) {
+	clock := cachiertest.NewFakeClock(time.Now())
+	engine := cachier.NewMemoryCacheWithClock(time.Hour, 0, clock)
+	cache := cachier.MakeCache[int](engine, cachier.WithClock(clock), cachier.WithEarlyExpiration(1))
+	evaluator := func() (*int, time.Duration, error) {
+		value := 1
+		return &value, time.Hour, nil
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_, err := cache.GetOrComputeWithTTL(key, evaluator)
+		require.Nil(t, err)
+	}
+	// the cost of values without TTL is not stored
+	_, err := cache.GetOrCompute("e", func() (*int, error) {
+		value := 1
+		return &value, nil
+	})
+	require.Nil(t, err)
+	_, err = cache.DrainWithin(context.Background(), nil)
+	require.Nil(t, err)
+
+	engineKeys, err := engine.Keys()
+	require.Nil(t, err)
+	assert.Len(t, engineKeys, 9)
+
+	// the keys holding the costs are not listed
+	keys, err := cache.Keys()
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, keys)
+	count, err := cache.Count()
+	require.Nil(t, err)
+	assert.Equal(t, 5, count)
+	page, _, err := cache.KeysPage("", 10)
+	require.Nil(t, err)
+	assert.Len(t, page, 5)
+	require.Nil(t, cache.ForEach(func(key string, value *int) bool { return true }, cachier.ForEachOptions{
+		OnError: func(key string, err error) {
+			t.Errorf("%q: %v", key, err)
+		},
+	}))
+
+	// and they are deleted with their values
+	require.Nil(t, cache.Delete("a"))
+	require.Nil(t, cache.DeleteKeys([]string{"b"}))
+	_, err = cache.DeleteWithPrefix("c")
+	require.Nil(t, err)
+	require.Nil(t, cache.PurgePrefix("d"))
+	engineKeys, err = engine.Keys()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"e"}, engineKeys)
+}